@@ -0,0 +1,129 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "os"
+    "time"
+
+    "github.com/Jthora/autoBotTrader/api/internal/accuracy"
+    "github.com/Jthora/autoBotTrader/api/internal/ephem"
+)
+
+// cmdAccuracy is the Go counterpart of scripts/ephem/validation/compute_metrics.py:
+// it writes metrics JSON and exits 1 when the run does not pass, 2 when FILE
+// cannot be opened (metrics with reason bad_header are still written).
+func cmdAccuracy(args []string, stdout io.Writer) error {
+    fs := newFlags("accuracy")
+    refFlag := fs.String("ref", "algo", `reference: "algo" (analytic Sun/Moon ephemeris) or a GTAB path`)
+    basis := fs.String("basis", "", "compared field: tide_bps or tide_raw (default: tide_raw if both sources have it)")
+    datasetID := fs.String("dataset-id", "", "dataset label (default: table provenance)")
+    var start, end timeFlag
+    fs.Var(&start, "start", "first time (default: end-7d, clipped to coverage)")
+    fs.Var(&end, "end", "last time (default: now, clipped to coverage)")
+    cadence := fs.Duration("cadence", time.Minute, "sampling cadence")
+    thPath := fs.String("thresholds", "", "JSON file overriding thresholds (metrics.schema.json keys)")
+    out := fs.String("out", "", "metrics output path (default: stdout)")
+    if err := parse(fs, args, 1, 1); err != nil {
+        return err
+    }
+    th := accuracy.DefaultThresholds
+    if *thPath != "" {
+        b, err := os.ReadFile(*thPath)
+        if err != nil {
+            return err
+        }
+        if err := json.Unmarshal(b, &th); err != nil {
+            return fmt.Errorf("%s: %w", *thPath, err)
+        }
+    }
+    cfg := accuracy.Config{DatasetID: *datasetID, Cadence: *cadence, Basis: *basis, Thresholds: th}
+
+    path := fs.Arg(0)
+    g, err := ephem.Open(path)
+    if err != nil {
+        cfg.End = end.or(time.Now().UTC())
+        cfg.Start = start.or(cfg.End.Add(-7 * 24 * time.Hour))
+        m := accuracy.Failed(cfg, accuracy.ReasonBadHeader, err.Error())
+        if werr := writeMetrics(m, *out, stdout); werr != nil {
+            return werr
+        }
+        return exitError{2, err}
+    }
+    defer g.Close()
+    if cfg.DatasetID == "" {
+        if prov, ok := g.Provenance(); ok {
+            cfg.DatasetID = prov.DatasetID
+        }
+    }
+    // the default window is the last week the table covers; explicit
+    // -start/-end are checked as given
+    covStart, covEnd := g.Coverage()
+    cfg.End = end.or(minTime(time.Now().UTC(), covEnd))
+    cfg.Start = start.or(cfg.End.Add(-7 * 24 * time.Hour))
+    if !start.set && cfg.Start.Before(covStart) {
+        cfg.Start = covStart
+    }
+
+    var ref ephem.TideFunc
+    var refFields uint32 = ephem.AlgoFields
+    if *refFlag != "algo" {
+        rg, err := ephem.Open(*refFlag)
+        if err != nil {
+            return err
+        }
+        defer rg.Close()
+        refFields = rg.Fields()
+        if cfg.Basis == "" {
+            cfg.Basis = pickBasis(g.Fields(), refFields)
+        }
+        if ref, err = accuracy.TableFunc(rg, cfg.Basis); err != nil {
+            return fmt.Errorf("reference: %w", err)
+        }
+    } else {
+        if cfg.Basis == "" {
+            cfg.Basis = pickBasis(g.Fields(), refFields)
+        }
+        if ref, err = accuracy.AlgoFunc(cfg.Basis, ephem.DefaultScale); err != nil {
+            return fmt.Errorf("%w: %v", errUsage, err)
+        }
+    }
+    table, err := accuracy.TableFunc(g, cfg.Basis)
+    if err != nil {
+        return err
+    }
+    m := accuracy.Run(table, ref, cfg)
+    if d, err := digest(path); err == nil {
+        m.TableSHA256 = d.SHA256
+    }
+    if err := writeMetrics(m, *out, stdout); err != nil {
+        return err
+    }
+    if !m.Passes {
+        return exitError{code: 1}
+    }
+    return nil
+}
+
+// pickBasis prefers tide_raw: tide_bps depends on the window each source was
+// normalized over.
+func pickBasis(a, b uint32) string {
+    if a&b&ephem.FieldTideRawF32 != 0 {
+        return accuracy.BasisTideRaw
+    }
+    return accuracy.BasisTideBPS
+}
+
+func writeMetrics(m accuracy.Metrics, path string, stdout io.Writer) error {
+    b, err := json.MarshalIndent(m, "", "  ")
+    if err != nil {
+        return err
+    }
+    b = append(b, '\n')
+    if path == "" {
+        _, err = stdout.Write(b)
+        return err
+    }
+    return os.WriteFile(path, b, 0o644)
+}
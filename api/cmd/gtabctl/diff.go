@@ -0,0 +1,153 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "math"
+    "sort"
+    "time"
+
+    "github.com/Jthora/autoBotTrader/api/internal/ephem"
+)
+
+// errStats summarizes the error of one column between two tables.
+type errStats struct {
+    Field   string    `json:"field"`
+    Points  int       `json:"points"`
+    Mean    float64   `json:"mean"`
+    RMSE    float64   `json:"rmse"`
+    P50     float64   `json:"p50"`
+    P95     float64   `json:"p95"`
+    P99     float64   `json:"p99"`
+    Max     float64   `json:"max"`
+    MaxTime time.Time `json:"max_time"`
+}
+
+// diffReport is the output of diff -json.
+type diffReport struct {
+    A     string     `json:"a"`
+    B     string     `json:"b"`
+    Start time.Time  `json:"start"`
+    End   time.Time  `json:"end"`
+    Step  string     `json:"step"`
+    Stats []errStats `json:"stats"`
+}
+
+func cmdDiff(args []string, stdout io.Writer) error {
+    fs := newFlags("diff")
+    step := fs.Duration("step", 0, "comparison cadence (default: the coarser table step)")
+    asJSON := fs.Bool("json", false, "print the report as JSON")
+    if err := parse(fs, args, 2, 2); err != nil {
+        return err
+    }
+    a, err := ephem.Open(fs.Arg(0))
+    if err != nil {
+        return err
+    }
+    defer a.Close()
+    b, err := ephem.Open(fs.Arg(1))
+    if err != nil {
+        return err
+    }
+    defer b.Close()
+    rep, err := diff(a, b, *step)
+    if err != nil {
+        return err
+    }
+    rep.A, rep.B = fs.Arg(0), fs.Arg(1)
+    if *asJSON {
+        enc := json.NewEncoder(stdout)
+        enc.SetIndent("", "  ")
+        return enc.Encode(rep)
+    }
+    fmt.Fprintf(stdout, "%s .. %s every %s\n", rep.Start.Format(time.RFC3339), rep.End.Format(time.RFC3339), rep.Step)
+    for _, s := range rep.Stats {
+        fmt.Fprintf(stdout, "%-12s n=%d mean=%.4g rmse=%.4g p50=%.4g p95=%.4g p99=%.4g max=%.4g at %s\n",
+            s.Field, s.Points, s.Mean, s.RMSE, s.P50, s.P95, s.P99, s.Max, s.MaxTime.Format(time.RFC3339))
+    }
+    return nil
+}
+
+// diff compares every column present in both tables over their common
+// coverage: absolute error for tide_bps, relative error for float columns.
+// Ticks where either table has a gap are skipped.
+func diff(a, b *ephem.GTAB, step time.Duration) (diffReport, error) {
+    if step == 0 {
+        step = max(a.Header().Step, b.Header().Step)
+    }
+    as, ae := a.Coverage()
+    bs, be := b.Coverage()
+    start, end := as, ae
+    if bs.After(start) {
+        start = bs
+    }
+    if be.Before(end) {
+        end = be
+    }
+    if end.Before(start) {
+        return diffReport{}, fmt.Errorf("tables do not overlap")
+    }
+    cols := present(a.Fields() & b.Fields())
+    errs := make([][]float64, len(cols))
+    worst := make([]time.Time, len(cols))
+    worstV := make([]float64, len(cols))
+    // Both series walk the same grid but skip their own gaps, so the one
+    // behind is advanced until the two land on the same tick.
+    ia, ib := a.Series(start, end, step), b.Series(start, end, step)
+    for oka, okb := ia.Next(), ib.Next(); oka && okb; oka, okb = ia.Next(), ib.Next() {
+        for oka && okb && !ia.Time().Equal(ib.Time()) {
+            if ia.Time().Before(ib.Time()) {
+                oka = ia.Next()
+            } else {
+                okb = ib.Next()
+            }
+        }
+        if !oka || !okb {
+            break
+        }
+        sa, sb := ia.Sample(), ib.Sample()
+        for k, c := range cols {
+            va, vb := c.get(sa), c.get(sb)
+            e := math.Abs(va - vb)
+            if c.bit != ephem.FieldTideBPS && va != 0 {
+                e /= math.Abs(va)
+            }
+            if len(errs[k]) == 0 || e > worstV[k] {
+                worst[k], worstV[k] = ia.Time(), e
+            }
+            errs[k] = append(errs[k], e)
+        }
+    }
+    if err := ia.Err(); err != nil {
+        return diffReport{}, err
+    }
+    if err := ib.Err(); err != nil {
+        return diffReport{}, err
+    }
+    rep := diffReport{Start: start, End: end, Step: step.String()}
+    for k, c := range cols {
+        rep.Stats = append(rep.Stats, summarize(c.name, errs[k], worst[k]))
+    }
+    return rep, nil
+}
+
+func summarize(name string, e []float64, worst time.Time) errStats {
+    s := errStats{Field: name, Points: len(e), MaxTime: worst}
+    if len(e) == 0 {
+        return s
+    }
+    var sum, sq float64
+    for _, x := range e {
+        sum += x
+        sq += x * x
+    }
+    sorted := append([]float64(nil), e...)
+    sort.Float64s(sorted)
+    pct := func(p float64) float64 { return sorted[int(p*float64(len(sorted)-1))] }
+    s.Mean = sum / float64(len(e))
+    s.RMSE = math.Sqrt(sq / float64(len(e)))
+    s.P50, s.P95, s.P99 = pct(0.50), pct(0.95), pct(0.99)
+    s.Max = sorted[len(sorted)-1]
+    return s
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "io"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/Jthora/autoBotTrader/api/internal/ephem"
+)
+
+// timeFlag is an optional RFC 3339 time flag.
+type timeFlag struct {
+    t   time.Time
+    set bool
+}
+
+func (f *timeFlag) String() string {
+    if !f.set {
+        return ""
+    }
+    return f.t.Format(time.RFC3339Nano)
+}
+
+func (f *timeFlag) Set(s string) error {
+    t, err := time.Parse(time.RFC3339Nano, s)
+    if err != nil {
+        return err
+    }
+    f.t, f.set = t.UTC(), true
+    return nil
+}
+
+// or returns the flag value, or def when unset.
+func (f *timeFlag) or(def time.Time) time.Time {
+    if f.set {
+        return f.t
+    }
+    return def
+}
+
+func cmdDump(args []string, stdout io.Writer) error {
+    fs := newFlags("dump")
+    format := fs.String("format", "csv", "output format: csv or ndjson")
+    var start, end timeFlag
+    fs.Var(&start, "start", "first time (default: start of coverage)")
+    fs.Var(&end, "end", "last time (default: end of coverage)")
+    step := fs.Duration("step", 0, "output cadence (default: table step)")
+    interp := fs.String("interp", "linear", "interpolation: nearest, linear or hermite")
+    if err := parse(fs, args, 1, 1); err != nil {
+        return err
+    }
+    if *format != "csv" && *format != "ndjson" {
+        return fmt.Errorf("%w: unknown format %q", errUsage, *format)
+    }
+    mode, err := ephem.ParseInterp(*interp)
+    if err != nil {
+        return fmt.Errorf("%w: %v", errUsage, err)
+    }
+    g, err := ephem.OpenWithOptions(fs.Arg(0), ephem.Options{Interp: mode})
+    if err != nil {
+        return err
+    }
+    defer g.Close()
+    covStart, covEnd := g.Coverage()
+    if *step == 0 {
+        *step = g.Header().Step
+    }
+    cols := present(g.Fields())
+    w := bufio.NewWriter(stdout)
+    if *format == "csv" {
+        names := []string{"time"}
+        for _, c := range cols {
+            names = append(names, c.name)
+        }
+        fmt.Fprintln(w, strings.Join(names, ","))
+    }
+    it := g.Series(start.or(covStart), end.or(covEnd), *step)
+    for it.Next() {
+        if err := writeRow(w, *format, it.Time(), it.Sample(), cols); err != nil {
+            return err
+        }
+    }
+    if err := it.Err(); err != nil {
+        return err
+    }
+    return w.Flush()
+}
+
+func writeRow(w io.Writer, format string, t time.Time, s ephem.Sample, cols []field) error {
+    ts := t.Format(time.RFC3339Nano)
+    if format == "ndjson" {
+        row := map[string]any{"time": ts}
+        for _, c := range cols {
+            row[c.name] = c.get(s)
+        }
+        b, err := json.Marshal(row)
+        if err != nil {
+            return err
+        }
+        _, err = fmt.Fprintf(w, "%s\n", b)
+        return err
+    }
+    line := []string{ts}
+    for _, c := range cols {
+        line = append(line, strconv.FormatFloat(c.get(s), 'g', -1, 32))
+    }
+    _, err := fmt.Fprintln(w, strings.Join(line, ","))
+    return err
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "sort"
+    "time"
+
+    "github.com/Jthora/autoBotTrader/api/internal/ephem"
+)
+
+func cmdSlice(args []string, stdout io.Writer) error {
+    fs := newFlags("slice")
+    var start, end timeFlag
+    fs.Var(&start, "start", "first record time (rounded up to the grid)")
+    fs.Var(&end, "end", "last record time (rounded down to the grid)")
+    out := fs.String("o", "", "output path")
+    version := fs.Int("version", 0, "output format version (default: same as input)")
+    if err := parse(fs, args, 1, 1); err != nil {
+        return err
+    }
+    if *out == "" {
+        return fmt.Errorf("%w: -o is required", errUsage)
+    }
+    g, err := ephem.Open(fs.Arg(0))
+    if err != nil {
+        return err
+    }
+    defer g.Close()
+    h := g.Header()
+    covStart, covEnd := g.Coverage()
+    // flag times are UTC; the grid is counted on the table's time scale
+    from := recordAtOrAfter(h, ephem.ToScale(start.or(covStart), h.Scale))
+    to := int64(g.Len()) - 1
+    if t := end.or(covEnd); t.Before(covEnd) {
+        to = recordAtOrBefore(h, ephem.ToScale(t, h.Scale))
+    }
+    if from > to {
+        return fmt.Errorf("window selects no records")
+    }
+    h.Epoch = h.Epoch.Add(time.Duration(from) * h.Step)
+    if h.Epoch.Nanosecond() != 0 {
+        return fmt.Errorf("slice would start at %s; GTAB epochs are whole seconds", h.Epoch.Format(time.RFC3339Nano))
+    }
+    n, err := copyTables(*out, h, writeOptions([]*ephem.GTAB{g}, *version), []span{{g, from, to}})
+    if err != nil {
+        return err
+    }
+    fmt.Fprintf(stdout, "%s: %d records from %s\n", *out, n, g.TimeAt(from).Format(time.RFC3339))
+    return nil
+}
+
+// recordAtOrAfter returns the index of the first record at or after t.
+func recordAtOrAfter(h ephem.Header, t time.Time) int64 {
+    d := t.Sub(h.Epoch)
+    if d <= 0 {
+        return 0
+    }
+    return int64((d + h.Step - 1) / h.Step)
+}
+
+// recordAtOrBefore returns the index of the last record at or before t; -1
+// if t precedes the epoch.
+func recordAtOrBefore(h ephem.Header, t time.Time) int64 {
+    d := t.Sub(h.Epoch)
+    if d < 0 {
+        return -1 - int64((-d-1)/h.Step)
+    }
+    return int64(d / h.Step)
+}
+
+func cmdMerge(args []string, stdout io.Writer) error {
+    fs := newFlags("merge")
+    out := fs.String("o", "", "output path")
+    version := fs.Int("version", 0, "output format version (default: same as the first input)")
+    if err := parse(fs, args, 2, -1); err != nil {
+        return err
+    }
+    if *out == "" {
+        return fmt.Errorf("%w: -o is required", errUsage)
+    }
+    var tables []*ephem.GTAB
+    defer func() {
+        for _, g := range tables {
+            g.Close()
+        }
+    }()
+    for _, p := range fs.Args() {
+        g, err := ephem.Open(p)
+        if err != nil {
+            return err
+        }
+        tables = append(tables, g)
+    }
+    spans, start, err := planMerge(tables, fs.Args())
+    if err != nil {
+        return err
+    }
+    h := tables[0].Header()
+    h.Epoch = start
+    n, err := copyTables(*out, h, writeOptions(tables, *version), spans)
+    if err != nil {
+        return err
+    }
+    fmt.Fprintf(stdout, "%s: %d records from %s\n", *out, n, ephem.FromScale(h.Epoch, h.Scale).Format(time.RFC3339))
+    return nil
+}
+
+// span is a run of records [from, to] of one table.
+type span struct {
+    g        *ephem.GTAB
+    from, to int64
+}
+
+// planMerge splits the union coverage of tables into spans, each taken from
+// the last table on the command line that covers it, and returns them in time
+// order with the union start. Tables must share step, fields, time scale and
+// grid, and leave no gaps.
+func planMerge(tables []*ephem.GTAB, names []string) ([]span, time.Time, error) {
+    h0 := tables[0].Header()
+    for i, g := range tables[1:] {
+        h := g.Header()
+        if h.Step != h0.Step || h.Fields != h0.Fields || h.Scale != h0.Scale {
+            return nil, time.Time{}, fmt.Errorf("%s: step %s fields %#x scale %s differ from %s: step %s fields %#x scale %s",
+                names[i+1], h.Step, h.Fields, h.Scale, names[0], h0.Step, h0.Fields, h0.Scale)
+        }
+        if h.Epoch.Sub(h0.Epoch)%h0.Step != 0 {
+            return nil, time.Time{}, fmt.Errorf("%s: records are off the grid of %s", names[i+1], names[0])
+        }
+    }
+    start := h0.Epoch
+    for _, g := range tables[1:] {
+        start = minTime(start, g.Header().Epoch)
+    }
+    // grid index of each table's first record and one past its last
+    first := make([]int64, len(tables))
+    cuts := make([]int64, 0, 2*len(tables))
+    for i, g := range tables {
+        first[i] = int64(g.Header().Epoch.Sub(start) / h0.Step)
+        cuts = append(cuts, first[i], first[i]+int64(g.Len()))
+    }
+    sort.Slice(cuts, func(i, j int) bool { return cuts[i] < cuts[j] })
+    var spans []span
+    for k := 0; k+1 < len(cuts); k++ {
+        lo, hi := cuts[k], cuts[k+1]
+        if lo == hi {
+            continue
+        }
+        owner := -1
+        for i, g := range tables {
+            if first[i] <= lo && hi <= first[i]+int64(g.Len()) {
+                owner = i
+            }
+        }
+        if owner < 0 {
+            return nil, time.Time{}, fmt.Errorf("gap at %s", start.Add(time.Duration(lo)*h0.Step).Format(time.RFC3339))
+        }
+        if n := len(spans); n > 0 && spans[n-1].g == tables[owner] {
+            spans[n-1].to = hi - 1 - first[owner]
+            continue
+        }
+        spans = append(spans, span{tables[owner], lo - first[owner], hi - 1 - first[owner]})
+    }
+    return spans, start, nil
+}
+
+func minTime(a, b time.Time) time.Time {
+    if b.Before(a) {
+        return b
+    }
+    return a
+}
+
+// writeOptions keeps the format of the first input unless overridden, and the
+// provenance of the last input that has one.
+func writeOptions(tables []*ephem.GTAB, version int) ephem.WriteOptions {
+    if version == 0 {
+        version = tables[0].Version()
+    }
+    opts := ephem.WriteOptions{Version: version}
+    for _, g := range tables {
+        if prov, ok := g.Provenance(); ok {
+            opts.Provenance = prov
+        }
+    }
+    return opts
+}
+
+// copyTables writes the records of spans, in order, into a new table at path.
+// A table that cannot be copied completely is discarded, leaving any file
+// already at path untouched.
+func copyTables(path string, h ephem.Header, opts ephem.WriteOptions, spans []span) (int, error) {
+    w, err := ephem.CreateWithOptions(path, h, opts)
+    if err != nil {
+        return 0, err
+    }
+    if err := copyRecords(w, spans); err != nil {
+        w.Abort()
+        return 0, err
+    }
+    n := w.Len()
+    return n, w.Close()
+}
+
+func copyRecords(w *ephem.Writer, spans []span) error {
+    for _, sp := range spans {
+        for i := sp.from; i <= sp.to; i++ {
+            s, ok := sp.g.SampleAt(i)
+            if !ok {
+                return fmt.Errorf("record %d unreadable (corrupt chunk?)", i)
+            }
+            if err := w.Write(s); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "math"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/Jthora/autoBotTrader/api/internal/ephem"
+)
+
+var epoch = time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+
+// writeTable writes n minutely records starting at start with tide_bps = base+i.
+func writeTable(t *testing.T, path string, start time.Time, n, base int, version int) {
+    t.Helper()
+    h := ephem.Header{Epoch: start, Step: time.Minute, Fields: ephem.FieldTideBPS | ephem.FieldTideRawF32}
+    w, err := ephem.CreateWithOptions(path, h, ephem.WriteOptions{Version: version, Provenance: ephem.Provenance{DatasetID: "test"}})
+    if err != nil { t.Fatalf("create: %v", err) }
+    for i := 0; i < n; i++ {
+        if err := w.Write(ephem.Sample{TideBPS: uint16(base + i), TideRaw: float32(base+i) * 1e-15}); err != nil { t.Fatalf("write: %v", err) }
+    }
+    if err := w.Close(); err != nil { t.Fatalf("close: %v", err) }
+}
+
+func runCmd(t *testing.T, args ...string) (int, string, string) {
+    t.Helper()
+    var out, errOut bytes.Buffer
+    code := run(args, &out, &errOut)
+    return code, out.String(), errOut.String()
+}
+
+func TestUsageErrors(t *testing.T) {
+    if code, _, _ := runCmd(t); code != 2 { t.Fatalf("no args: exit %d", code) }
+    if code, _, _ := runCmd(t, "bogus"); code != 2 { t.Fatalf("unknown command: exit %d", code) }
+    if code, _, _ := runCmd(t, "diff", "only-one"); code != 2 { t.Fatalf("diff arity: exit %d", code) }
+    if code, out, _ := runCmd(t, "dump", "-h"); code != 0 || !strings.Contains(out, "usage: gtabctl dump") { t.Fatalf("help: exit %d %q", code, out) }
+    if code, _, _ := runCmd(t, "inspect", filepath.Join(t.TempDir(), "missing.bin")); code != 1 { t.Fatalf("missing file: exit %d", code) }
+}
+
+func TestInspectAndDump(t *testing.T) {
+    p := filepath.Join(t.TempDir(), "a.bin")
+    writeTable(t, p, epoch, 10, 100, 2)
+    code, out, _ := runCmd(t, "inspect", p)
+    if code != 0 { t.Fatalf("inspect exit %d", code) }
+    for _, want := range []string{"version:    2", "scale:      UTC", "records:    10", "[tide_bps tide_raw]", "dataset_id: test"} {
+        if !strings.Contains(out, want) { t.Fatalf("inspect output missing %q:\n%s", want, out) }
+    }
+    code, out, _ = runCmd(t, "dump", "-start", "2025-08-01T00:02:00Z", "-end", "2025-08-01T00:04:00Z", "-step", "30s", p)
+    if code != 0 { t.Fatalf("dump exit %d", code) }
+    lines := strings.Split(strings.TrimSpace(out), "\n")
+    if len(lines) != 6 || lines[0] != "time,tide_bps,tide_raw" { t.Fatalf("csv: %q", lines) }
+    if !strings.HasPrefix(lines[2], "2025-08-01T00:02:30Z,103,") { t.Fatalf("interpolated row: %q", lines[2]) }
+    code, out, _ = runCmd(t, "dump", "-format", "ndjson", "-end", "2025-08-01T00:01:00Z", p)
+    if code != 0 { t.Fatalf("ndjson exit %d", code) }
+    var row map[string]any
+    first := strings.SplitN(out, "\n", 2)[0]
+    if err := json.Unmarshal([]byte(first), &row); err != nil || row["tide_bps"] != 100.0 { t.Fatalf("ndjson row %q: %v", first, err) }
+}
+
+func TestInspectReportsGapsAndSliceKeepsThem(t *testing.T) {
+    dir := t.TempDir()
+    p := filepath.Join(dir, "gap.bin")
+    w, err := ephem.CreateWithOptions(p, ephem.Header{Epoch: epoch, Step: time.Minute, Fields: ephem.FieldTideBPS}, ephem.WriteOptions{Version: 2})
+    if err != nil { t.Fatalf("create: %v", err) }
+    for i := 0; i < 20; i++ {
+        if i == 3 || i == 10 || i == 11 { err = w.WriteMissing() } else { err = w.Write(ephem.Sample{TideBPS: uint16(i)}) }
+        if err != nil { t.Fatalf("write: %v", err) }
+    }
+    if err := w.Close(); err != nil { t.Fatalf("close: %v", err) }
+    code, out, _ := runCmd(t, "inspect", p)
+    if code != 0 || !strings.Contains(out, "gaps:       3 missing records in 2 runs, first at 2025-08-01T00:03:00Z") { t.Fatalf("inspect exit %d:\n%s", code, out) }
+    cut := filepath.Join(dir, "cut.bin")
+    if code, _, errOut := runCmd(t, "slice", "-start", "2025-08-01T00:05:00Z", "-end", "2025-08-01T00:15:00Z", "-o", cut, p); code != 0 { t.Fatalf("slice exit %d: %s", code, errOut) }
+    if code, out, _ = runCmd(t, "inspect", cut); code != 0 || !strings.Contains(out, "gaps:       2 missing records in 1 runs") { t.Fatalf("sliced inspect exit %d:\n%s", code, out) }
+}
+
+func TestInspectITAB(t *testing.T) {
+    p := filepath.Join(t.TempDir(), "funding.itab")
+    w, err := ephem.CreateITAB(p, ephem.ITABWriteOptions{Provenance: ephem.Provenance{DatasetID: "funding"}})
+    if err != nil { t.Fatalf("create: %v", err) }
+    for k, v := range []float64{0.5, math.NaN(), -0.25} {
+        if err := w.Write(epoch.Add(time.Duration(k*k)*time.Hour), v); err != nil { t.Fatalf("write: %v", err) }
+    }
+    if err := w.Close(); err != nil { t.Fatalf("close: %v", err) }
+    code, out, _ := runCmd(t, "inspect", p)
+    if code != 0 { t.Fatalf("inspect exit %d", code) }
+    for _, want := range []string{"ITAB 1", "points:     3 (1 missing)", "2025-08-01T04:00:00Z", "values:     -0.25 .. 0.5", "dataset_id: funding"} {
+        if !strings.Contains(out, want) { t.Fatalf("inspect output missing %q:\n%s", want, out) }
+    }
+}
+
+func TestPlanetsWritesPTAB(t *testing.T) {
+    p := filepath.Join(t.TempDir(), "planets.ptab")
+    code, out, errOut := runCmd(t, "planets", "-start", "2025-08-01T00:00:00Z", "-end", "2025-08-03T00:00:00Z", "-step", "6h", "-bodies", "Moon,mars", "-dataset", "p1", "-o", p)
+    if code != 0 || !strings.Contains(out, "9 records of 2 bodies") { t.Fatalf("planets exit %d: %s%s", code, out, errOut) }
+    tab, err := ephem.OpenPTAB(p, ephem.PTABOptions{Verify: ephem.VerifyOnOpen})
+    if err != nil { t.Fatalf("open: %v", err) }
+    at := epoch.Add(9 * time.Hour)
+    if s, ok := tab.Lookup(ephem.BodyMars, at); !ok || math.Abs(s.Lon-ephem.ComputeBodyState(ephem.BodyMars, at).Lon) > 1e-3 { t.Fatalf("mars: %+v %v", s, ok) }
+    _ = tab.Close()
+    code, out, _ = runCmd(t, "inspect", p)
+    if code != 0 { t.Fatalf("inspect exit %d", code) }
+    for _, want := range []string{"PTAB 1", "records:    9", "bodies:     [moon mars]", "dataset_id: p1"} {
+        if !strings.Contains(out, want) { t.Fatalf("inspect output missing %q:\n%s", want, out) }
+    }
+    if code, _, _ := runCmd(t, "planets", "-start", "2025-08-01T00:00:00Z", "-end", "2025-08-02T00:00:00Z", "-bodies", "pluto", "-o", p); code != 2 { t.Fatalf("unknown body: exit %d", code) }
+    if code, _, _ := runCmd(t, "planets", "-start", "2025-08-01T00:00:00Z", "-o", p); code != 2 { t.Fatalf("missing -end: exit %d", code) }
+}
+
+func TestVerify(t *testing.T) {
+    dir := t.TempDir()
+    p := filepath.Join(dir, "gtab_60s.bin")
+    writeTable(t, p, epoch, 5000, 0, 2)
+    meta := filepath.Join(dir, "gtab.meta.json")
+    if err := os.WriteFile(meta, []byte(`{"dataset_id": "test"}`), 0o644); err != nil { t.Fatal(err) }
+    if code, _, _ := runCmd(t, "verify", p); code != 1 { t.Fatalf("unrecorded file: exit %d", code) }
+    if code, _, errOut := runCmd(t, "verify", "-write", p); code != 0 { t.Fatalf("write exit %d: %s", code, errOut) }
+    b, _ := os.ReadFile(meta)
+    if !strings.Contains(string(b), `"dataset_id": "test"`) || !strings.Contains(string(b), `"sha256"`) { t.Fatalf("meta not updated:\n%s", b) }
+    if code, out, _ := runCmd(t, "verify", p); code != 0 || !strings.Contains(out, "OK") { t.Fatalf("verify exit %d: %s", code, out) }
+
+    // flip a byte inside the records: both the digest and the chunk CRC catch it
+    data, _ := os.ReadFile(p)
+    data[len(data)/2] ^= 0xff
+    if err := os.WriteFile(p, data, 0o644); err != nil { t.Fatal(err) }
+    code, out, _ := runCmd(t, "verify", p)
+    if code != 1 || !strings.Contains(out, "sha256") || !strings.Contains(out, "checksum") { t.Fatalf("corrupt file: exit %d: %s", code, out) }
+}
+
+func TestSliceMergeDiff(t *testing.T) {
+    dir := t.TempDir()
+    a, b := filepath.Join(dir, "a.bin"), filepath.Join(dir, "b.bin")
+    writeTable(t, a, epoch, 100, 0, 1)
+    writeTable(t, b, epoch.Add(80*time.Minute), 100, 1000, 3) // overlaps a by 20 records
+
+    s := filepath.Join(dir, "s.bin")
+    if code, _, errOut := runCmd(t, "slice", "-start", "2025-08-01T00:09:30Z", "-end", "2025-08-01T00:20:00Z", "-o", s, a); code != 0 { t.Fatalf("slice exit %d: %s", code, errOut) }
+    g, err := ephem.Open(s)
+    if err != nil { t.Fatal(err) }
+    if st, _ := g.Coverage(); !st.Equal(epoch.Add(10*time.Minute)) || g.Len() != 11 { t.Fatalf("slice coverage %s len %d", st, g.Len()) }
+    if v, _ := g.SampleAt(0); v.TideBPS != 10 { t.Fatalf("slice first record %+v", v) }
+    g.Close()
+
+    m := filepath.Join(dir, "m.bin")
+    if code, _, errOut := runCmd(t, "merge", "-o", m, "-version", "2", a, b); code != 0 { t.Fatalf("merge exit %d: %s", code, errOut) }
+    g, err = ephem.Open(m)
+    if err != nil { t.Fatal(err) }
+    defer g.Close()
+    if g.Len() != 180 || g.Version() != 2 { t.Fatalf("merged len %d version %d", g.Len(), g.Version()) }
+    for i, want := range map[int64]uint16{0: 0, 79: 79, 80: 1000, 179: 1099} {
+        if v, _ := g.SampleAt(i); v.TideBPS != want { t.Fatalf("merged record %d = %d want %d", i, v.TideBPS, want) }
+    }
+    if prov, _ := g.Provenance(); prov.DatasetID != "test" { t.Fatalf("provenance lost: %+v", prov) }
+
+    gap := filepath.Join(dir, "gap.bin")
+    writeTable(t, gap, epoch.Add(300*time.Minute), 10, 0, 1)
+    if code, _, errOut := runCmd(t, "merge", "-o", filepath.Join(dir, "x.bin"), a, gap); code != 1 || !strings.Contains(errOut, "gap") { t.Fatalf("gap merge exit %d: %s", code, errOut) }
+    if _, err := os.Stat(filepath.Join(dir, "x.bin")); !os.IsNotExist(err) { t.Fatal("failed merge left output behind") }
+
+    code, out, _ := runCmd(t, "diff", "-json", a, m)
+    if code != 0 { t.Fatalf("diff exit %d", code) }
+    var rep diffReport
+    if err := json.Unmarshal([]byte(out), &rep); err != nil { t.Fatal(err) }
+    if len(rep.Stats) != 2 || rep.Stats[0].Field != "tide_bps" { t.Fatalf("stats %+v", rep.Stats) }
+    // m replaces a's last 20 records with b's, offset by 1000-80
+    if st := rep.Stats[0]; st.Points != 100 || st.Max != 920 || !st.MaxTime.Equal(epoch.Add(80*time.Minute)) || st.P50 != 0 { t.Fatalf("tide_bps stats %+v", st) }
+}
+
+func TestDiffSkipsGapsInOneTable(t *testing.T) {
+    dir := t.TempDir()
+    a, b := filepath.Join(dir, "a.bin"), filepath.Join(dir, "b.bin")
+    writeTable(t, a, epoch, 10, 0, 2)
+    w, err := ephem.CreateWithOptions(b, ephem.Header{Epoch: epoch, Step: time.Minute, Fields: ephem.FieldTideBPS | ephem.FieldTideRawF32}, ephem.WriteOptions{Version: 2})
+    if err != nil { t.Fatalf("create: %v", err) }
+    for i := 0; i < 10; i++ {
+        if i == 4 { err = w.WriteMissing() } else { err = w.Write(ephem.Sample{TideBPS: uint16(i), TideRaw: float32(i) * 1e-15}) }
+        if err != nil { t.Fatalf("write: %v", err) }
+    }
+    if err := w.Close(); err != nil { t.Fatalf("close: %v", err) }
+    for _, args := range [][]string{{a, b}, {b, a}} {
+        code, out, _ := runCmd(t, "diff", "-json", args[0], args[1])
+        if code != 0 { t.Fatalf("diff exit %d", code) }
+        var rep diffReport
+        if err := json.Unmarshal([]byte(out), &rep); err != nil { t.Fatal(err) }
+        if st := rep.Stats[0]; st.Points != 9 || st.Max != 0 { t.Fatalf("%v: tide_bps stats %+v", args, st) }
+    }
+}
+
+func TestSliceRejectsWindowOutsideTable(t *testing.T) {
+    dir := t.TempDir()
+    src, out := filepath.Join(dir, "src.bin"), filepath.Join(dir, "out.bin")
+    writeTable(t, src, epoch, 100, 0, 1)
+    for _, w := range [][]string{
+        {"-end", "2025-07-31T23:59:30Z"},  // half a step before the epoch
+        {"-end", "2025-07-31T23:00:00Z"},
+        {"-start", "2025-08-01T02:00:00Z"}, // after the last record
+    } {
+        args := append(append([]string{"slice"}, w...), "-o", out, src)
+        if code, _, errOut := runCmd(t, args...); code != 1 || !strings.Contains(errOut, "no records") { t.Fatalf("%v: exit %d: %s", w, code, errOut) }
+        if _, err := os.Stat(out); !os.IsNotExist(err) { t.Fatalf("%v: output written", w) }
+    }
+}
+
+func TestFailedSliceKeepsExistingOutput(t *testing.T) {
+    dir := t.TempDir()
+    src, out := filepath.Join(dir, "src.bin"), filepath.Join(dir, "out.bin")
+    writeTable(t, src, epoch, 100, 0, 2)
+    // corrupt a record so its chunk fails the CRC partway through the copy
+    b, err := os.ReadFile(src)
+    if err != nil { t.Fatal(err) }
+    b[64+6*50] ^= 0xFF
+    if err := os.WriteFile(src, b, 0o644); err != nil { t.Fatal(err) }
+    if err := os.WriteFile(out, []byte("keep"), 0o644); err != nil { t.Fatal(err) }
+    if code, _, errOut := runCmd(t, "slice", "-start", "2025-08-01T00:00:00Z", "-end", "2025-08-01T01:39:00Z", "-o", out, src); code != 1 || !strings.Contains(errOut, "unreadable") { t.Fatalf("slice exit %d: %s", code, errOut) }
+    if got, err := os.ReadFile(out); err != nil || string(got) != "keep" { t.Fatalf("existing output clobbered: %q %v", got, err) }
+    if _, err := os.Stat(out + ".tmp"); !os.IsNotExist(err) { t.Fatal("temporary table left behind") }
+}
+
+func TestSliceAndMergeOnTTScale(t *testing.T) {
+    dir := t.TempDir()
+    tt := filepath.Join(dir, "tt.bin")
+    // record i sits at UTC epoch + i minutes; the TT label of epoch is 69.184s later
+    w, err := ephem.Create(tt, ephem.Header{Epoch: epoch.Add(69 * time.Second), Step: time.Minute, Fields: ephem.FieldTideBPS, Scale: ephem.ScaleTT})
+    if err != nil { t.Fatalf("create: %v", err) }
+    for i := 0; i < 30; i++ {
+        if err := w.Write(ephem.Sample{TideBPS: uint16(i)}); err != nil { t.Fatalf("write: %v", err) }
+    }
+    if err := w.Close(); err != nil { t.Fatalf("close: %v", err) }
+    if code, out, _ := runCmd(t, "inspect", tt); code != 0 || !strings.Contains(out, "scale:      TT") || !strings.Contains(out, "2025-07-31T23:59:59.816Z") { t.Fatalf("inspect exit %d:\n%s", code, out) }
+    s := filepath.Join(dir, "s.bin")
+    if code, _, errOut := runCmd(t, "slice", "-start", "2025-08-01T00:05:00Z", "-end", "2025-08-01T00:10:00Z", "-o", s, tt); code != 0 { t.Fatalf("slice exit %d: %s", code, errOut) }
+    g, err := ephem.Open(s)
+    if err != nil { t.Fatal(err) }
+    if v, _ := g.SampleAt(0); g.Scale() != ephem.ScaleTT || g.Len() != 5 || v.TideBPS != 6 { t.Fatalf("slice scale %s len %d first %+v", g.Scale(), g.Len(), v) }
+    g.Close()
+    utc := filepath.Join(dir, "utc.bin")
+    writeTable(t, utc, epoch, 10, 0, 1)
+    if code, _, errOut := runCmd(t, "merge", "-o", filepath.Join(dir, "x.bin"), tt, utc); code != 1 || !strings.Contains(errOut, "scale") { t.Fatalf("mixed-scale merge exit %d: %s", code, errOut) }
+}
+
+func TestAccuracy(t *testing.T) {
+    dir := t.TempDir()
+    p := filepath.Join(dir, "a.bin")
+    writeTable(t, p, epoch, 200, 0, 2)
+    code, out, errOut := runCmd(t, "accuracy", "-ref", p, p)
+    if code != 0 { t.Fatalf("self reference: exit %d: %s%s", code, out, errOut) }
+    var m map[string]any
+    if err := json.Unmarshal([]byte(out), &m); err != nil { t.Fatalf("metrics json: %v", err) }
+    if m["passes"] != true || m["error_basis"] != "tide_raw" || m["dataset_id"] != "test" || m["table_sha256"] == "" { t.Fatalf("metrics: %v", m) }
+
+    // synthetic values are nowhere near the analytic ephemeris
+    outPath := filepath.Join(dir, "metrics.json")
+    if code, _, _ := runCmd(t, "accuracy", "-out", outPath, p); code != 1 { t.Fatalf("algo reference: exit %d", code) }
+    b, _ := os.ReadFile(outPath)
+    if !strings.Contains(string(b), `"reason": "thresholds_exceeded"`) { t.Fatalf("metrics file:\n%s", b) }
+
+    bad := filepath.Join(dir, "bad.bin")
+    if err := os.WriteFile(bad, []byte("not a table"), 0o644); err != nil { t.Fatal(err) }
+    code, out, _ = runCmd(t, "accuracy", bad)
+    if code != 2 || !strings.Contains(out, `"reason": "bad_header"`) { t.Fatalf("bad header: exit %d: %s", code, out) }
+    if code, _, _ := runCmd(t, "accuracy", "-basis", "moon", p); code != 2 { t.Fatalf("bad basis: exit %d", code) }
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "math"
+    "os"
+    "time"
+
+    "github.com/Jthora/autoBotTrader/api/internal/ephem"
+)
+
+// field describes one GTAB column for printing.
+type field struct {
+    bit  uint32
+    name string
+    get  func(s ephem.Sample) float64
+}
+
+var fields = []field{
+    {ephem.FieldTideBPS, "tide_bps", func(s ephem.Sample) float64 { return float64(s.TideBPS) }},
+    {ephem.FieldTideRawF32, "tide_raw", func(s ephem.Sample) float64 { return float64(s.TideRaw) }},
+    {ephem.FieldMoonRkmF32, "moon_r_km", func(s ephem.Sample) float64 { return float64(s.MoonRkm) }},
+    {ephem.FieldSunRkmF32, "sun_r_km", func(s ephem.Sample) float64 { return float64(s.SunRkm) }},
+    {ephem.FieldMoonRinv3F32, "moon_rinv3", func(s ephem.Sample) float64 { return float64(s.MoonRinv3) }},
+    {ephem.FieldSunRinv3F32, "sun_rinv3", func(s ephem.Sample) float64 { return float64(s.SunRinv3) }},
+    {ephem.FieldTideRawDtF32, "tide_raw_dt", func(s ephem.Sample) float64 { return float64(s.TideRawDt) }},
+}
+
+// present returns the columns carried by mask, in record order.
+func present(mask uint32) []field {
+    var out []field
+    for _, f := range fields {
+        if mask&f.bit != 0 {
+            out = append(out, f)
+        }
+    }
+    return out
+}
+
+func fieldNames(mask uint32) []string {
+    var names []string
+    for _, f := range present(mask) {
+        names = append(names, f.name)
+    }
+    return names
+}
+
+func cmdInspect(args []string, stdout io.Writer) error {
+    fs := newFlags("inspect")
+    if err := parse(fs, args, 1, -1); err != nil {
+        return err
+    }
+    for i, path := range fs.Args() {
+        if i > 0 {
+            fmt.Fprintln(stdout)
+        }
+        if err := inspect(path, stdout); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func inspect(path string, w io.Writer) error {
+    switch magic(path) {
+    case "ITAB1":
+        return inspectITAB(path, w)
+    case "PTAB1":
+        return inspectPTAB(path, w)
+    }
+    g, err := ephem.Open(path)
+    if err != nil {
+        return err
+    }
+    defer g.Close()
+    st, err := os.Stat(path)
+    if err != nil {
+        return err
+    }
+    h := g.Header()
+    start, end := g.Coverage()
+    fmt.Fprintf(w, "file:       %s (%d bytes)\n", path, st.Size())
+    fmt.Fprintf(w, "version:    %d", g.Version())
+    if g.Compressed() {
+        fmt.Fprintf(w, " (compressed)")
+    }
+    fmt.Fprintln(w)
+    fmt.Fprintf(w, "step:       %s\n", h.Step)
+    fmt.Fprintf(w, "scale:      %s\n", h.Scale)
+    fmt.Fprintf(w, "records:    %d\n", g.Len())
+    fmt.Fprintf(w, "coverage:   %s .. %s (%s)\n", start.Format(time.RFC3339Nano), end.Format(time.RFC3339Nano), end.Sub(start))
+    fmt.Fprintf(w, "fields:     %#x %v\n", h.Fields, fieldNames(h.Fields))
+    missing, runs, first := gaps(g)
+    if missing > 0 {
+        fmt.Fprintf(w, "gaps:       %d missing records in %d runs, first at %s\n", missing, runs, first.Format(time.RFC3339Nano))
+    }
+    if prov, ok := g.Provenance(); ok {
+        printProvenance(w, prov)
+    }
+    return nil
+}
+
+// printProvenance prints the provenance lines shared by GTAB and ITAB.
+func printProvenance(w io.Writer, prov ephem.Provenance) {
+    fmt.Fprintf(w, "dataset_id: %s\n", prov.DatasetID)
+    if prov.GeneratorCommit != "" {
+        fmt.Fprintf(w, "generator:  %s\n", prov.GeneratorCommit)
+    }
+    if prov.KernelHash != "" {
+        fmt.Fprintf(w, "kernel:     %s\n", prov.KernelHash)
+    }
+    fmt.Fprintf(w, "created_at: %s\n", prov.CreatedAt.Format(time.RFC3339))
+}
+
+// magic returns the 5-byte format magic of path ("GTAB1", "ITAB1", "PTAB1", ...),
+// or "" if it cannot be read.
+func magic(path string) string {
+    f, err := os.Open(path)
+    if err != nil {
+        return ""
+    }
+    defer f.Close()
+    b := make([]byte, 5)
+    if _, err := io.ReadFull(f, b); err != nil {
+        return ""
+    }
+    return string(b)
+}
+
+func inspectITAB(path string, w io.Writer) error {
+    s, err := ephem.OpenITAB(path, ephem.ITABOptions{})
+    if err != nil {
+        return err
+    }
+    st, err := os.Stat(path)
+    if err != nil {
+        return err
+    }
+    start, end := s.Coverage()
+    missing := 0
+    lo, hi := math.Inf(1), math.Inf(-1)
+    for i := 0; i < s.Len(); i++ {
+        _, v := s.At(i)
+        if math.IsNaN(v) {
+            missing++
+            continue
+        }
+        lo, hi = math.Min(lo, v), math.Max(hi, v)
+    }
+    fmt.Fprintf(w, "file:       %s (%d bytes)\n", path, st.Size())
+    fmt.Fprintf(w, "format:     ITAB 1 (irregular)\n")
+    fmt.Fprintf(w, "scale:      %s\n", s.Scale())
+    fmt.Fprintf(w, "points:     %d", s.Len())
+    if missing > 0 {
+        fmt.Fprintf(w, " (%d missing)", missing)
+    }
+    fmt.Fprintln(w)
+    fmt.Fprintf(w, "coverage:   %s .. %s (%s)\n", start.Format(time.RFC3339Nano), end.Format(time.RFC3339Nano), end.Sub(start))
+    if missing < s.Len() {
+        fmt.Fprintf(w, "values:     %g .. %g\n", lo, hi)
+    }
+    prov, _ := s.Provenance()
+    printProvenance(w, prov)
+    return nil
+}
+
+// gaps counts the missing records of g (see ephem.Sample.Missing) and the runs
+// they form, and returns the time of the first.
+func gaps(g *ephem.GTAB) (missing, runs int, first time.Time) {
+    prev := false
+    for i := 0; i < g.Len(); i++ {
+        s, ok := g.SampleAt(int64(i))
+        gap := ok && s.Missing()
+        if gap {
+            if missing == 0 {
+                first = g.TimeAt(int64(i))
+            }
+            if !prev {
+                runs++
+            }
+            missing++
+        }
+        prev = gap
+    }
+    return missing, runs, first
+}
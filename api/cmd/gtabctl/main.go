@@ -0,0 +1,122 @@
+// Command gtabctl inspects and maintains GTAB ephemeris tables.
+//
+//	gtabctl inspect FILE...
+//	gtabctl verify [-meta gtab.meta.json] [-write] FILE...
+//	gtabctl dump [-format csv|ndjson] [-start T] [-end T] [-step D] [-interp MODE] FILE
+//	gtabctl slice -start T -end T -o OUT [-version N] FILE
+//	gtabctl merge -o OUT [-version N] FILE...
+//	gtabctl diff [-step D] [-json] A B
+//	gtabctl planets -start T -end T -o OUT [-step D] [-scale S] [-bodies LIST] [-dataset ID]
+//	gtabctl accuracy [-ref algo|FILE] [-basis B] [-start T] [-end T] [-cadence D] [-thresholds PATH] [-out PATH] FILE
+//
+// Times are RFC 3339, durations Go syntax (e.g. 1s, 15m).
+package main
+
+import (
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+)
+
+// errUsage marks command-line mistakes (exit status 2).
+var errUsage = errors.New("usage")
+
+// exitError carries an explicit exit status; its message, if any, is printed.
+type exitError struct {
+    code int
+    err  error
+}
+
+func (e exitError) Error() string {
+    if e.err == nil {
+        return fmt.Sprintf("exit status %d", e.code)
+    }
+    return e.err.Error()
+}
+
+func main() {
+    os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+type command struct {
+    name  string
+    usage string
+    run   func(args []string, stdout io.Writer) error
+}
+
+var commands = []command{
+    {"inspect", "inspect FILE...  print header, coverage and provenance (GTAB, ITAB or PTAB)", cmdInspect},
+    {"verify", "verify [-meta PATH] [-write] FILE...  check size, sha256 and embedded checksums", cmdVerify},
+    {"dump", "dump [-format csv|ndjson] [-start T] [-end T] [-step D] [-interp MODE] FILE  print samples", cmdDump},
+    {"slice", "slice -start T -end T -o OUT [-version N] FILE  copy a time window into a new table", cmdSlice},
+    {"merge", "merge -o OUT [-version N] FILE...  join adjacent or overlapping tables", cmdMerge},
+    {"diff", "diff [-step D] [-json] A B  compare two tables over their common coverage", cmdDiff},
+    {"planets", "planets -start T -end T -o OUT [-step D] [-scale S] [-bodies LIST] [-dataset ID]  write a PTAB planetary table from the analytic ephemeris", cmdPlanets},
+    {"accuracy", "accuracy [-ref algo|FILE] [-basis tide_bps|tide_raw] [-start T] [-end T] [-cadence D] [-thresholds PATH] [-out PATH] FILE  accuracy metrics (exit 1 unless passing)", cmdAccuracy},
+}
+
+// run executes one subcommand and returns the process exit status.
+func run(args []string, stdout, stderr io.Writer) int {
+    if len(args) == 0 {
+        printUsage(stderr)
+        return 2
+    }
+    for _, c := range commands {
+        if c.name != args[0] {
+            continue
+        }
+        err := c.run(args[1:], stdout)
+        var ee exitError
+        switch {
+        case err == nil:
+            return 0
+        case errors.As(err, &ee):
+            if ee.err != nil {
+                fmt.Fprintf(stderr, "gtabctl %s: %v\n", c.name, ee.err)
+            }
+            return ee.code
+        case errors.Is(err, flag.ErrHelp):
+            fmt.Fprintf(stdout, "usage: gtabctl %s\n", c.usage)
+            return 0
+        case errors.Is(err, errUsage):
+            fmt.Fprintf(stderr, "gtabctl %s: %v\nusage: gtabctl %s\n", c.name, err, c.usage)
+            return 2
+        default:
+            fmt.Fprintf(stderr, "gtabctl %s: %v\n", c.name, err)
+            return 1
+        }
+    }
+    fmt.Fprintf(stderr, "gtabctl: unknown command %q\n", args[0])
+    printUsage(stderr)
+    return 2
+}
+
+func printUsage(w io.Writer) {
+    fmt.Fprintln(w, "usage: gtabctl <command> [flags] [args]")
+    for _, c := range commands {
+        fmt.Fprintf(w, "  %s\n", c.usage)
+    }
+}
+
+// newFlags returns a FlagSet that reports errors instead of exiting.
+func newFlags(name string) *flag.FlagSet {
+    fs := flag.NewFlagSet(name, flag.ContinueOnError)
+    fs.SetOutput(io.Discard)
+    return fs
+}
+
+// parse parses flags and checks the number of positional arguments (max<0: unbounded).
+func parse(fs *flag.FlagSet, args []string, minArgs, maxArgs int) error {
+    if err := fs.Parse(args); err != nil {
+        if errors.Is(err, flag.ErrHelp) {
+            return err
+        }
+        return fmt.Errorf("%w: %v", errUsage, err)
+    }
+    if n := fs.NArg(); n < minArgs || (maxArgs >= 0 && n > maxArgs) {
+        return fmt.Errorf("%w: wrong number of arguments", errUsage)
+    }
+    return nil
+}
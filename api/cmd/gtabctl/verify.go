@@ -0,0 +1,132 @@
+package main
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+
+    "github.com/Jthora/autoBotTrader/api/internal/ephem"
+)
+
+// fileDigest is the per-file entry under "files" in gtab.meta.json.
+type fileDigest struct {
+    Size   int64  `json:"size"`
+    SHA256 string `json:"sha256"`
+}
+
+func cmdVerify(args []string, stdout io.Writer) error {
+    fs := newFlags("verify")
+    metaPath := fs.String("meta", "", "metadata file (default: gtab.meta.json beside the first FILE)")
+    write := fs.Bool("write", false, "record size and sha256 of FILE... in the metadata instead of checking")
+    if err := parse(fs, args, 1, -1); err != nil {
+        return err
+    }
+    if *metaPath == "" {
+        *metaPath = filepath.Join(filepath.Dir(fs.Arg(0)), "gtab.meta.json")
+    }
+    meta, err := readMeta(*metaPath, *write)
+    if err != nil {
+        return err
+    }
+    files := map[string]fileDigest{}
+    if raw, ok := meta["files"]; ok {
+        if err := json.Unmarshal(raw, &files); err != nil {
+            return fmt.Errorf("%s: decode files: %w", *metaPath, err)
+        }
+    }
+    failed := 0
+    for _, path := range fs.Args() {
+        name := filepath.Base(path)
+        got, err := digest(path)
+        if err != nil {
+            return err
+        }
+        if *write {
+            files[name] = got
+            fmt.Fprintf(stdout, "%s: recorded size=%d sha256=%s\n", name, got.Size, got.SHA256)
+            continue
+        }
+        if problems := verifyOne(path, got, files); len(problems) > 0 {
+            failed++
+            for _, p := range problems {
+                fmt.Fprintf(stdout, "%s: FAIL %s\n", name, p)
+            }
+            continue
+        }
+        fmt.Fprintf(stdout, "%s: OK size=%d sha256=%s\n", name, got.Size, got.SHA256)
+    }
+    if *write {
+        b, err := json.Marshal(files)
+        if err != nil {
+            return err
+        }
+        meta["files"] = b
+        out, err := json.MarshalIndent(meta, "", "  ")
+        if err != nil {
+            return err
+        }
+        return os.WriteFile(*metaPath, append(out, '\n'), 0o644)
+    }
+    if failed > 0 {
+        return fmt.Errorf("%d of %d files failed verification", failed, fs.NArg())
+    }
+    return nil
+}
+
+// readMeta loads the metadata as raw keys so unknown keys survive a rewrite.
+func readMeta(path string, allowMissing bool) (map[string]json.RawMessage, error) {
+    b, err := os.ReadFile(path)
+    if errors.Is(err, os.ErrNotExist) && allowMissing {
+        return map[string]json.RawMessage{}, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    meta := map[string]json.RawMessage{}
+    if err := json.Unmarshal(b, &meta); err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+    return meta, nil
+}
+
+// verifyOne checks a table against its recorded digest and its own structure.
+func verifyOne(path string, got fileDigest, files map[string]fileDigest) []string {
+    var problems []string
+    want, ok := files[filepath.Base(path)]
+    switch {
+    case !ok:
+        problems = append(problems, "no size/sha256 recorded in metadata")
+    case want.Size != got.Size:
+        problems = append(problems, fmt.Sprintf("size %d, metadata says %d", got.Size, want.Size))
+    case want.SHA256 != got.SHA256:
+        problems = append(problems, fmt.Sprintf("sha256 %s, metadata says %s", got.SHA256, want.SHA256))
+    }
+    g, err := ephem.Open(path)
+    if err != nil {
+        return append(problems, err.Error())
+    }
+    defer g.Close()
+    if err := g.Verify(); err != nil && !errors.Is(err, ephem.ErrNoChecksums) {
+        problems = append(problems, err.Error())
+    }
+    return problems
+}
+
+func digest(path string) (fileDigest, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return fileDigest{}, err
+    }
+    defer f.Close()
+    h := sha256.New()
+    n, err := io.Copy(h, f)
+    if err != nil {
+        return fileDigest{}, err
+    }
+    return fileDigest{Size: n, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
@@ -6,10 +6,15 @@ import (
     "net/http"
     "os"
     "os/signal"
+    "strconv"
+    "strings"
     "syscall"
     "time"
 
+    "github.com/Jthora/autoBotTrader/api/internal/accuracy"
     "github.com/Jthora/autoBotTrader/api/internal/chain"
+    "github.com/Jthora/autoBotTrader/api/internal/ephem"
+    "github.com/Jthora/autoBotTrader/api/internal/ephemdata"
     httpapi "github.com/Jthora/autoBotTrader/api/internal/http"
     "github.com/Jthora/autoBotTrader/api/internal/providers"
 )
@@ -21,27 +26,103 @@ func main() {
     // Select grav provider
     var grav providers.GravimetricProvider = providers.MockGravimetric{}
     gravMode := "mock"
-    if os.Getenv("EPHEM_MODE") == "file" {
-        table := os.Getenv("EPHEM_TABLE_PATH")
-        if table == "" {
-            // Fallback search: ./ephem/gtab_1s.bin relative to working dir
-            if _, err := os.Stat("./ephem/gtab_1s.bin"); err == nil {
-                table = "./ephem/gtab_1s.bin"
+    if os.Getenv("EPHEM_MODE") == "file" && os.Getenv("EPHEM_TABLE_DIR") != "" {
+        // EPHEM_TABLE_DIR serves a rotating directory of tables; new files are picked up every EPHEM_CATALOG_POLL (default 1m)
+        dir := os.Getenv("EPHEM_TABLE_DIR")
+        if fg, err := providers.NewFileGravimetricCatalog(dir, os.Getenv("EPHEM_DATASET_ID")); err == nil {
+            poll := time.Minute
+            if d, err := time.ParseDuration(os.Getenv("EPHEM_CATALOG_POLL")); err == nil && d > 0 { poll = d }
+            fg.Watch(poll, func(changed bool, err error) {
+                if err != nil { log.Printf("[ephem] catalog refresh %s: %v", dir, err) }
+                if changed { log.Printf("[ephem] catalog %s: table set updated", dir) }
+            })
+            grav = fg
+            gravMode = "file"
+        } else {
+            log.Printf("[startup] EPHEM_MODE=file but catalog init failed (dir=%s): %v — falling back to mock", dir, err)
+        }
+    } else if os.Getenv("EPHEM_MODE") == "file" {
+        // EPHEM_TABLE_PATH may list several cadences (comma-separated), e.g. gtab_1s.bin,gtab_60s.bin
+        var tables []string
+        for _, p := range strings.Split(os.Getenv("EPHEM_TABLE_PATH"), ",") {
+            if p = strings.TrimSpace(p); p != "" { tables = append(tables, p) }
+        }
+        var embedded *providers.FileGravimetric
+        if len(tables) == 0 && ephemdata.FS != nil {
+            // Built with -tags embedephem: the embedded dataset wins over the ./ephem search
+            fg, err := providers.NewFileGravimetricFS(ephemdata.FS, os.Getenv("EPHEM_DATASET_ID"))
+            if err == nil { embedded = fg } else { log.Printf("[startup] embedded ephemeris dataset unusable: %v — trying ./ephem", err) }
+        }
+        if len(tables) == 0 && embedded == nil {
+            // Fallback search: ./ephem/gtab_1s.bin and ./ephem/gtab_60s.bin relative to working dir
+            for _, p := range []string{"./ephem/gtab_1s.bin", "./ephem/gtab_60s.bin"} {
+                if _, err := os.Stat(p); err == nil { tables = append(tables, p) }
             }
         }
-        if table != "" {
-            if fg, err := providers.NewFileGravimetric(table, os.Getenv("EPHEM_DATASET_ID")); err == nil {
+        switch {
+        case embedded != nil:
+            log.Printf("[startup] serving embedded ephemeris dataset (dataset=%s)", embedded.DatasetID())
+            grav = embedded
+            gravMode = "file"
+        case len(tables) > 0:
+            if fg, err := providers.NewFileGravimetricPyramid(tables, os.Getenv("EPHEM_DATASET_ID")); err == nil {
+                // Replaced tables and gtab.meta.json are reloaded every EPHEM_RELOAD_POLL (default 1m, 0 disables)
+                poll := time.Minute
+                if d, err := time.ParseDuration(os.Getenv("EPHEM_RELOAD_POLL")); err == nil && d >= 0 { poll = d }
+                if poll > 0 {
+                    fg.Watch(poll, func(changed bool, err error) {
+                        if err != nil { log.Printf("[ephem] reload %s: %v", strings.Join(tables, ","), err) }
+                        if changed { log.Printf("[ephem] reloaded %s (dataset=%s)", strings.Join(tables, ","), fg.DatasetID()) }
+                    })
+                }
                 grav = fg
                 gravMode = "file"
             } else {
-                log.Printf("[startup] EPHEM_MODE=file but init failed (table=%s): %v — falling back to mock", table, err)
+                log.Printf("[startup] EPHEM_MODE=file but init failed (tables=%s): %v — falling back to mock", strings.Join(tables, ","), err)
             }
-        } else {
-            log.Printf("[startup] EPHEM_MODE=file but EPHEM_TABLE_PATH empty and ./ephem/gtab_1s.bin not found — using mock")
+        default:
+            log.Printf("[startup] EPHEM_MODE=file but EPHEM_TABLE_PATH empty and ./ephem/gtab_{1s,60s}.bin not found — using mock")
         }
     }
+    if os.Getenv("EPHEM_MODE") == "algo" {
+        // Pure-Go Sun/Moon ephemeris: no tables, never stale
+        grav = providers.NewAlgoGravimetric()
+        gravMode = "algo"
+    }
     log.Printf("[startup] grav provider mode: %s", gravMode)
-    h := &httpapi.Handlers{Astro: providers.MockAstrology{}, Grav: grav, Chain: chainClient}
+    // Astrology defaults to the offline planetary ephemeris; ASTRO_MODE=file serves the PTAB
+    // table at ASTRO_TABLE_PATH and ASTRO_MODE=mock restores random values
+    aspects, weight := aspectsFromEnv()
+    var astro providers.AstrologyProvider = providers.AlgoAstrology{Aspects: aspects, AspectWeight: weight}
+    astroMode := "algo"
+    switch os.Getenv("ASTRO_MODE") {
+    case "mock":
+        astro = providers.MockAstrology{}
+        astroMode = "mock"
+    case "file":
+        path := os.Getenv("ASTRO_TABLE_PATH")
+        if fa, err := providers.NewFileAstrology(path, os.Getenv("ASTRO_DATASET_ID")); err == nil {
+            fa.Aspects, fa.AspectWeight = aspects, weight
+            astro = fa
+            astroMode = "file"
+        } else {
+            log.Printf("[startup] ASTRO_MODE=file but table init failed (path=%s): %v — falling back to algo", path, err)
+        }
+    }
+    log.Printf("[startup] astro provider mode: %s", astroMode)
+    // retries and circuit breakers around both sources; the raw providers are kept for shutdown
+    policy := policyFromEnv()
+    rAstro, rGrav := providers.NewResilientAstrology(astro, policy), providers.NewResilientGravimetric(grav, policy)
+    signals := providers.DefaultRegistry(rAstro, rGrav)
+    if s := os.Getenv("SIGNALS"); s != "" {
+        if err := signals.Configure(s); err != nil { log.Printf("[startup] SIGNALS=%q ignored: %v", s, err) }
+    }
+    for _, sig := range signals.Enabled() { log.Printf("[startup] signal %s (provider=%s weight=%d)", sig.Name, sig.Provider, sig.Weight) }
+    h := &httpapi.Handlers{Astro: rAstro, Grav: rGrav, Chain: chainClient, Signals: signals}
+    if fg, ok := grav.(*providers.FileGravimetric); ok {
+        // EPHEM_SELFCHECK (e.g. 6h) compares the served dataset with the analytic ephemeris at startup and on that interval
+        h.Accuracy = startSelfCheck(fg)
+    }
     mux := httpapi.NewRouter(h)
     port := os.Getenv("API_PORT")
     if port == "" { port = "8080" }
@@ -72,12 +153,76 @@ func main() {
     if err := srv.Shutdown(ctx); err != nil {
         log.Printf("server shutdown error: %v", err)
     }
-    // Close file-backed provider if present
-    if c, ok := grav.(interface{ Close() error }); ok {
-        if err := c.Close(); err != nil {
-            log.Printf("provider close error: %v", err)
+    h.Accuracy.Stop()
+    // Close file-backed providers if present
+    for _, p := range []any{grav, astro} {
+        if c, ok := p.(interface{ Close() error }); ok {
+            if err := c.Close(); err != nil {
+                log.Printf("provider close error: %v", err)
+            }
         }
     }
     log.Printf("shutdown complete")
 }
 
+// aspectsFromEnv reads the aspect configuration: ASTRO_ORBS overrides the
+// default orbs (e.g. "square:6,sextile:4") and ASTRO_ASPECT_WEIGHT (0..1,
+// default 0) blends the aspect index into volatility_index.
+func aspectsFromEnv() (providers.AspectOptions, float64) {
+    var opts providers.AspectOptions
+    var weight float64
+    if s := os.Getenv("ASTRO_ORBS"); s != "" {
+        if orbs, err := providers.ParseOrbs(s); err == nil { opts = opts.With(orbs) } else { log.Printf("[startup] ASTRO_ORBS: %v — using default orbs", err) }
+    }
+    if s := os.Getenv("ASTRO_ASPECT_WEIGHT"); s != "" {
+        if w, err := strconv.ParseFloat(s, 64); err == nil && w >= 0 && w <= 1 { weight = w } else { log.Printf("[startup] ASTRO_ASPECT_WEIGHT=%q ignored: want a number in [0, 1]", s) }
+    }
+    return opts, weight
+}
+
+// policyFromEnv reads the provider retry and circuit breaker policy from
+// PROVIDER_POLICY (e.g. "attempts:4,backoff:50ms,threshold:3,cooldown:1m";
+// see providers.ParsePolicy) and logs circuit transitions.
+func policyFromEnv() providers.Policy {
+    policy := providers.DefaultPolicy
+    if s := os.Getenv("PROVIDER_POLICY"); s != "" {
+        if p, err := providers.ParsePolicy(s); err == nil { policy = p } else { log.Printf("[startup] PROVIDER_POLICY: %v — using defaults", err) }
+    }
+    policy.OnStateChange = func(name string, from, to providers.CircuitState, err error) {
+        if err != nil {
+            log.Printf("[provider] %s circuit %s -> %s: %v", name, from, to, err)
+            return
+        }
+        log.Printf("[provider] %s circuit %s -> %s", name, from, to)
+    }
+    return policy
+}
+
+// startSelfCheck schedules the accuracy harness against fg when EPHEM_SELFCHECK
+// is a positive duration. The window (EPHEM_SELFCHECK_WINDOW, default 7 days)
+// ends at each run and is clipped to the dataset's coverage; sampling is hourly since extrema cannot be timed to the
+// spec's 120s from denser samples of a flat peak.
+func startSelfCheck(fg *providers.FileGravimetric) *accuracy.SelfCheck {
+    every, err := time.ParseDuration(os.Getenv("EPHEM_SELFCHECK"))
+    if err != nil || every <= 0 { return nil }
+    window := 7 * 24 * time.Hour
+    if d, err := time.ParseDuration(os.Getenv("EPHEM_SELFCHECK_WINDOW")); err == nil && d > 0 { window = d }
+    basis := os.Getenv("EPHEM_SELFCHECK_BASIS")
+    if basis == "" { basis = accuracy.BasisTideRaw }
+    table, err := accuracy.SampleFunc(fg.SampleAt, basis)
+    if err != nil { log.Printf("[startup] EPHEM_SELFCHECK_BASIS: %v — self-check disabled", err); return nil }
+    ref, _ := accuracy.AlgoFunc(basis, ephem.DefaultScale)
+    cfg := accuracy.Config{Cadence: time.Hour, Basis: basis, Thresholds: accuracy.DefaultThresholds}
+    sc := accuracy.NewSelfCheck(table, ref, cfg, window, fg.DatasetID)
+    sc.ClipTo(fg.Coverage)
+    sc.Start(every, func(m accuracy.Metrics) {
+        if m.Skipped {
+            log.Printf("[ephem] self-check skipped (dataset=%s): %s", m.DatasetID, m.Notes)
+        } else if m.Passes {
+            log.Printf("[ephem] self-check passed (dataset=%s rel_error_p99=%.2e peak_drift_p99=%.0fs)", m.DatasetID, m.Stats.RelErrorP99, m.Stats.PeakDriftSecondsP99)
+        } else {
+            log.Printf("[ephem] self-check FAILED (dataset=%s reason=%s): %s", m.DatasetID, m.Reason, m.Notes)
+        }
+    })
+    return sc
+}
@@ -0,0 +1,294 @@
+// Package accuracy compares a GTAB dataset against a reference source and
+// reports the metrics of docs/perf/ACCURACY_HARNESS_SPEC.md in the shape of
+// scripts/ephem/validation/metrics.schema.json. It is the Go counterpart of
+// compute_metrics.py and needs no Skyfield: the reference is another table or
+// the analytic ephemeris in package ephem.
+package accuracy
+
+import (
+    "fmt"
+    "math"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/Jthora/autoBotTrader/api/internal/ephem"
+)
+
+// Compared quantities (metrics "error_basis").
+const (
+    BasisTideBPS = "tide_bps"
+    BasisTideRaw = "tide_raw"
+)
+
+// Reasons reported when a run cannot pass regardless of its statistics.
+const (
+    ReasonBadHeader           = "bad_header"
+    ReasonCoverageGap         = "coverage_gap"
+    ReasonInsufficientSamples = "insufficient_samples"
+    ReasonThresholds          = "thresholds_exceeded"
+)
+
+// minSamples is the fewest compared points a run needs to pass.
+const minSamples = 10
+
+// Thresholds are the hard limits of a passing run. The first three are the
+// schema's; PeakValueRelDriftP99Max is the spec's fourth (0 disables it).
+type Thresholds struct {
+    RelErrorMedianMax       float64 `json:"rel_error_median_max"`
+    RelErrorP99Max          float64 `json:"rel_error_p99_max"`
+    PeakDriftSecondsP99Max  float64 `json:"peak_drift_seconds_p99_max"`
+    PeakValueRelDriftP99Max float64 `json:"peak_value_rel_drift_p99_max,omitempty"`
+}
+
+// DefaultThresholds are the spec defaults.
+var DefaultThresholds = Thresholds{
+    RelErrorMedianMax:       1.0e-3,
+    RelErrorP99Max:          5.0e-3,
+    PeakDriftSecondsP99Max:  120,
+    PeakValueRelDriftP99Max: 2.0e-3,
+}
+
+// Range is the compared time window.
+type Range struct {
+    Start time.Time `json:"start"`
+    End   time.Time `json:"end"`
+}
+
+// Stats holds the error distribution of a run. The schema requires the
+// median/p95/p99 relative error and the median/p99 peak drift; the rest are
+// the spec's informational metrics.
+type Stats struct {
+    RelErrorMean   float64 `json:"rel_error_mean"`
+    RelErrorMedian float64 `json:"rel_error_median"`
+    RelErrorP95    float64 `json:"rel_error_p95"`
+    RelErrorP99    float64 `json:"rel_error_p99"`
+    RelErrorMax    float64 `json:"rel_error_max"`
+    AbsErrorMean   float64 `json:"abs_error_mean"`
+    AbsErrorP95    float64 `json:"abs_error_p95"`
+    AbsErrorP99    float64 `json:"abs_error_p99"`
+    AbsErrorMax    float64 `json:"abs_error_max"`
+
+    PeakCount              int     `json:"peak_count"`     // extrema in the reference
+    PeakUnmatched          int     `json:"peak_unmatched"` // of those, none in the table within 2 cadences
+    PeakDriftSecondsMean   float64 `json:"peak_drift_seconds_mean"`
+    PeakDriftSecondsMedian float64 `json:"peak_drift_seconds_median"`
+    PeakDriftSecondsP95    float64 `json:"peak_drift_seconds_p95"`
+    PeakDriftSecondsP99    float64 `json:"peak_drift_seconds_p99"`
+    PeakDriftSecondsMax    float64 `json:"peak_drift_seconds_max"`
+    PeakValueRelDriftMean  float64 `json:"peak_value_rel_drift_mean"`
+    PeakValueRelDriftP95   float64 `json:"peak_value_rel_drift_p95"`
+    PeakValueRelDriftP99   float64 `json:"peak_value_rel_drift_p99"`
+    PeakValueRelDriftMax   float64 `json:"peak_value_rel_drift_max"`
+}
+
+// Metrics is one harness run, encoded per metrics.schema.json.
+type Metrics struct {
+    DatasetID      string     `json:"dataset_id"`
+    GeneratedAt    time.Time  `json:"generated_at"`
+    Range          Range      `json:"range"`
+    CadenceSeconds int        `json:"cadence_seconds"`
+    Samples        int        `json:"samples"`
+    ErrorBasis     string     `json:"error_basis"`
+    Stats          Stats      `json:"stats"`
+    Thresholds     Thresholds `json:"thresholds"`
+    Passes         bool       `json:"passes"`
+    // Skipped marks a scheduled run that compared nothing (see SelfCheck); it
+    // neither passes nor fails and has no statistics.
+    Skipped bool `json:"skipped,omitempty"`
+    Reason         string     `json:"reason,omitempty"`
+    Notes          string     `json:"notes,omitempty"`
+    TableSHA256    string     `json:"table_sha256,omitempty"`
+}
+
+// Config parameterizes Run.
+type Config struct {
+    DatasetID string
+    Start     time.Time
+    End       time.Time
+    // Cadence is the sampling step; 0 means 60s.
+    Cadence time.Duration
+    // Basis labels the compared quantity; "" means tide_bps.
+    Basis string
+    // Thresholds; the zero value means DefaultThresholds.
+    Thresholds Thresholds
+}
+
+// Failed returns metrics for a run that could not sample at all, e.g. a
+// table with an invalid header.
+func Failed(cfg Config, reason, notes string) Metrics {
+    m, _ := newMetrics(cfg)
+    m.Reason, m.Notes = reason, notes
+    return m
+}
+
+// newMetrics fills in cfg's defaults and the run-independent metrics fields.
+func newMetrics(cfg Config) (Metrics, Config) {
+    if cfg.Cadence <= 0 {
+        cfg.Cadence = time.Minute
+    }
+    if cfg.Basis == "" {
+        cfg.Basis = BasisTideBPS
+    }
+    if cfg.Thresholds == (Thresholds{}) {
+        cfg.Thresholds = DefaultThresholds
+    }
+    return Metrics{
+        DatasetID:      cfg.DatasetID,
+        GeneratedAt:    time.Now().UTC().Truncate(time.Second),
+        Range:          Range{Start: cfg.Start.UTC(), End: cfg.End.UTC()},
+        CadenceSeconds: max(1, int(math.Round(cfg.Cadence.Seconds()))),
+        ErrorBasis:     cfg.Basis,
+        Thresholds:     cfg.Thresholds,
+    }, cfg
+}
+
+// Run samples table and ref every cfg.Cadence over [cfg.Start, cfg.End] and
+// applies the thresholds. Errors are |table−ref|, relative to |ref| (at least 1
+// for tide_bps); the first and last sample are left out of the error stats.
+// Extrema of both series are located with ephem.FindEvents and each reference
+// extremum is matched to the nearest table extremum of the same kind within
+// two cadences. Points where either source has no data make the run fail with
+// ReasonCoverageGap.
+func Run(table, ref ephem.TideFunc, cfg Config) Metrics {
+    m, cfg := newMetrics(cfg)
+    step := cfg.Cadence
+    floor := 1.0
+    if m.ErrorBasis != BasisTideBPS {
+        floor = math.SmallestNonzeroFloat64
+    }
+    var rel, abs []float64
+    gaps := 0
+    var ts []time.Time
+    for t := m.Range.Start; !t.After(m.Range.End); t = t.Add(step) {
+        ts = append(ts, t)
+    }
+    for i, t := range ts {
+        a, okA := ref(t)
+        g, okG := table(t)
+        if !okA || !okG {
+            gaps++
+            continue
+        }
+        if i == 0 || i == len(ts)-1 {
+            continue
+        }
+        e := math.Abs(g - a)
+        abs = append(abs, e)
+        rel = append(rel, e/math.Max(math.Abs(a), floor))
+    }
+    m.Samples = len(rel)
+    s := &m.Stats
+    s.RelErrorMean, s.RelErrorMedian, s.RelErrorP95, s.RelErrorP99, s.RelErrorMax = summarize(rel)
+    s.AbsErrorMean, _, s.AbsErrorP95, s.AbsErrorP99, s.AbsErrorMax = summarize(abs)
+
+    drift, valDrift, count, unmatched := matchPeaks(table, ref, m.Range, step, floor)
+    s.PeakCount, s.PeakUnmatched = count, unmatched
+    s.PeakDriftSecondsMean, s.PeakDriftSecondsMedian, s.PeakDriftSecondsP95, s.PeakDriftSecondsP99, s.PeakDriftSecondsMax = summarize(drift)
+    s.PeakValueRelDriftMean, _, s.PeakValueRelDriftP95, s.PeakValueRelDriftP99, s.PeakValueRelDriftMax = summarize(valDrift)
+
+    var notes []string
+    if unmatched > 0 {
+        notes = append(notes, fmt.Sprintf("%d of %d reference extrema unmatched within %s", unmatched, count, 2*step))
+    }
+    switch {
+    case gaps > 0:
+        m.Reason = ReasonCoverageGap
+        notes = append(notes, fmt.Sprintf("%d of %d points without data", gaps, len(ts)))
+    case m.Samples < minSamples:
+        m.Reason = ReasonInsufficientSamples
+    default:
+        if failed := m.Thresholds.exceeded(*s); len(failed) > 0 {
+            m.Reason = ReasonThresholds
+            notes = append(notes, "exceeded: "+strings.Join(failed, ", "))
+        } else {
+            m.Passes = true
+        }
+    }
+    m.Notes = strings.Join(notes, "; ")
+    return m
+}
+
+// exceeded lists the thresholds s violates; unmatched extrema always fail.
+func (th Thresholds) exceeded(s Stats) []string {
+    var out []string
+    if s.RelErrorMedian > th.RelErrorMedianMax {
+        out = append(out, "rel_error_median")
+    }
+    if s.RelErrorP99 > th.RelErrorP99Max {
+        out = append(out, "rel_error_p99")
+    }
+    if s.PeakDriftSecondsP99 > th.PeakDriftSecondsP99Max {
+        out = append(out, "peak_drift_seconds_p99")
+    }
+    if th.PeakValueRelDriftP99Max > 0 && s.PeakValueRelDriftP99 > th.PeakValueRelDriftP99Max {
+        out = append(out, "peak_value_rel_drift_p99")
+    }
+    if s.PeakUnmatched > 0 {
+        // an extremum displaced by more than two cadences has no drift to measure
+        out = append(out, "peak_unmatched")
+    }
+    return out
+}
+
+// matchPeaks returns the timing drift (seconds) and relative value drift of
+// each matched reference extremum, the number of reference extrema and how
+// many found no partner.
+func matchPeaks(table, ref ephem.TideFunc, r Range, step time.Duration, floor float64) (drift, valDrift []float64, count, unmatched int) {
+    opts := ephem.EventOptions{Step: step, Kinds: ephem.EventMax | ephem.EventMin}
+    want, err := ephem.FindEvents(ref, r.Start, r.End, opts)
+    if err != nil {
+        return nil, nil, 0, 0
+    }
+    got, err := ephem.FindEvents(table, r.Start, r.End, opts)
+    if err != nil {
+        return nil, nil, len(want), len(want)
+    }
+    for _, w := range want {
+        best, bestDt := -1, 2*step
+        for j, g := range got {
+            if g.Kind != w.Kind {
+                continue
+            }
+            dt := g.Time.Sub(w.Time)
+            if dt < 0 {
+                dt = -dt
+            }
+            if dt <= bestDt {
+                best, bestDt = j, dt
+            }
+        }
+        if best < 0 {
+            unmatched++
+            continue
+        }
+        drift = append(drift, bestDt.Seconds())
+        valDrift = append(valDrift, math.Abs(got[best].Value-w.Value)/math.Max(math.Abs(w.Value), floor))
+    }
+    return drift, valDrift, len(want), unmatched
+}
+
+// summarize returns mean, median, p95, p99 and max of v (zeros when empty).
+func summarize(v []float64) (mean, median, p95, p99, maxV float64) {
+    if len(v) == 0 {
+        return
+    }
+    s := append([]float64(nil), v...)
+    sort.Float64s(s)
+    var sum float64
+    for _, x := range s {
+        sum += x
+    }
+    return sum / float64(len(s)), percentile(s, 0.50), percentile(s, 0.95), percentile(s, 0.99), s[len(s)-1]
+}
+
+// percentile interpolates linearly between closest ranks, like compute_metrics.py.
+func percentile(sorted []float64, p float64) float64 {
+    k := float64(len(sorted)-1) * p
+    f := int(math.Floor(k))
+    c := min(f+1, len(sorted)-1)
+    if f == c {
+        return sorted[f]
+    }
+    return sorted[f]*(float64(c)-k) + sorted[c]*(k-float64(f))
+}
@@ -0,0 +1,130 @@
+package accuracy
+
+import (
+    "encoding/json"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/Jthora/autoBotTrader/api/internal/ephem"
+)
+
+var epoch = time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+
+// writeAlgoTable tabulates the analytic ephemeris every step for span,
+// evaluated shift later than each record's time. tide_raw follows the Earth–Moon
+// distance, so its extrema are days apart.
+func writeAlgoTable(t *testing.T, span, step, shift time.Duration) *ephem.GTAB {
+    t.Helper()
+    p := filepath.Join(t.TempDir(), "algo.bin")
+    w, err := ephem.Create(p, ephem.Header{Epoch: epoch, Step: step, Fields: ephem.AlgoFields})
+    if err != nil { t.Fatalf("create: %v", err) }
+    for i := 0; i < int(span/step); i++ {
+        if err := w.Write(ephem.ComputeSample(epoch.Add(time.Duration(i)*step+shift), ephem.DefaultScale)); err != nil { t.Fatalf("write: %v", err) }
+    }
+    if err := w.Close(); err != nil { t.Fatalf("close: %v", err) }
+    g, err := ephem.Open(p)
+    if err != nil { t.Fatalf("open: %v", err) }
+    t.Cleanup(func(){ _ = g.Close() })
+    return g
+}
+
+func run(t *testing.T, g *ephem.GTAB, basis string, start, end time.Time) Metrics {
+    t.Helper()
+    return runEvery(t, g, basis, start, end, time.Minute)
+}
+
+func runEvery(t *testing.T, g *ephem.GTAB, basis string, start, end time.Time, cadence time.Duration) Metrics {
+    t.Helper()
+    table, err := TableFunc(g, basis)
+    if err != nil { t.Fatal(err) }
+    ref, err := AlgoFunc(basis, ephem.DefaultScale)
+    if err != nil { t.Fatal(err) }
+    return Run(table, ref, Config{DatasetID: "test", Start: start, End: end, Basis: basis, Cadence: cadence})
+}
+
+const day = 24 * time.Hour
+
+func TestRunPassesAgainstOwnSource(t *testing.T) {
+    g := writeAlgoTable(t, 60*day, time.Hour, 0)
+    m := runEvery(t, g, BasisTideRaw, epoch, epoch.Add(59*day), time.Hour)
+    if !m.Passes || m.Reason != "" { t.Fatalf("expected pass: %+v", m) }
+    if m.Samples != 59*24-1 || m.CadenceSeconds != 3600 || m.ErrorBasis != BasisTideRaw { t.Fatalf("samples %d cadence %d basis %s", m.Samples, m.CadenceSeconds, m.ErrorBasis) }
+    if m.Stats.PeakCount < 3 || m.Stats.PeakUnmatched != 0 { t.Fatalf("peaks %d unmatched %d", m.Stats.PeakCount, m.Stats.PeakUnmatched) }
+    // tide_bps is truncated to whole basis points, which the relative
+    // thresholds near 0 bps do not tolerate; the absolute error shows it
+    m = runEvery(t, g, BasisTideBPS, epoch, epoch.Add(59*day), time.Hour)
+    if m.Stats.AbsErrorMax >= 1 || m.Stats.RelErrorMedian > 1e-3 || m.Stats.PeakUnmatched != 0 { t.Fatalf("tide_bps: %+v", m.Stats) }
+    // between records the table is a lerp of float32 values; extrema of a
+    // monthly cycle are too flat to time to 120s from sub-hourly samples
+    if m := runEvery(t, g, BasisTideRaw, epoch, epoch.Add(10*day), 7*time.Minute); m.Stats.RelErrorMax > 1e-5 { t.Fatalf("interpolated: %+v", m.Stats) }
+}
+
+func TestRunDetectsDrift(t *testing.T) {
+    g := writeAlgoTable(t, 60*day, time.Hour, 90*time.Minute)
+    m := runEvery(t, g, BasisTideRaw, epoch, epoch.Add(59*day), time.Hour)
+    if m.Passes || m.Reason != ReasonThresholds || !strings.Contains(m.Notes, "peak_drift_seconds_p99") { t.Fatalf("expected drift failure: %+v", m) }
+    if d := m.Stats.PeakDriftSecondsMedian; d < 3600 || d > 7200 { t.Fatalf("median drift %v, want ~5400", d) }
+    g = writeAlgoTable(t, 60*day, time.Hour, 6*time.Hour)
+    if m := runEvery(t, g, BasisTideRaw, epoch, epoch.Add(59*day), time.Hour); m.Passes || m.Stats.PeakUnmatched == 0 || !strings.Contains(m.Notes, "peak_unmatched") { t.Fatalf("expected unmatched extrema: %+v", m) }
+}
+
+func TestRunFailureModes(t *testing.T) {
+    g := writeAlgoTable(t, day, time.Minute, 0)
+    if m := run(t, g, BasisTideRaw, epoch.Add(12*time.Hour), epoch.Add(36*time.Hour)); m.Passes || m.Reason != ReasonCoverageGap { t.Fatalf("gap: %+v", m) }
+    if m := run(t, g, BasisTideRaw, epoch, epoch.Add(5*time.Minute)); m.Passes || m.Reason != ReasonInsufficientSamples { t.Fatalf("short: %+v", m) }
+    if _, err := TableFunc(g, "moon"); err == nil { t.Fatal("expected unknown basis error") }
+}
+
+func TestMetricsJSONHasSchemaFields(t *testing.T) {
+    g := writeAlgoTable(t, day, time.Minute, 0)
+    b, err := json.Marshal(run(t, g, BasisTideBPS, epoch, epoch.Add(23*time.Hour)))
+    if err != nil { t.Fatal(err) }
+    var doc map[string]any
+    if err := json.Unmarshal(b, &doc); err != nil { t.Fatal(err) }
+    for _, k := range []string{"dataset_id", "generated_at", "range", "cadence_seconds", "samples", "error_basis", "stats", "thresholds", "passes"} {
+        if _, ok := doc[k]; !ok { t.Fatalf("missing %s in %s", k, b) }
+    }
+    for _, k := range []string{"rel_error_median", "rel_error_p95", "rel_error_p99", "peak_drift_seconds_median", "peak_drift_seconds_p99"} {
+        if _, ok := doc["stats"].(map[string]any)[k]; !ok { t.Fatalf("missing stats.%s", k) }
+    }
+    for _, k := range []string{"rel_error_median_max", "rel_error_p99_max", "peak_drift_seconds_p99_max"} {
+        if _, ok := doc["thresholds"].(map[string]any)[k]; !ok { t.Fatalf("missing thresholds.%s", k) }
+    }
+    if r := doc["range"].(map[string]any); r["start"] != "2025-08-01T00:00:00Z" { t.Fatalf("range %v", r) }
+}
+
+func TestSelfCheckSchedule(t *testing.T) {
+    g := writeAlgoTable(t, day, time.Minute, 0)
+    table, _ := TableFunc(g, BasisTideRaw)
+    ref, _ := AlgoFunc(BasisTideRaw, ephem.DefaultScale)
+    c := NewSelfCheck(table, ref, Config{Basis: BasisTideRaw}, 6*time.Hour, func() string { return "live" })
+    if _, ok := c.Last(); ok { t.Fatal("no result before the first run") }
+    if m := c.Check(epoch.Add(12 * time.Hour)); !m.Passes || m.DatasetID != "live" { t.Fatalf("check: %+v", m) }
+    got := make(chan Metrics, 4)
+    c.Start(time.Hour, func(m Metrics) { got <- m })
+    select {
+    case m := <-got:
+        // now is far outside the 2025 table: nothing to compare
+        if m.Passes || !m.Skipped || m.Samples != 0 { t.Fatalf("scheduled run: %+v", m) }
+    case <-time.After(5 * time.Second):
+        t.Fatal("Start did not run immediately")
+    }
+    c.Stop()
+    if last, ok := c.Last(); !ok || !last.Skipped { t.Fatalf("last: %+v", last) }
+}
+
+func TestSelfCheckClipsWindowToCoverage(t *testing.T) {
+    g := writeAlgoTable(t, day, time.Minute, 0)
+    table, _ := TableFunc(g, BasisTideRaw)
+    ref, _ := AlgoFunc(BasisTideRaw, ephem.DefaultScale)
+    c := NewSelfCheck(table, ref, Config{Basis: BasisTideRaw}, 7*day, nil)
+    // a dataset younger than the window
+    now := epoch.Add(20 * time.Hour)
+    if m := c.Check(now); m.Passes || m.Reason != ReasonCoverageGap { t.Fatalf("unclipped: %+v", m) }
+    c.ClipTo(g.Coverage)
+    m := c.Check(now)
+    if !m.Passes || m.Skipped || !m.Range.Start.Equal(epoch) || !m.Range.End.Equal(now) { t.Fatalf("clipped: %+v", m) }
+    if m := c.Check(epoch.Add(-time.Hour)); m.Passes || !m.Skipped || !strings.Contains(m.Notes, "overlap") { t.Fatalf("before the dataset: %+v", m) }
+}
@@ -0,0 +1,130 @@
+package accuracy
+
+import (
+    "sync"
+    "time"
+
+    "github.com/Jthora/autoBotTrader/api/internal/ephem"
+)
+
+// SelfCheck runs the harness against a serving dataset at startup and on a
+// schedule, keeping the latest result for /health.
+type SelfCheck struct {
+    table, ref ephem.TideFunc
+    cfg        Config
+    window     time.Duration
+    datasetID  func() string
+    coverage   func() (start, end time.Time)
+
+    mu   sync.RWMutex
+    last *Metrics
+    stop chan struct{}
+    done chan struct{}
+}
+
+// NewSelfCheck checks table against ref over the window ending at each run.
+// cfg supplies cadence, basis and thresholds; datasetID, if non-nil, labels
+// each run (datasets may be reloaded while serving).
+func NewSelfCheck(table, ref ephem.TideFunc, cfg Config, window time.Duration, datasetID func() string) *SelfCheck {
+    return &SelfCheck{table: table, ref: ref, cfg: cfg, window: window, datasetID: datasetID}
+}
+
+// ClipTo limits each window to the extent coverage reports, e.g. the served
+// dataset's, so a dataset younger than the window is checked from its first
+// record instead of failing with a coverage gap. Call it before Start.
+func (c *SelfCheck) ClipTo(coverage func() (start, end time.Time)) {
+    c.coverage = coverage
+}
+
+// Check runs the harness over [now-window, now], clipped to the coverage
+// given to ClipTo, and records the result. A window that leaves nothing to
+// compare is recorded as skipped rather than failed.
+func (c *SelfCheck) Check(now time.Time) Metrics {
+    cfg := c.cfg
+    cfg.Start, cfg.End = now.Add(-c.window), now
+    if c.datasetID != nil {
+        cfg.DatasetID = c.datasetID()
+    }
+    if c.coverage != nil {
+        start, end := c.coverage()
+        if start.After(cfg.Start) {
+            cfg.Start = start
+        }
+        if end.Before(cfg.End) {
+            cfg.End = end
+        }
+    }
+    var m Metrics
+    if cfg.End.Before(cfg.Start) {
+        m, _ = newMetrics(cfg)
+        m.Skipped, m.Notes = true, "window does not overlap the dataset"
+    } else if m = Run(c.table, c.ref, cfg); m.Samples == 0 {
+        notes := "no samples in window"
+        if m.Notes != "" {
+            notes += ": " + m.Notes
+        }
+        m, _ = newMetrics(cfg)
+        m.Skipped, m.Notes = true, notes
+    }
+    c.mu.Lock()
+    c.last = &m
+    c.mu.Unlock()
+    return m
+}
+
+// Last returns the most recent result; ok=false before the first run.
+func (c *SelfCheck) Last() (Metrics, bool) {
+    if c == nil {
+        return Metrics{}, false
+    }
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+    if c.last == nil {
+        return Metrics{}, false
+    }
+    return *c.last, true
+}
+
+// Start runs Check now and then every interval until Stop, calling notify
+// (if non-nil) with each result.
+func (c *SelfCheck) Start(every time.Duration, notify func(Metrics)) {
+    c.mu.Lock()
+    if c.stop != nil {
+        c.mu.Unlock()
+        return
+    }
+    c.stop, c.done = make(chan struct{}), make(chan struct{})
+    stop, done := c.stop, c.done
+    c.mu.Unlock()
+    go func() {
+        defer close(done)
+        tick := time.NewTicker(every)
+        defer tick.Stop()
+        for {
+            m := c.Check(time.Now().UTC())
+            if notify != nil {
+                notify(m)
+            }
+            select {
+            case <-stop:
+                return
+            case <-tick.C:
+            }
+        }
+    }()
+}
+
+// Stop ends the schedule started by Start and waits for a running check.
+func (c *SelfCheck) Stop() {
+    if c == nil {
+        return
+    }
+    c.mu.Lock()
+    stop, done := c.stop, c.done
+    c.stop = nil
+    c.mu.Unlock()
+    if stop != nil {
+        close(stop)
+        <-done
+    }
+}
@@ -0,0 +1,60 @@
+package accuracy
+
+import (
+    "fmt"
+    "time"
+
+    "github.com/Jthora/autoBotTrader/api/internal/ephem"
+)
+
+// TableFunc returns basis of g as a TideFunc: GTAB.TideAt for tide_bps, the
+// interpolated tide_raw column otherwise.
+func TableFunc(g *ephem.GTAB, basis string) (ephem.TideFunc, error) {
+    switch basis {
+    case BasisTideBPS, "":
+        if g.Fields()&ephem.FieldTideBPS == 0 {
+            return nil, fmt.Errorf("table has no tide_bps")
+        }
+        return g.TideAt, nil
+    case BasisTideRaw:
+        if g.Fields()&ephem.FieldTideRawF32 == 0 {
+            return nil, fmt.Errorf("table has no tide_raw")
+        }
+        return SampleFunc(g.LookupSample, basis)
+    }
+    return nil, fmt.Errorf("unknown basis %q", basis)
+}
+
+// SampleFunc adapts a sample lookup (GTAB.LookupSample,
+// FileGravimetric.SampleAt, ...) to a TideFunc of basis. Points whose sample
+// lacks the field count as missing.
+func SampleFunc(lookup func(time.Time) (ephem.Sample, bool), basis string) (ephem.TideFunc, error) {
+    switch basis {
+    case BasisTideBPS, "":
+        return func(t time.Time) (float64, bool) {
+            s, ok := lookup(t)
+            return float64(s.TideBPS), ok && s.Has(ephem.FieldTideBPS)
+        }, nil
+    case BasisTideRaw:
+        return func(t time.Time) (float64, bool) {
+            s, ok := lookup(t)
+            return float64(s.TideRaw), ok && s.Has(ephem.FieldTideRawF32)
+        }, nil
+    }
+    return nil, fmt.Errorf("unknown basis %q", basis)
+}
+
+// AlgoFunc returns the analytic Sun/Moon ephemeris as a TideFunc. tide_bps is
+// normalized with sc and clamped to 0..10000 like a table; compare tide_raw
+// instead when the table was normalized over a different window.
+func AlgoFunc(basis string, sc ephem.Scale) (ephem.TideFunc, error) {
+    switch basis {
+    case BasisTideBPS, "":
+        return func(t time.Time) (float64, bool) {
+            return min(10000, max(0, sc.Value(ephem.TideRaw(t)))), true
+        }, nil
+    case BasisTideRaw:
+        return func(t time.Time) (float64, bool) { return ephem.TideRaw(t), true }, nil
+    }
+    return nil, fmt.Errorf("unknown basis %q", basis)
+}
@@ -0,0 +1,213 @@
+package ephem
+
+import (
+	"math"
+	"time"
+)
+
+// Low-precision analytic ephemeris for the Sun and Moon after Meeus,
+// Astronomical Algorithms (2nd ed.): the Sun from ch. 25 and the Moon from the
+// truncated ELP-2000/82 series of ch. 47. Distances agree with JPL DE kernels
+// to roughly 1e-5 (Sun) and 1e-4 (Moon) relative, ample for tide_bps.
+
+// Gravitational parameters, matching scripts/ephem/generate.py.
+const (
+    MuSun  = 1.32712440018e11 // km^3/s^2
+    MuMoon = 4.9048695e3      // km^3/s^2
+    AUKm   = 149597870.7
+)
+
+
+// Scale maps tide_raw to tide_bps the way the generator does: P5 maps to 0,
+// P95 to 10000, clamped.
+type Scale struct {
+    P5  float64
+    P95 float64
+}
+
+// DefaultScale holds the 5th/95th percentiles of tide_raw over 2000–2050 at
+// hourly cadence, so algorithmic tide_bps lines up with multi-year tables.
+var DefaultScale = Scale{P5: 1.1260e-13, P95: 1.4417e-13}
+
+// Value maps a tide_raw value to basis points without clamping or rounding.
+func (s Scale) Value(raw float64) float64 {
+    span := math.Max(s.P95-s.P5, 1e-30)
+    return (raw - s.P5) / span * 10000
+}
+
+// BPS normalizes a tide_raw value to basis points.
+func (s Scale) BPS(raw float64) uint16 {
+    v := s.Value(raw)
+    if v < 0 {
+        return 0
+    }
+    if v > 10000 {
+        return 10000
+    }
+    return uint16(v)
+}
+
+// julianCenturiesTT returns Julian centuries of TT since J2000.0 for a UTC time.
+func julianCenturiesTT(t time.Time) float64 {
+    j2000 := time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC)
+    return float64(ToScale(t, ScaleTT).Sub(j2000)) / float64(36525*24*time.Hour)
+}
+
+func deg2rad(d float64) float64 { return d * math.Pi / 180 }
+
+// normDeg reduces an angle to [0, 360).
+func normDeg(d float64) float64 {
+    d = math.Mod(d, 360)
+    if d < 0 {
+        d += 360
+    }
+    return d
+}
+
+// SunPosition returns the Sun's geometric geocentric ecliptic longitude
+// (degrees, mean equinox of date) and distance (km) at t.
+func SunPosition(t time.Time) (lonDeg, distKm float64) {
+    T := julianCenturiesTT(t)
+    L0 := 280.46646 + 36000.76983*T + 0.0003032*T*T
+    M := deg2rad(357.52911 + 35999.05029*T - 0.0001537*T*T)
+    e := 0.016708634 - 0.000042037*T - 0.0000001267*T*T
+    C := (1.914602-0.004817*T-0.000014*T*T)*math.Sin(M) +
+        (0.019993-0.000101*T)*math.Sin(2*M) +
+        0.000289*math.Sin(3*M)
+    nu := M + deg2rad(C)
+    r := 1.000001018 * (1 - e*e) / (1 + e*math.Cos(nu))
+    return normDeg(L0 + C), r * AUKm
+}
+
+// moonTerm is one row of Meeus table 47.A: multiples of D, M, M', F and the
+// longitude (1e-6 deg, sine) and distance (1e-3 km, cosine) coefficients.
+type moonTerm struct {
+    d, m, mp, f int8
+    l, r        int32
+}
+
+var moonTerms = [...]moonTerm{
+    {0, 0, 1, 0, 6288774, -20905355},
+    {2, 0, -1, 0, 1274027, -3699111},
+    {2, 0, 0, 0, 658314, -2955968},
+    {0, 0, 2, 0, 213618, -569925},
+    {0, 1, 0, 0, -185116, 48888},
+    {0, 0, 0, 2, -114332, -3149},
+    {2, 0, -2, 0, 58793, 246158},
+    {2, -1, -1, 0, 57066, -152138},
+    {2, 0, 1, 0, 53322, -170733},
+    {2, -1, 0, 0, 45758, -204586},
+    {0, 1, -1, 0, -40923, -129620},
+    {1, 0, 0, 0, -34720, 108743},
+    {0, 1, 1, 0, -30383, 104755},
+    {2, 0, 0, -2, 15327, 10321},
+    {0, 0, 1, 2, -12528, 0},
+    {0, 0, 1, -2, 10980, 79661},
+    {4, 0, -1, 0, 10675, -34782},
+    {0, 0, 3, 0, 10034, -23210},
+    {4, 0, -2, 0, 8548, -21636},
+    {2, 1, -1, 0, -7888, 24208},
+    {2, 1, 0, 0, -6766, 30824},
+    {1, 0, -1, 0, -5163, -8379},
+    {1, 1, 0, 0, 4987, -16675},
+    {2, -1, 1, 0, 4036, -12831},
+    {2, 0, 2, 0, 3994, -10445},
+    {4, 0, 0, 0, 3861, -11650},
+    {2, 0, -3, 0, 3665, 14403},
+    {0, 1, -2, 0, -2689, -7003},
+    {2, 0, -1, 2, -2602, 0},
+    {2, -1, -2, 0, 2390, 10056},
+    {1, 0, 1, 0, -2348, 6322},
+    {2, -2, 0, 0, 2236, -9884},
+    {0, 1, 2, 0, -2120, 5751},
+    {0, 2, 0, 0, -2069, 0},
+    {2, -2, -1, 0, 2048, -4950},
+    {2, 0, 1, -2, -1773, 4130},
+    {2, 0, 0, 2, -1595, 0},
+    {4, -1, -1, 0, 1215, -3958},
+    {0, 0, 2, 2, -1110, 0},
+    {3, 0, -1, 0, -892, 3258},
+    {2, 1, 1, 0, -810, 2616},
+    {4, -1, -2, 0, 759, -1897},
+    {0, 2, -1, 0, -713, -2117},
+    {2, 2, -1, 0, -700, 2354},
+    {2, 1, -2, 0, 691, 0},
+    {2, -1, 0, -2, 596, 0},
+    {4, 0, 1, 0, 549, -1423},
+    {0, 0, 4, 0, 537, -1117},
+    {4, -1, 0, 0, 520, -1571},
+    {1, 0, -2, 0, -487, -1739},
+    {2, 1, 0, -2, -399, 0},
+    {0, 0, 2, -2, -381, -4421},
+    {1, 1, 1, 0, 351, 0},
+    {3, 0, -2, 0, -340, 0},
+    {4, 0, -3, 0, 330, 0},
+    {2, -1, 2, 0, 327, 0},
+    {0, 2, 1, 0, -323, 1165},
+    {1, 1, -1, 0, 299, 0},
+    {2, 0, 3, 0, 294, 0},
+    {2, 0, -1, -2, 0, 8752},
+}
+
+// MoonPosition returns the Moon's geocentric ecliptic longitude (degrees, mean
+// equinox of date) and distance (km) at t.
+func MoonPosition(t time.Time) (lonDeg, distKm float64) {
+    T := julianCenturiesTT(t)
+    T2, T3, T4 := T*T, T*T*T, T*T*T*T
+    Lp := 218.3164477 + 481267.88123421*T - 0.0015786*T2 + T3/538841 - T4/65194000
+    D := deg2rad(297.8501921 + 445267.1114034*T - 0.0018819*T2 + T3/545868 - T4/113065000)
+    M := deg2rad(357.5291092 + 35999.0502909*T - 0.0001536*T2 + T3/24490000)
+    Mp := deg2rad(134.9633964 + 477198.8675055*T + 0.0087414*T2 + T3/69699 - T4/14712000)
+    F := deg2rad(93.2720950 + 483202.0175233*T - 0.0036539*T2 - T3/3526000 + T4/863310000)
+    E := 1 - 0.002516*T - 0.0000074*T2
+    var sl, sr float64
+    for _, k := range moonTerms {
+        arg := float64(k.d)*D + float64(k.m)*M + float64(k.mp)*Mp + float64(k.f)*F
+        ecc := 1.0
+        switch k.m {
+        case 1, -1:
+            ecc = E
+        case 2, -2:
+            ecc = E * E
+        }
+        sl += ecc * float64(k.l) * math.Sin(arg)
+        sr += ecc * float64(k.r) * math.Cos(arg)
+    }
+    A1 := deg2rad(119.75 + 131.849*T)
+    A2 := deg2rad(53.09 + 479264.290*T)
+    sl += 3958*math.Sin(A1) + 1962*math.Sin(deg2rad(Lp)-F) + 318*math.Sin(A2)
+    return normDeg(Lp + sl/1e6), 385000.56 + sr/1000
+}
+
+// TideRaw returns the generator's raw tide scalar μ_sun/r_sun³ + μ_moon/r_moon³ (s⁻²).
+func TideRaw(t time.Time) float64 {
+    _, rs := SunPosition(t)
+    _, rm := MoonPosition(t)
+    return MuSun/(rs*rs*rs) + MuMoon/(rm*rm*rm)
+}
+
+// AlgoFields is the fields mask of samples produced by ComputeSample.
+const AlgoFields = FieldTideBPS | FieldTideRawF32 | FieldMoonRkmF32 | FieldSunRkmF32 |
+    FieldMoonRinv3F32 | FieldSunRinv3F32 | FieldTideRawDtF32
+
+// ComputeSample evaluates every GTAB field at t analytically, normalizing
+// tide_bps with sc. tide_raw_dt is a ±30s central difference.
+func ComputeSample(t time.Time, sc Scale) Sample {
+    _, rs := SunPosition(t)
+    _, rm := MoonPosition(t)
+    sunInv3 := MuSun / (rs * rs * rs)
+    moonInv3 := MuMoon / (rm * rm * rm)
+    raw := sunInv3 + moonInv3
+    const h = 30 * time.Second
+    dt := (TideRaw(t.Add(h)) - TideRaw(t.Add(-h))) / (2 * h.Seconds())
+    return Sample{
+        Fields:    AlgoFields,
+        TideBPS:   sc.BPS(raw),
+        TideRaw:   float32(raw),
+        MoonRkm:   float32(rm),
+        SunRkm:    float32(rs),
+        MoonRinv3: float32(moonInv3),
+        SunRinv3:  float32(sunInv3),
+        TideRawDt: float32(dt),
+    }
+}
@@ -0,0 +1,38 @@
+package ephem
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// tt converts a Terrestrial Time calendar instant (as used in Meeus' examples) to UTC.
+func tt(y int, m time.Month, d int) time.Time {
+    return FromScale(time.Date(y, m, d, 0, 0, 0, 0, time.UTC), ScaleTT)
+}
+
+func TestMoonPositionMeeusExample47a(t *testing.T) {
+    lon, dist := MoonPosition(tt(1992, time.April, 12))
+    if math.Abs(lon-133.162655) > 1e-5 { t.Fatalf("longitude: %.6f", lon) }
+    if math.Abs(dist-368409.7) > 0.1 { t.Fatalf("distance: %.1f", dist) }
+}
+
+func TestSunPositionMeeusExample25a(t *testing.T) {
+    lon, dist := SunPosition(tt(1992, time.October, 13))
+    if math.Abs(lon-199.90988) > 1e-4 { t.Fatalf("longitude: %.5f", lon) }
+    if math.Abs(dist/AUKm-0.99766) > 1e-5 { t.Fatalf("distance: %.6f AU", dist/AUKm) }
+}
+
+func TestComputeSample(t *testing.T) {
+    at := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    s := ComputeSample(at, DefaultScale)
+    if s.Fields != AlgoFields { t.Fatalf("fields: %#x", s.Fields) }
+    if s.MoonRkm < 356000 || s.MoonRkm > 407000 || s.SunRkm < 1.47e8 || s.SunRkm > 1.53e8 { t.Fatalf("distances: %+v", s) }
+    if d := float64(s.TideRaw) - float64(s.MoonRinv3+s.SunRinv3); math.Abs(d) > 1e-19 { t.Fatalf("raw != sum of terms: %g", d) }
+    if s.TideBPS != DefaultScale.BPS(float64(s.TideRaw)) { t.Fatalf("bps: %d", s.TideBPS) }
+    // The stored derivative predicts the value an hour later to first order.
+    next := ComputeSample(at.Add(time.Hour), DefaultScale)
+    pred := float64(s.TideRaw) + float64(s.TideRawDt)*3600
+    if math.Abs(pred-float64(next.TideRaw)) > 1e-3*float64(s.TideRaw) { t.Fatalf("derivative: predicted %g, got %g", pred, next.TideRaw) }
+    if DefaultScale.BPS(0) != 0 || DefaultScale.BPS(1) != 10000 { t.Fatal("BPS should clamp") }
+}
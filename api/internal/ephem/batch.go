@@ -0,0 +1,141 @@
+package ephem
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// LookupTideBPSMany is LookupTideBPS for many timestamps: out[k] receives the
+// value at ts[k], or MissingBPS where LookupTideBPS would report ok=false (out
+// of range, in a gap, field absent or unreadable). It
+// returns the number of timestamps answered. Points are visited in time order
+// (ts itself is not reordered) and records are fetched through one read-ahead
+// window, so on unmapped tables a batch costs one ReadAt per block of
+// neighbouring records rather than one or two per point. out must be at least
+// as long as ts.
+func (g *GTAB) LookupTideBPSMany(ts []time.Time, out []uint16) int {
+    out = out[:len(ts)]
+    if g.lay.tideBPS < 0 {
+        for k := range out {
+            out[k] = MissingBPS
+        }
+        return 0
+    }
+    order := timeOrder(ts)
+    br := blockReader{g: g, winStart: -1}
+    n := 0
+    for k := range ts {
+        j, next := k, k+1
+        if order != nil {
+            j = order[k]
+            if next < len(order) {
+                next = order[next]
+            }
+        }
+        // read a whole block when the next point is close enough to use it
+        want := int64(2)
+        if next < len(ts) && ts[next].Sub(ts[j]) < seriesBlock*g.dt {
+            want = seriesBlock
+        }
+        v, ok := g.batchTideBPS(&br, ts[j], want)
+        if !ok {
+            out[j] = MissingBPS
+            continue
+        }
+        out[j] = v
+        n++
+    }
+    return n
+}
+
+// batchTideBPS computes LookupTideBPS(t) with records served by br.
+func (g *GTAB) batchTideBPS(br *blockReader, t time.Time, want int64) (uint16, bool) {
+    i, frac, ok := g.IndexFor(t)
+    if !ok {
+        return 0, false
+    }
+    if g.interp == InterpNearest {
+        if frac >= 0.5 {
+            i++
+        }
+        frac = 0
+    }
+    if frac != 0 {
+        br.prefetch(i, i+1, want)
+    }
+    r0, ok := br.record(i, want)
+    if !ok {
+        return 0, false
+    }
+    a := g.decode(r0)
+    if a.Missing() {
+        return 0, false
+    }
+    if frac == 0 {
+        return a.TideBPS, true
+    }
+    r1, ok := br.record(i+1, want)
+    if !ok {
+        return 0, false
+    }
+    b := g.decode(r1)
+    if b.Missing() {
+        return 0, false
+    }
+    if g.interp == InterpHermite {
+        return g.hermiteSample(a, b, frac).TideBPS, true
+    }
+    return lerpBPS(a.TideBPS, b.TideBPS, frac), true
+}
+
+// timeOrder returns the indices of ts in time order, or nil when ts is
+// already sorted (the common case for backtests). Offsets from the first
+// timestamp are sorted as integers, which is several times faster than
+// comparing time.Time values.
+func timeOrder(ts []time.Time) []int {
+    sorted := true
+    for k := 1; k < len(ts); k++ {
+        if ts[k].Before(ts[k-1]) {
+            sorted = false
+            break
+        }
+    }
+    if sorted {
+        return nil
+    }
+    type keyed struct {
+        off time.Duration // saturates far from ts[0], which keeps the order
+        k   int
+    }
+    keys := make([]keyed, len(ts))
+    for k, t := range ts {
+        keys[k] = keyed{t.Sub(ts[0]), k}
+    }
+    slices.SortFunc(keys, func(a, b keyed) int { return cmp.Compare(a.off, b.off) })
+    order := make([]int, len(ts))
+    for m, kk := range keys {
+        order[m] = kk.k
+    }
+    return order
+}
+
+// lookupGroups runs one batch per table over the points grouped to it and
+// scatters the results back into out. It returns the number answered.
+func lookupGroups(tables []*GTAB, groups [][]int, ts []time.Time, out []uint16) int {
+    n := 0
+    for l, idx := range groups {
+        if len(idx) == 0 {
+            continue
+        }
+        sub, vals := make([]time.Time, len(idx)), make([]uint16, len(idx))
+        for m, k := range idx {
+            sub[m] = ts[k]
+        }
+        n += tables[l].LookupTideBPSMany(sub, vals)
+        for m, k := range idx {
+            out[k] = vals[m]
+        }
+    }
+    return n
+}
@@ -0,0 +1,134 @@
+package ephem
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingReaderAt counts ReadAt calls.
+type countingReaderAt struct {
+    r     *bytes.Reader
+    calls atomic.Int64
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+    c.calls.Add(1)
+    return c.r.ReadAt(p, off)
+}
+
+// checkMany compares a batch lookup with per-point lookups.
+func checkMany(t *testing.T, name string, ts []time.Time, many func([]time.Time, []uint16) int, one func(time.Time) (uint16, bool)) {
+    t.Helper()
+    out := make([]uint16, len(ts))
+    n := many(ts, out)
+    want := 0
+    for k, at := range ts {
+        v, ok := one(at)
+        if !ok {
+            v = MissingBPS
+        } else {
+            want++
+        }
+        if out[k] != v { t.Fatalf("%s: ts[%d]=%s: batch %d, single %d", name, k, at, out[k], v) }
+    }
+    if n != want { t.Fatalf("%s: answered %d, want %d", name, n, want) }
+}
+
+func TestLookupTideBPSManyMatchesLookup(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    p := writeSineGTAB(t, dir, epoch.Unix(), 1, 3000, 0.01, true)
+    _, v3 := writeSmooth(t, dir, 3000, 256)
+    rng := rand.New(rand.NewSource(1))
+    for _, path := range []string{p, v3} {
+        for _, opts := range []Options{{}, {DisableMmap: true}, {Interp: InterpNearest, DisableMmap: true}, {Interp: InterpHermite}, {Interp: InterpHermite, DisableMmap: true}} {
+            g, err := OpenWithOptions(path, opts)
+            if err != nil { t.Fatalf("open: %v", err) }
+            start, end := g.Coverage()
+            span := end.Sub(start) + 20*time.Second
+            // shuffled, with duplicates and points on both sides of the coverage
+            ts := make([]time.Time, 4000)
+            for k := range ts {
+                ts[k] = start.Add(-10*time.Second + time.Duration(rng.Int63n(int64(span))))
+            }
+            ts[1] = ts[0]
+            checkMany(t, path, ts, g.LookupTideBPSMany, g.LookupTideBPS)
+            // sorted and sparse
+            sparse := make([]time.Time, 0, 40)
+            for at := start; !at.After(end); at = at.Add(77*time.Second + 250*time.Millisecond) {
+                sparse = append(sparse, at)
+            }
+            checkMany(t, path, sparse, g.LookupTideBPSMany, g.LookupTideBPS)
+            _ = g.Close()
+        }
+    }
+}
+
+func TestLookupTideBPSManyCoalescesReads(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    b, err := os.ReadFile(writeSineGTAB(t, dir, epoch.Unix(), 1, 5000, 0.01, false))
+    if err != nil { t.Fatal(err) }
+    cr := &countingReaderAt{r: bytes.NewReader(b)}
+    g, err := OpenReader(cr, int64(len(b)), "mem", Options{})
+    if err != nil { t.Fatalf("open: %v", err) }
+    defer g.Close()
+    ts := make([]time.Time, 0, 20000)
+    for k := 0; k < 19996; k++ {
+        ts = append(ts, epoch.Add(time.Duration(k)*250*time.Millisecond))
+    }
+    rand.New(rand.NewSource(2)).Shuffle(len(ts), func(a, b int) { ts[a], ts[b] = ts[b], ts[a] })
+    out := make([]uint16, len(ts))
+    before := cr.calls.Load()
+    if n := g.LookupTideBPSMany(ts, out); n != len(ts) { t.Fatalf("answered %d of %d", n, len(ts)) }
+    if reads := cr.calls.Load() - before; reads > 5000/seriesBlock+2 { t.Fatalf("expected block reads, got %d ReadAt calls", reads) }
+    // a table without tide_bps answers nothing
+    raw := filepath.Join(dir, "raw.bin")
+    w, err := Create(raw, Header{Epoch: epoch, Step: time.Second, Fields: FieldTideRawF32})
+    if err != nil { t.Fatalf("create: %v", err) }
+    for k := 0; k < 10; k++ {
+        if err := w.Write(Sample{TideRaw: 1}); err != nil { t.Fatalf("write: %v", err) }
+    }
+    if err := w.Close(); err != nil { t.Fatalf("close: %v", err) }
+    gr, err := Open(raw)
+    if err != nil { t.Fatalf("open: %v", err) }
+    defer gr.Close()
+    if n := gr.LookupTideBPSMany([]time.Time{epoch, epoch.Add(time.Second)}, out); n != 0 || out[0] != MissingBPS || out[1] != MissingBPS { t.Fatalf("no tide_bps: %d %v", n, out[:2]) }
+}
+
+func TestPyramidAndCatalogLookupMany(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    fine := writeSineGTAB(t, dir, epoch.Unix(), 1, 600, 0.01, false)
+    coarse := writeConst(t, filepath.Join(dir, "gtab_60s.bin"), epoch, time.Minute, 24*60, 6000)
+    p, err := OpenPyramid([]string{coarse, fine}, Options{DisableMmap: true})
+    if err != nil { t.Fatalf("open pyramid: %v", err) }
+    defer p.Close()
+    var ts []time.Time
+    for at := epoch.Add(-time.Minute); at.Before(epoch.Add(25 * time.Hour)); at = at.Add(37 * time.Second) {
+        ts = append(ts, at)
+    }
+    pyrOne := func(at time.Time) (uint16, bool) { v, _, ok := p.LookupTideBPS(at); return v, ok }
+    checkMany(t, "pyramid", ts, p.LookupTideBPSMany, pyrOne)
+
+    cdir := t.TempDir()
+    writeConst(t, filepath.Join(cdir, "a.bin"), epoch, time.Minute, 60, 1000)
+    writeConst(t, filepath.Join(cdir, "b.bin"), epoch.Add(time.Hour), time.Minute, 60, 3000)
+    writeConst(t, filepath.Join(cdir, "c.bin"), epoch.Add(90*time.Minute), time.Minute, 120, 5000)
+    c, err := OpenCatalog(cdir, Options{})
+    if err != nil { t.Fatalf("open catalog: %v", err) }
+    ts = ts[:0]
+    for at := epoch.Add(-time.Minute); at.Before(epoch.Add(4 * time.Hour)); at = at.Add(7 * time.Second) {
+        ts = append(ts, at)
+    }
+    catOne := func(at time.Time) (uint16, bool) { v, _, ok := c.LookupTideBPS(at); return v, ok }
+    checkMany(t, "catalog", ts, c.LookupTideBPSMany, catOne)
+    _ = c.Close()
+    out := make([]uint16, len(ts))
+    if n := c.LookupTideBPSMany(ts, out); n != 0 { t.Fatalf("closed catalog answered %d", n) }
+}
@@ -0,0 +1,416 @@
+package ephem
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CatalogPattern selects the tables a Catalog loads from its directory. The
+// Writer stages tables as "*.bin.tmp", so half-written files never match.
+const CatalogPattern = "*.bin"
+
+// ErrCatalogGap reports tables whose combined coverage is not contiguous.
+var ErrCatalogGap = errors.New("catalog: coverage gap")
+
+// Catalog serves a directory of consecutive or overlapping tables (e.g. one
+// 90-day table per monthly rotation) as one continuous dataset. Lookups go to
+// the finest table covering t, preferring the newest on ties; points between
+// the last record of one table and the first record of the next are
+// interpolated across the boundary.
+//
+// Refresh (or Watch) picks up tables added to, replaced in, or removed from the
+// directory. A new set is swapped in only if it opens, verifies and stays
+// contiguous; otherwise the previous set keeps serving. Retired tables are
+// closed after in-flight lookups have finished.
+type Catalog struct {
+    dir  string
+    opts Options
+
+    refreshMu sync.Mutex // serializes Refresh
+    mu        sync.RWMutex
+    files     map[string]catalogFile
+    tables    []*GTAB // finest step first; ties newest start first
+    start     time.Time
+    end       time.Time
+    closed    bool
+
+    stop chan struct{}
+    done chan struct{}
+}
+
+type catalogFile struct {
+    info os.FileInfo
+    g    *GTAB
+}
+
+// OpenCatalog loads every table matching CatalogPattern in dir. Unlike a later
+// Refresh, any table that fails to open, or a coverage gap, is an error here.
+func OpenCatalog(dir string, opts Options) (*Catalog, error) {
+    c := &Catalog{dir: dir, opts: opts, files: map[string]catalogFile{}}
+    if _, err := c.Refresh(); err != nil {
+        c.Close()
+        return nil, err
+    }
+    if len(c.tables) == 0 {
+        return nil, fmt.Errorf("%s: no tables matching %s", dir, CatalogPattern)
+    }
+    return c, nil
+}
+
+// Dir returns the directory the catalog serves.
+func (c *Catalog) Dir() string { return c.dir }
+
+// Refresh rescans the directory and reports whether the served set changed.
+// Files that fail to open or verify are skipped (a replaced file keeps its
+// previous version serving) and reported in the returned error; a set that would leave
+// a gap is rejected as a whole.
+func (c *Catalog) Refresh() (changed bool, err error) {
+    c.refreshMu.Lock()
+    defer c.refreshMu.Unlock()
+    paths, err := filepath.Glob(filepath.Join(c.dir, CatalogPattern))
+    if err != nil {
+        return false, err
+    }
+    c.mu.RLock()
+    old, closed := c.files, c.closed
+    c.mu.RUnlock()
+    if closed {
+        return false, errors.New("catalog: closed")
+    }
+    next := make(map[string]catalogFile, len(paths))
+    var errs []error
+    var opened []*GTAB
+    for _, p := range paths {
+        fi, err := os.Stat(p)
+        if err != nil {
+            errs = append(errs, err)
+            continue
+        }
+        prev, had := old[p]
+        if had && SameFile(prev.info, fi) {
+            next[p] = prev
+            continue
+        }
+        g, err := openVerified(p, c.opts)
+        if err != nil {
+            errs = append(errs, err)
+            if had {
+                next[p] = prev
+            }
+            continue
+        }
+        next[p] = catalogFile{info: fi, g: g}
+        opened = append(opened, g)
+        changed = true
+    }
+    for p := range old {
+        if _, ok := next[p]; !ok {
+            changed = true
+        }
+    }
+    if !changed {
+        return false, errors.Join(errs...)
+    }
+    tables := make([]*GTAB, 0, len(next))
+    for _, cf := range next {
+        tables = append(tables, cf.g)
+    }
+    start, end, err := checkContiguous(tables)
+    if err != nil {
+        for _, g := range opened {
+            g.Close()
+        }
+        return false, errors.Join(append(errs, fmt.Errorf("%s: %w", c.dir, err))...)
+    }
+    sort.Slice(tables, func(i, j int) bool {
+        if tables[i].dt != tables[j].dt {
+            return tables[i].dt < tables[j].dt
+        }
+        return tables[i].TimeAt(0).After(tables[j].TimeAt(0))
+    })
+    var retired []*GTAB
+    c.mu.Lock()
+    for p, cf := range old {
+        if n, ok := next[p]; !ok || n.g != cf.g {
+            retired = append(retired, cf.g)
+        }
+    }
+    c.files, c.tables, c.start, c.end = next, tables, start, end
+    c.mu.Unlock()
+    // Lookups hold the read lock for their whole duration, so none can still
+    // be using a retired table once the swap above has completed.
+    for _, g := range retired {
+        g.Close()
+    }
+    return true, errors.Join(errs...)
+}
+
+// openVerified opens the table at path and checks every embedded checksum,
+// so a corrupt file is rejected before it serves.
+func openVerified(path string, opts Options) (*GTAB, error) {
+    g, err := OpenWithOptions(path, opts)
+    if err != nil {
+        return nil, err
+    }
+    if err := g.Verify(); err != nil && !errors.Is(err, ErrNoChecksums) {
+        g.Close()
+        return nil, err
+    }
+    return g, nil
+}
+
+// SameFile reports whether a file is unchanged between two stats: same inode,
+// size and mtime. Both nil (absent then and now) counts as unchanged. Catalog
+// and hot-reloading providers use it to detect replaced tables.
+func SameFile(a, b os.FileInfo) bool {
+    if a == nil || b == nil {
+        return a == nil && b == nil
+    }
+    return os.SameFile(a, b) && a.Size() == b.Size() && a.ModTime().Equal(b.ModTime())
+}
+
+// checkContiguous returns the union coverage of tables, or ErrCatalogGap when
+// a table starts more than one step after everything before it has ended.
+func checkContiguous(tables []*GTAB) (start, end time.Time, err error) {
+    if len(tables) == 0 {
+        return start, end, errors.New("no tables")
+    }
+    byStart := append([]*GTAB(nil), tables...)
+    sort.Slice(byStart, func(i, j int) bool { return byStart[i].TimeAt(0).Before(byStart[j].TimeAt(0)) })
+    var endStep time.Duration
+    for i, g := range byStart {
+        s, e := g.Coverage()
+        if i == 0 {
+            start, end, endStep = s, e, g.dt
+            continue
+        }
+        if slack := max(endStep, g.dt); s.Sub(end) > slack {
+            return start, end, fmt.Errorf("%w: %s ends %s, %s starts %s", ErrCatalogGap,
+                filepath.Base(byStart[i-1].path), end.Format(time.RFC3339), filepath.Base(g.path), s.Format(time.RFC3339))
+        }
+        if e.After(end) {
+            end, endStep = e, g.dt
+        }
+    }
+    return start, end, nil
+}
+
+// Watch calls Refresh every interval until Close. notify, if non-nil, is
+// called after each refresh that changed the set or failed.
+func (c *Catalog) Watch(every time.Duration, notify func(changed bool, err error)) {
+    c.mu.Lock()
+    if c.stop != nil || c.closed {
+        c.mu.Unlock()
+        return
+    }
+    c.stop, c.done = make(chan struct{}), make(chan struct{})
+    stop, done := c.stop, c.done
+    c.mu.Unlock()
+    go func() {
+        defer close(done)
+        tick := time.NewTicker(every)
+        defer tick.Stop()
+        for {
+            select {
+            case <-stop:
+                return
+            case <-tick.C:
+                changed, err := c.Refresh()
+                if notify != nil && (changed || err != nil) {
+                    notify(changed, err)
+                }
+            }
+        }
+    }()
+}
+
+// Tables returns the currently served tables, finest first. They may be closed
+// by a later refresh; use them for metadata only.
+func (c *Catalog) Tables() []*GTAB {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+    return append([]*GTAB(nil), c.tables...)
+}
+
+// Levels is Tables, for parity with Pyramid.
+func (c *Catalog) Levels() []*GTAB { return c.Tables() }
+
+// Coverage returns the union coverage of the served tables.
+func (c *Catalog) Coverage() (start, end time.Time) {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+    return c.start, c.end
+}
+
+// Select returns the table that answers a lookup at t: the finest covering
+// table or, across a boundary, the table whose last record precedes t.
+func (c *Catalog) Select(t time.Time) (*GTAB, bool) {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+    if g, ok := c.covering(t); ok {
+        return g, true
+    }
+    a, _, ok := c.boundary(t)
+    return a, ok
+}
+
+// covering returns the finest table covering t. Callers hold c.mu.
+func (c *Catalog) covering(t time.Time) (*GTAB, bool) {
+    for _, g := range c.tables {
+        if _, _, ok := g.IndexFor(t); ok {
+            return g, true
+        }
+    }
+    return nil, false
+}
+
+// boundary finds the tables around a point that falls between the last record
+// of a and the first record of b. Callers hold c.mu.
+func (c *Catalog) boundary(t time.Time) (a, b *GTAB, ok bool) {
+    var aEnd, bStart time.Time
+    for _, g := range c.tables {
+        s, e := g.Coverage()
+        if e.Before(t) && (a == nil || e.After(aEnd)) {
+            a, aEnd = g, e
+        }
+        if s.After(t) && (b == nil || s.Before(bStart)) {
+            b, bStart = g, s
+        }
+    }
+    if a == nil || b == nil || bStart.Sub(aEnd) > max(a.dt, b.dt) {
+        return nil, nil, false
+    }
+    return a, b, true
+}
+
+// LookupTideBPS returns tide_bps at t and the step of the answering table.
+// ok=false outside coverage or after Close.
+func (c *Catalog) LookupTideBPS(t time.Time) (uint16, time.Duration, bool) {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+    if g, ok := c.covering(t); ok {
+        v, ok := g.LookupTideBPS(t)
+        return v, g.dt, ok
+    }
+    s, step, ok := c.bridge(t)
+    return s.TideBPS, step, ok && s.Has(FieldTideBPS)
+}
+
+// LookupTideBPSMany is LookupTideBPS for many timestamps (see
+// GTAB.LookupTideBPSMany): points inside a table are looked up as one batch
+// per table, points across a boundary one by one. It returns the number
+// answered.
+func (c *Catalog) LookupTideBPSMany(ts []time.Time, out []uint16) int {
+    out = out[:len(ts)]
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+    groups := make([][]int, len(c.tables))
+    n := 0
+    for k, t := range ts {
+        out[k] = MissingBPS
+        covered := false
+        for l, g := range c.tables {
+            if _, _, ok := g.IndexFor(t); ok {
+                groups[l] = append(groups[l], k)
+                covered = true
+                break
+            }
+        }
+        if !covered {
+            if s, _, ok := c.bridge(t); ok && s.Has(FieldTideBPS) {
+                out[k] = s.TideBPS
+                n++
+            }
+        }
+    }
+    return n + lookupGroups(c.tables, groups, ts, out)
+}
+
+// LookupSample is LookupTideBPS for every field present in the answering table.
+func (c *Catalog) LookupSample(t time.Time) (Sample, time.Duration, bool) {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+    if g, ok := c.covering(t); ok {
+        s, ok := g.LookupSample(t)
+        return s, g.dt, ok
+    }
+    return c.bridge(t)
+}
+
+// TideAt is GTAB.TideAt routed like LookupTideBPS; it satisfies TideFunc.
+func (c *Catalog) TideAt(t time.Time) (float64, bool) {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+    if g, ok := c.covering(t); ok {
+        return g.TideAt(t)
+    }
+    a, b, ok := c.boundary(t)
+    if !ok {
+        return 0, false
+    }
+    v0, ok0 := a.readTideBPS(int64(a.n) - 1)
+    v1, ok1 := b.readTideBPS(0)
+    if !ok0 || !ok1 {
+        return 0, false
+    }
+    _, aEnd := a.Coverage()
+    frac := float64(t.Sub(aEnd)) / float64(b.TimeAt(0).Sub(aEnd))
+    return float64(v0) + (float64(v1)-float64(v0))*frac, true
+}
+
+// bridge interpolates across a table boundary between the last record of one
+// table and the first record of the next. Only fields present in both survive.
+func (c *Catalog) bridge(t time.Time) (Sample, time.Duration, bool) {
+    a, b, ok := c.boundary(t)
+    if !ok {
+        return Sample{}, 0, false
+    }
+    sa, ok0 := a.SampleAt(int64(a.n) - 1)
+    sb, ok1 := b.SampleAt(0)
+    if !ok0 || !ok1 || sa.Missing() || sb.Missing() {
+        return Sample{}, 0, false
+    }
+    _, aEnd := a.Coverage()
+    frac := float64(t.Sub(aEnd)) / float64(b.TimeAt(0).Sub(aEnd))
+    mask := sa.Fields & sb.Fields
+    sa.Fields, sb.Fields = mask, mask
+    var s Sample
+    if a.interp == InterpNearest {
+        if s = sa; frac >= 0.5 {
+            s = sb
+        }
+    } else {
+        s = lerpSample(sa, sb, frac)
+    }
+    return s, max(a.dt, b.dt), true
+}
+
+// Close stops Watch and closes every table. Lookups afterwards report ok=false.
+func (c *Catalog) Close() error {
+    c.mu.Lock()
+    stop, done := c.stop, c.done
+    c.stop = nil
+    c.closed = true
+    c.mu.Unlock()
+    if stop != nil {
+        close(stop)
+        <-done
+    }
+    c.refreshMu.Lock()
+    defer c.refreshMu.Unlock()
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    var err error
+    for _, cf := range c.files {
+        if cerr := cf.g.Close(); err == nil {
+            err = cerr
+        }
+    }
+    c.files, c.tables = nil, nil
+    return err
+}
@@ -0,0 +1,127 @@
+package ephem
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCatalogRoutesAcrossBoundaries(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    writeConst(t, filepath.Join(dir, "a.bin"), epoch, time.Minute, 60, 1000)                  // 00:00..00:59
+    writeConst(t, filepath.Join(dir, "b.bin"), epoch.Add(time.Hour), time.Minute, 60, 3000)   // 01:00..01:59
+    writeConst(t, filepath.Join(dir, "c.bin"), epoch.Add(90*time.Minute), time.Minute, 120, 5000) // overlaps b, newer
+    os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0o644)
+    c, err := OpenCatalog(dir, Options{})
+    if err != nil { t.Fatalf("open: %v", err) }
+    defer c.Close()
+    if len(c.Tables()) != 3 { t.Fatalf("tables: %d", len(c.Tables())) }
+    if s, e := c.Coverage(); !s.Equal(epoch) || !e.Equal(epoch.Add(209*time.Minute)) { t.Fatalf("coverage: %s..%s", s, e) }
+    cases := []struct{ at time.Duration; want uint16 }{
+        {30 * time.Minute, 1000},
+        {59*time.Minute + 30*time.Second, 2000}, // bridged between a and b
+        {70 * time.Minute, 3000},
+        {100 * time.Minute, 5000}, // overlap: newest table wins
+        {200 * time.Minute, 5000},
+    }
+    for _, tc := range cases {
+        v, step, ok := c.LookupTideBPS(epoch.Add(tc.at))
+        if !ok || v != tc.want || step != time.Minute { t.Fatalf("at %v: %d %v %v, want %d", tc.at, v, step, ok, tc.want) }
+        if s, _, ok := c.LookupSample(epoch.Add(tc.at)); !ok || s.TideBPS != tc.want { t.Fatalf("sample at %v: %+v", tc.at, s) }
+        if _, ok := c.Select(epoch.Add(tc.at)); !ok { t.Fatalf("select at %v", tc.at) }
+    }
+    if _, _, ok := c.LookupTideBPS(epoch.Add(-time.Second)); ok { t.Fatal("before coverage should miss") }
+}
+
+func TestCatalogRejectsGap(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    writeConst(t, filepath.Join(dir, "a.bin"), epoch, time.Minute, 60, 1)
+    writeConst(t, filepath.Join(dir, "b.bin"), epoch.Add(2*time.Hour), time.Minute, 60, 2)
+    if _, err := OpenCatalog(dir, Options{}); !errors.Is(err, ErrCatalogGap) { t.Fatalf("expected gap error: %v", err) }
+    if _, err := OpenCatalog(t.TempDir(), Options{}); err == nil { t.Fatal("empty directory should fail") }
+}
+
+func TestCatalogRefreshRotation(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    writeConst(t, filepath.Join(dir, "2025-08.bin"), epoch, time.Minute, 60, 1000)
+    c, err := OpenCatalog(dir, Options{})
+    if err != nil { t.Fatalf("open: %v", err) }
+    defer c.Close()
+    if changed, err := c.Refresh(); changed || err != nil { t.Fatalf("idle refresh: %v %v", changed, err) }
+
+    // A gap-creating drop is rejected; the old set keeps serving.
+    writeConst(t, filepath.Join(dir, "2025-10.bin"), epoch.Add(3*time.Hour), time.Minute, 60, 9000)
+    if changed, err := c.Refresh(); changed || !errors.Is(err, ErrCatalogGap) { t.Fatalf("gap refresh: %v %v", changed, err) }
+    os.Remove(filepath.Join(dir, "2025-10.bin"))
+
+    // A truncated (still copying) file is reported and skipped.
+    os.WriteFile(filepath.Join(dir, "partial.bin"), []byte("GTAB1"), 0o644)
+    if changed, err := c.Refresh(); changed || err == nil { t.Fatalf("partial refresh: %v %v", changed, err) }
+    os.Remove(filepath.Join(dir, "partial.bin"))
+
+    // The next table is picked up, then the old one retired.
+    writeConst(t, filepath.Join(dir, "2025-09.bin"), epoch.Add(time.Hour), time.Minute, 60, 2000)
+    if changed, err := c.Refresh(); !changed || err != nil { t.Fatalf("rotation refresh: %v %v", changed, err) }
+    if v, _, ok := c.LookupTideBPS(epoch.Add(90 * time.Minute)); !ok || v != 2000 { t.Fatalf("new table: %d %v", v, ok) }
+    os.Remove(filepath.Join(dir, "2025-08.bin"))
+    if changed, err := c.Refresh(); !changed || err != nil { t.Fatalf("retire refresh: %v %v", changed, err) }
+    if s, _ := c.Coverage(); !s.Equal(epoch.Add(time.Hour)) { t.Fatalf("coverage start after retire: %s", s) }
+
+    // Replacing a file in place (rename over) reopens it.
+    writeConst(t, filepath.Join(dir, "2025-09.bin"), epoch.Add(time.Hour), time.Minute, 60, 2500)
+    if changed, err := c.Refresh(); !changed || err != nil { t.Fatalf("replace refresh: %v %v", changed, err) }
+    if v, _, _ := c.LookupTideBPS(epoch.Add(90 * time.Minute)); v != 2500 { t.Fatalf("replaced table: %d", v) }
+
+    // A corrupt replacement fails verification; the previous version keeps serving.
+    tmp := filepath.Join(dir, "v2.tmp")
+    w, err := CreateWithOptions(tmp, Header{Epoch: epoch.Add(time.Hour), Step: time.Minute, Fields: FieldTideBPS}, WriteOptions{Version: 2})
+    if err != nil { t.Fatalf("create: %v", err) }
+    for i := 0; i < 60; i++ { w.Write(Sample{TideBPS: 3000}) }
+    if err := w.Close(); err != nil { t.Fatalf("close: %v", err) }
+    b, err := os.ReadFile(tmp)
+    if err != nil { t.Fatal(err) }
+    b[len(b)-5] ^= 0xff // the last record, just before the CRC trailer
+    os.WriteFile(filepath.Join(dir, "corrupt.tmp"), b, 0o644)
+    os.Rename(filepath.Join(dir, "corrupt.tmp"), filepath.Join(dir, "2025-09.bin"))
+    if changed, err := c.Refresh(); changed || !errors.Is(err, ErrChecksum) { t.Fatalf("corrupt refresh: %v %v", changed, err) }
+    if v, _, _ := c.LookupTideBPS(epoch.Add(119 * time.Minute)); v != 2500 { t.Fatalf("corrupt table served: %d", v) }
+}
+
+func TestCatalogWatchConcurrentLookups(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    writeConst(t, filepath.Join(dir, "a.bin"), epoch, time.Second, 3600, 4000)
+    c, err := OpenCatalog(dir, Options{DisableMmap: true})
+    if err != nil { t.Fatalf("open: %v", err) }
+    changes := make(chan bool, 16)
+    c.Watch(5*time.Millisecond, func(changed bool, err error) { if changed { changes <- true } })
+    var wg sync.WaitGroup
+    stop := make(chan struct{})
+    for i := 0; i < 4; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for {
+                select { case <-stop: return; default: }
+                if v, _, ok := c.LookupTideBPS(epoch.Add(30 * time.Minute)); !ok || (v != 4000 && v != 6000) { t.Errorf("lookup during rotation: %d %v", v, ok); return }
+            }
+        }()
+    }
+    writeConst(t, filepath.Join(dir, "a.bin"), epoch, time.Second, 3600, 6000)
+    select {
+    case <-changes:
+    case <-time.After(2 * time.Second):
+        t.Fatal("watch did not pick up the replaced table")
+    }
+    close(stop)
+    wg.Wait()
+    if v, _, _ := c.LookupTideBPS(epoch.Add(30 * time.Minute)); v != 6000 { t.Fatalf("after rotation: %d", v) }
+    if err := c.Close(); err != nil { t.Fatalf("close: %v", err) }
+    if _, _, ok := c.LookupTideBPS(epoch); ok { t.Fatal("lookup after close should miss") }
+}
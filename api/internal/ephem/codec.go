@@ -0,0 +1,244 @@
+package ephem
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+)
+
+// Column codecs for compressed (v3) chunks. Each chunk stores its records
+// column by column in layout order: tide_bps as zigzag varint deltas, every
+// float32 field as a Gorilla-style XOR bit stream padded to a byte boundary.
+// Decoding a chunk reproduces the exact v1 record bytes.
+
+var errCorruptChunk = errors.New("gtab: corrupt compressed chunk")
+
+// bitWriter appends bits MSB-first.
+type bitWriter struct {
+    buf  []byte
+    nbit uint8 // bits used in the last byte (0 = start a new byte)
+}
+
+func (w *bitWriter) writeBits(v uint64, n uint8) {
+    for n > 0 {
+        if w.nbit == 0 {
+            w.buf = append(w.buf, 0)
+            w.nbit = 8
+        }
+        take := n
+        if take > w.nbit {
+            take = w.nbit
+        }
+        chunk := byte(v>>(n-take)) & (0xff >> (8 - take))
+        w.buf[len(w.buf)-1] |= chunk << (w.nbit - take)
+        w.nbit -= take
+        n -= take
+    }
+}
+
+// bitReader reads bits MSB-first and tracks the byte position for alignment.
+type bitReader struct {
+    buf  []byte
+    pos  int   // next byte
+    left uint8 // unread bits in buf[pos-1]
+}
+
+func (r *bitReader) readBits(n uint8) (uint64, bool) {
+    var v uint64
+    for n > 0 {
+        if r.left == 0 {
+            if r.pos >= len(r.buf) {
+                return 0, false
+            }
+            r.pos++
+            r.left = 8
+        }
+        take := n
+        if take > r.left {
+            take = r.left
+        }
+        b := r.buf[r.pos-1] >> (r.left - take) & (0xff >> (8 - take))
+        v = v<<take | uint64(b)
+        r.left -= take
+        n -= take
+    }
+    return v, true
+}
+
+// encodeFloatColumn compresses float32 bit patterns by XOR with the previous
+// value, storing only the meaningful bits (after Gorilla, Pelkonen et al. 2015).
+func encodeFloatColumn(dst []byte, vals []uint32) []byte {
+    w := bitWriter{buf: dst}
+    var prev uint32
+    lead, trail := uint8(255), uint8(0)
+    for i, v := range vals {
+        if i == 0 {
+            w.writeBits(uint64(v), 32)
+            prev = v
+            continue
+        }
+        x := v ^ prev
+        prev = v
+        if x == 0 {
+            w.writeBits(0, 1)
+            continue
+        }
+        w.writeBits(1, 1)
+        l, t := uint8(bits.LeadingZeros32(x)), uint8(bits.TrailingZeros32(x))
+        if lead != 255 && l >= lead && t >= trail {
+            // fits the previous window
+            w.writeBits(0, 1)
+            w.writeBits(uint64(x>>trail), 32-lead-trail)
+            continue
+        }
+        lead, trail = l, t
+        sig := 32 - l - t
+        w.writeBits(1, 1)
+        w.writeBits(uint64(l), 5)
+        w.writeBits(uint64(sig-1), 5)
+        w.writeBits(uint64(x>>t), sig)
+    }
+    return w.buf
+}
+
+// decodeFloatColumn reverses encodeFloatColumn for count values and returns
+// the number of bytes consumed.
+func decodeFloatColumn(src []byte, out []uint32) (int, error) {
+    r := bitReader{buf: src}
+    var prev uint32
+    var lead, trail uint8
+    window := false
+    for i := range out {
+        if i == 0 {
+            v, ok := r.readBits(32)
+            if !ok {
+                return 0, errCorruptChunk
+            }
+            prev = uint32(v)
+            out[0] = prev
+            continue
+        }
+        ctl, ok := r.readBits(1)
+        if !ok {
+            return 0, errCorruptChunk
+        }
+        if ctl == 1 {
+            fresh, ok := r.readBits(1)
+            if !ok {
+                return 0, errCorruptChunk
+            }
+            if fresh == 1 {
+                l, ok1 := r.readBits(5)
+                sig, ok2 := r.readBits(5)
+                if !ok1 || !ok2 || l+sig+1 > 32 {
+                    return 0, errCorruptChunk
+                }
+                lead = uint8(l)
+                trail = 32 - lead - uint8(sig+1)
+                window = true
+            } else if !window {
+                return 0, errCorruptChunk
+            }
+            m, ok := r.readBits(32 - lead - trail)
+            if !ok {
+                return 0, errCorruptChunk
+            }
+            prev ^= uint32(m) << trail
+        }
+        out[i] = prev
+    }
+    return r.pos, nil
+}
+
+// encodeBPSColumn stores the first value and successive deltas as zigzag varints.
+func encodeBPSColumn(dst []byte, vals []uint16) []byte {
+    var prev int64
+    for _, v := range vals {
+        dst = binary.AppendVarint(dst, int64(v)-prev)
+        prev = int64(v)
+    }
+    return dst
+}
+
+func decodeBPSColumn(src []byte, out []uint16) (int, error) {
+    pos := 0
+    var prev int64
+    for i := range out {
+        d, n := binary.Varint(src[pos:])
+        if n <= 0 {
+            return 0, errCorruptChunk
+        }
+        pos += n
+        prev += d
+        if prev < 0 || prev > math.MaxUint16 {
+            return 0, errCorruptChunk
+        }
+        out[i] = uint16(prev)
+    }
+    return pos, nil
+}
+
+// floatOffsets lists the record offsets of the float32 fields present, in layout order.
+func (l *layout) floatOffsets() []int64 {
+    var offs []int64
+    for _, off := range []int64{l.tideRaw, l.moonRkm, l.sunRkm, l.moonRinv3, l.sunRinv3, l.tideRawDt} {
+        if off >= 0 {
+            offs = append(offs, off)
+        }
+    }
+    return offs
+}
+
+// encodeChunk compresses count packed records column by column.
+func encodeChunk(l *layout, recs []byte, count int) []byte {
+    var out []byte
+    if l.tideBPS >= 0 {
+        col := make([]uint16, count)
+        for i := range col {
+            off := int64(i)*l.size + l.tideBPS
+            col[i] = binary.LittleEndian.Uint16(recs[off : off+2])
+        }
+        out = encodeBPSColumn(out, col)
+    }
+    col := make([]uint32, count)
+    for _, fo := range l.floatOffsets() {
+        for i := range col {
+            off := int64(i)*l.size + fo
+            col[i] = binary.LittleEndian.Uint32(recs[off : off+4])
+        }
+        out = encodeFloatColumn(out, col)
+    }
+    return out
+}
+
+// decodeChunk expands a compressed chunk back into count packed records.
+func decodeChunk(l *layout, src []byte, count int) ([]byte, error) {
+    recs := make([]byte, int64(count)*l.size)
+    pos := 0
+    if l.tideBPS >= 0 {
+        col := make([]uint16, count)
+        n, err := decodeBPSColumn(src, col)
+        if err != nil {
+            return nil, err
+        }
+        pos += n
+        for i, v := range col {
+            off := int64(i)*l.size + l.tideBPS
+            binary.LittleEndian.PutUint16(recs[off:off+2], v)
+        }
+    }
+    col := make([]uint32, count)
+    for _, fo := range l.floatOffsets() {
+        n, err := decodeFloatColumn(src[pos:], col)
+        if err != nil {
+            return nil, err
+        }
+        pos += n
+        for i, v := range col {
+            off := int64(i)*l.size + fo
+            binary.LittleEndian.PutUint32(recs[off:off+4], v)
+        }
+    }
+    return recs, nil
+}
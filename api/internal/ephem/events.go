@@ -0,0 +1,236 @@
+package ephem
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// TideFunc samples tide_bps at t as a float so refinement is not limited by
+// the uint16 quantization. ok=false marks a point without data (out of range,
+// gap or corrupt chunk); events are never reported across such points.
+type TideFunc func(t time.Time) (float64, bool)
+
+// EventKind classifies a tide event.
+type EventKind uint8
+
+const (
+    EventMax  EventKind = 1 << iota // local maximum
+    EventMin                        // local minimum
+    EventRise                       // upward threshold crossing
+    EventFall                       // downward threshold crossing
+)
+
+// EventAll selects every kind.
+const EventAll = EventMax | EventMin | EventRise | EventFall
+
+func (k EventKind) String() string {
+    switch k {
+    case EventMax:
+        return "max"
+    case EventMin:
+        return "min"
+    case EventRise:
+        return "rise"
+    case EventFall:
+        return "fall"
+    }
+    return fmt.Sprintf("EventKind(%d)", uint8(k))
+}
+
+// MarshalText encodes the kind as its name, e.g. in JSON.
+func (k EventKind) MarshalText() ([]byte, error) { return []byte(k.String()), nil }
+
+// Event is a refined tide extremum or threshold crossing.
+type Event struct {
+    Kind      EventKind `json:"kind"`
+    Time      time.Time `json:"time"`
+    Value     float64   `json:"tide_bps"`            // tide_bps at Time
+    Threshold float64   `json:"threshold,omitempty"` // crossings only
+}
+
+// EventOptions configures FindEvents.
+type EventOptions struct {
+    // Step is the scan cadence; GTAB.FindEvents defaults it to the table step.
+    Step time.Duration
+    // Kinds filters the reported events; 0 means EventAll.
+    Kinds EventKind
+    // Thresholds lists tide_bps levels whose crossings are reported.
+    Thresholds []float64
+    // Tolerance bounds the refined time of threshold crossings; 0 means 1ms.
+    Tolerance time.Duration
+    // Limit stops the scan after this many events; 0 means no limit.
+    Limit int
+}
+
+// FindEvents scans f over [start, end] every opts.Step and returns events in
+// time order. Extrema are refined by fitting a parabola through the sample at
+// the turn and its neighbours (flat tops report the middle of the plateau);
+// crossings are refined by bisection on f to opts.Tolerance.
+func FindEvents(f TideFunc, start, end time.Time, opts EventOptions) ([]Event, error) {
+    if opts.Step <= 0 {
+        return nil, fmt.Errorf("events: invalid step %v", opts.Step)
+    }
+    if end.Before(start) {
+        return nil, fmt.Errorf("events: end %s before start %s", end.Format(time.RFC3339), start.Format(time.RFC3339))
+    }
+    if opts.Kinds == 0 {
+        opts.Kinds = EventAll
+    }
+    if opts.Tolerance <= 0 {
+        opts.Tolerance = time.Millisecond
+    }
+    s := eventScan{f: f, opts: opts}
+    for t := start; !t.After(end); t = t.Add(opts.Step) {
+        v, ok := f(t)
+        if !ok {
+            s.reset()
+            continue
+        }
+        s.push(t, v)
+        if opts.Limit > 0 && len(s.events) >= opts.Limit {
+            break
+        }
+    }
+    // a crossing is found in the same step as the turn preceding it
+    sort.SliceStable(s.events, func(i, j int) bool { return s.events[i].Time.Before(s.events[j].Time) })
+    if opts.Limit > 0 && len(s.events) > opts.Limit {
+        s.events = s.events[:opts.Limit]
+    }
+    return s.events, nil
+}
+
+// FindEvents scans the table over [start, end] with linear float
+// interpolation of tide_bps. A zero opts.Step scans at the table step.
+func (g *GTAB) FindEvents(start, end time.Time, opts EventOptions) ([]Event, error) {
+    if opts.Step == 0 {
+        opts.Step = g.dt
+    }
+    return FindEvents(g.TideAt, start, end, opts)
+}
+
+// TideAt returns tide_bps at t linearly interpolated without rounding; it
+// satisfies TideFunc.
+func (g *GTAB) TideAt(t time.Time) (float64, bool) {
+    i, frac, ok := g.IndexFor(t)
+    if !ok {
+        return 0, false
+    }
+    v0, ok := g.readTideBPS(i)
+    if !ok {
+        return 0, false
+    }
+    if frac == 0 {
+        return float64(v0), true
+    }
+    v1, ok := g.readTideBPS(i + 1)
+    if !ok {
+        return 0, false
+    }
+    return float64(v0) + (float64(v1)-float64(v0))*frac, true
+}
+
+// eventScan is the incremental state of FindEvents.
+type eventScan struct {
+    f      TideFunc
+    opts   EventOptions
+    events []Event
+    n      int // samples since the last reset
+    // previous sample and the run of equal values it belongs to
+    prevT, runT time.Time
+    prevV       float64
+    // value before the run, and the direction that led into the run
+    beforeV float64
+    trend   int
+}
+
+func (s *eventScan) reset() { s.n, s.trend = 0, 0 }
+
+func (s *eventScan) push(t time.Time, v float64) {
+    if s.n == 0 {
+        s.runT = t
+    } else {
+        s.advance(t, v)
+    }
+    s.prevT, s.prevV = t, v
+    s.n++
+}
+
+// advance compares the new sample with the previous one.
+func (s *eventScan) advance(t time.Time, v float64) {
+    for _, th := range s.opts.Thresholds {
+        if (s.prevV >= th) != (v >= th) {
+            s.crossing(th, s.prevT, t, s.prevV < th)
+        }
+    }
+    d := 0
+    if v > s.prevV {
+        d = 1
+    } else if v < s.prevV {
+        d = -1
+    }
+    if d == 0 {
+        return
+    }
+    if s.trend != 0 && d != s.trend {
+        s.extremum(t, v)
+    }
+    s.trend = d
+    s.beforeV = s.prevV
+    s.runT = t
+}
+
+// extremum records the turn at the run ending at prevT; t, v is the first
+// sample after it.
+func (s *eventScan) extremum(t time.Time, v float64) {
+    kind := EventMin
+    if s.trend > 0 {
+        kind = EventMax
+    }
+    if s.opts.Kinds&kind == 0 {
+        return
+    }
+    ev := Event{Kind: kind, Time: s.prevT, Value: s.prevV}
+    if s.runT.Equal(s.prevT) {
+        // single-sample turn: vertex of the parabola through three equally spaced samples
+        h := float64(s.opts.Step)
+        y0, y1, y2 := s.beforeV, s.prevV, v
+        if den := y0 - 2*y1 + y2; den != 0 {
+            off := h * (y0 - y2) / (2 * den)
+            off = max(-h, min(h, off))
+            ev.Time = s.prevT.Add(time.Duration(off))
+            ev.Value = y1 - (y0-y2)*(y0-y2)/(8*den)
+        }
+    } else {
+        ev.Time = s.runT.Add(s.prevT.Sub(s.runT) / 2)
+    }
+    s.events = append(s.events, ev)
+}
+
+// crossing records a crossing of th between a and b, refined by bisection.
+func (s *eventScan) crossing(th float64, a, b time.Time, rising bool) {
+    kind := EventFall
+    if rising {
+        kind = EventRise
+    }
+    if s.opts.Kinds&kind == 0 {
+        return
+    }
+    for b.Sub(a) > s.opts.Tolerance {
+        mid := a.Add(b.Sub(a) / 2)
+        v, ok := s.f(mid)
+        if !ok {
+            break
+        }
+        if (v >= th) == rising {
+            b = mid
+        } else {
+            a = mid
+        }
+    }
+    v, ok := s.f(b)
+    if !ok {
+        v = th
+    }
+    s.events = append(s.events, Event{Kind: kind, Time: b, Value: v, Threshold: th})
+}
@@ -0,0 +1,75 @@
+package ephem
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFindEventsOnSine(t *testing.T) {
+    t0 := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    period := 12*time.Hour + 25*time.Minute
+    w := 2 * math.Pi / period.Seconds()
+    f := func(t time.Time) (float64, bool) { return 5000 + 4000*math.Sin(w*t.Sub(t0).Seconds()), true }
+    evs, err := FindEvents(f, t0, t0.Add(2*period), EventOptions{Step: 10 * time.Minute, Thresholds: []float64{5000}})
+    if err != nil { t.Fatalf("find: %v", err) }
+    var kinds []EventKind
+    for _, e := range evs { kinds = append(kinds, e.Kind) }
+    // the series starts on the threshold, which counts as above: no initial rise
+    want := []EventKind{EventMax, EventFall, EventMin, EventRise, EventMax, EventFall, EventMin}
+    if len(kinds) != len(want) { t.Fatalf("events: %v", evs) }
+    for i := range want { if kinds[i] != want[i] { t.Fatalf("event %d: %v want %v (%v)", i, kinds[i], want[i], evs) } }
+    peak := t0.Add(period / 4)
+    if d := evs[0].Time.Sub(peak); d < -30*time.Second || d > 30*time.Second { t.Fatalf("max refined to %s, want ~%s", evs[0].Time, peak) }
+    if math.Abs(evs[0].Value-9000) > 1 { t.Fatalf("max value: %v", evs[0].Value) }
+    fall := t0.Add(period / 2)
+    if d := evs[1].Time.Sub(fall); d < -time.Millisecond || d > time.Millisecond { t.Fatalf("fall refined to %s, want %s", evs[1].Time, fall) }
+    if evs[1].Threshold != 5000 || evs[1].Kind.String() != "fall" { t.Fatalf("crossing: %+v", evs[1]) }
+    // Kind filter and limit: next minimum only.
+    evs, _ = FindEvents(f, t0, t0.Add(10*period), EventOptions{Step: 10 * time.Minute, Kinds: EventMin, Limit: 1})
+    if len(evs) != 1 || evs[0].Kind != EventMin { t.Fatalf("next min: %v", evs) }
+    if _, err := FindEvents(f, t0, t0.Add(time.Hour), EventOptions{}); err == nil { t.Fatal("zero step should error") }
+}
+
+func TestFindEventsPlateauAndGaps(t *testing.T) {
+    t0 := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    vals := []float64{1, 2, 3, 3, 3, 2, 1, -1, 0, 5, 4}
+    f := func(t time.Time) (float64, bool) {
+        i := int(t.Sub(t0) / time.Second)
+        if i < 0 || i >= len(vals) || vals[i] < 0 { return 0, false }
+        return vals[i], true
+    }
+    evs, err := FindEvents(f, t0, t0.Add(10*time.Second), EventOptions{Step: time.Second})
+    if err != nil { t.Fatalf("find: %v", err) }
+    // plateau max at 2..4s -> 3s; the gap at 7s hides the minimum at 6..8s; max at 9s
+    if len(evs) != 2 || evs[0].Kind != EventMax || !evs[0].Time.Equal(t0.Add(3*time.Second)) || evs[0].Value != 3 { t.Fatalf("events: %+v", evs) }
+    if evs[1].Kind != EventMax || evs[1].Time.Before(t0.Add(8*time.Second)) || evs[1].Time.After(t0.Add(10*time.Second)) { t.Fatalf("second max: %+v", evs[1]) }
+}
+
+func TestGTABFindEvents(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    w := 2 * math.Pi / 3600 // 1h period at 60s cadence
+    p := writeSineGTAB(t, dir, epoch.Unix(), 60, 181, w, false)
+    g, err := Open(p)
+    if err != nil { t.Fatalf("open: %v", err) }
+    defer g.Close()
+    start, end := g.Coverage()
+    evs, err := g.FindEvents(start, end, EventOptions{Kinds: EventMax | EventRise, Thresholds: []float64{7000}})
+    if err != nil { t.Fatalf("find: %v", err) }
+    var maxes, rises int
+    for _, e := range evs {
+        switch e.Kind {
+        case EventMax:
+            maxes++
+            // peaks at 15, 75, 135 minutes
+            off := e.Time.Sub(epoch) % time.Hour
+            if off < 14*time.Minute || off > 16*time.Minute { t.Fatalf("max at %s", e.Time) }
+        case EventRise:
+            rises++
+            if math.Abs(e.Value-7000) > 2 { t.Fatalf("rise value: %v", e.Value) }
+        }
+    }
+    if maxes != 3 || rises != 3 { t.Fatalf("maxes=%d rises=%d: %+v", maxes, rises, evs) }
+    if v, ok := g.TideAt(epoch.Add(30 * time.Second)); !ok || v <= 5000 { t.Fatalf("TideAt: %v %v", v, ok) }
+}
@@ -0,0 +1,81 @@
+package ephem
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeGapped writes a tide_bps ramp (100*i) with tide_raw, leaving records
+// 10 and 20..22 missing.
+func writeGapped(t *testing.T, dir string, version int) string {
+    t.Helper()
+    p := filepath.Join(dir, "gap.bin")
+    h := Header{Epoch: time.Date(2025,8,1,0,0,0,0,time.UTC), Step: time.Second, Fields: FieldTideBPS | FieldTideRawF32}
+    w, err := CreateWithOptions(p, h, WriteOptions{Version: version, ChunkRecords: 16})
+    if err != nil { t.Fatalf("create: %v", err) }
+    for i := 0; i < 40; i++ {
+        switch {
+        case i == 10:
+            err = w.WriteMissing()
+        case i >= 20 && i <= 22:
+            // a NaN in any field marks the whole record missing
+            err = w.Write(Sample{TideBPS: 5, TideRaw: float32(math.NaN())})
+        default:
+            err = w.Write(Sample{TideBPS: uint16(100 * i), TideRaw: float32(i)})
+        }
+        if err != nil { t.Fatalf("write %d: %v", i, err) }
+    }
+    if err := w.Close(); err != nil { t.Fatalf("close: %v", err) }
+    return p
+}
+
+func TestGapsAreNeverInterpolated(t *testing.T) {
+    dir := t.TempDir()
+    for _, version := range []int{1, 2, 3} {
+        p := writeGapped(t, dir, version)
+        for _, opts := range []Options{{}, {DisableMmap: true}, {Interp: InterpNearest}, {Interp: InterpHermite, DisableMmap: true}} {
+            g, err := OpenWithOptions(p, opts)
+            if err != nil { t.Fatalf("v%d open: %v", version, err) }
+            at := func(sec float64) time.Time { return g.epoch.Add(time.Duration(sec * float64(time.Second))) }
+            s, ok := g.SampleAt(10)
+            if !ok || !s.Missing() || s.TideBPS != MissingBPS || !math.IsNaN(float64(s.TideRaw)) { t.Fatalf("v%d gap record: %+v ok=%v", version, s, ok) }
+            if s, ok := g.SampleAt(21); !ok || !s.Missing() || s.TideBPS != MissingBPS { t.Fatalf("v%d NaN record should be normalized: %+v", version, s) }
+            for _, sec := range []float64{10, 9.5, 10.5, 19.75, 21, 22.25} {
+                if opts.Interp == InterpNearest && sec == 10.5 {
+                    continue // nearest resolves to record 11
+                }
+                if v, ok := g.LookupTideBPS(at(sec)); ok { t.Fatalf("v%d %+v: LookupTideBPS(%v) = %d across a gap", version, opts, sec, v) }
+                if s, ok := g.LookupSample(at(sec)); ok { t.Fatalf("v%d %+v: LookupSample(%v) = %+v across a gap", version, opts, sec, s) }
+                if _, ok := g.TideAt(at(sec)); ok { t.Fatalf("v%d: TideAt(%v) across a gap", version, sec) }
+            }
+            if v, ok := g.LookupTideBPS(at(9)); !ok || v != 900 { t.Fatalf("v%d: lookup beside a gap: %d %v", version, v, ok) }
+            if v, ok := g.LookupTideBPS(at(23)); !ok || v != 2300 { t.Fatalf("v%d: lookup after a gap: %d %v", version, v, ok) }
+            ts := []time.Time{at(9), at(9.5), at(10), at(11), at(11.5), at(21)}
+            checkMany(t, p, ts, g.LookupTideBPSMany, g.LookupTideBPS)
+            // a series steps over the gap points
+            var got []int
+            it := g.Series(at(8), at(24), time.Second)
+            for it.Next() {
+                got = append(got, int(it.Time().Sub(g.epoch)/time.Second))
+                if it.Sample().Missing() { t.Fatalf("v%d: series yielded a gap sample at %v", version, it.Time()) }
+            }
+            if err := it.Err(); err != nil { t.Fatalf("v%d series: %v", version, err) }
+            if want := []int{8, 9, 11, 12, 13, 14, 15, 16, 17, 18, 19, 23, 24}; !equalInts(got, want) { t.Fatalf("v%d series points: %v want %v", version, got, want) }
+            _ = g.Close()
+        }
+    }
+}
+
+func equalInts(a, b []int) bool {
+    if len(a) != len(b) {
+        return false
+    }
+    for i := range a {
+        if a[i] != b[i] {
+            return false
+        }
+    }
+    return true
+}
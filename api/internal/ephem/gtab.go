@@ -1,10 +1,12 @@
 package ephem
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
 	"os"
+	"sync"
 	"time"
 )
 
@@ -16,41 +18,169 @@ const (
     FieldSunRkmF32    uint32 = 0x08
     FieldMoonRinv3F32 uint32 = 0x10
     FieldSunRinv3F32  uint32 = 0x20
+    FieldTideRawDtF32 uint32 = 0x40 // d(tide_raw)/dt per second, enables Hermite interpolation
+
+    // fieldsKnown covers every bit this reader can lay out.
+    fieldsKnown = FieldTideBPS | FieldTideRawF32 | FieldMoonRkmF32 | FieldSunRkmF32 | FieldMoonRinv3F32 | FieldSunRinv3F32 | FieldTideRawDtF32
 )
 
-// GTAB is a minimal reader for the GTAB v1 binary layout described in docs/EPHEMERIS_DATA_FORMAT.md.
-// It supports O(1) indexed access and direct ReadAt without loading entire file into memory.
+// headerSizeV1 is magic[5] + version u16 + epoch i64 + dt_ns i64 + n u32 + fields_mask u32 + time_scale u8 + reserved[15].
+const headerSizeV1 = 5 + 2 + 8 + 8 + 4 + 4 + 16
+
+// Header describes a table's time axis and record layout independent of storage.
+type Header struct {
+    Epoch  time.Time     // time of sample 0 on Scale, whole seconds
+    Step   time.Duration // dt between samples, in seconds of Scale
+    Fields uint32        // fields_mask
+    Scale  TimeScale     // time scale of Epoch and the record grid; zero is UTC
+}
+
+// GTAB is a minimal reader for the GTAB v1/v2/v3 binary layouts described in docs/EPHEMERIS_DATA_FORMAT.md.
+// It supports O(1) indexed access; records are decoded straight from a read-only
+// memory mapping when available, or via ReadAt without loading the entire file into memory.
 type GTAB struct {
-    f          *os.File
-    path       string
+    store
     epoch      time.Time
     dt         time.Duration
-    n          uint32
     fieldsMask uint32
-    headerSize int64
-    recordSize int64
-    offTideBPS int64 // -1 if absent
+    scale      TimeScale
+    lay        layout
+    version    uint16
+    interp     Interp
+    bpsPerRaw  float64 // tide_bps per unit tide_raw, fitted for Hermite; 0 if unknown
+    // v2 only: embedded provenance
+    prov Provenance
+    // v3 only: decoded-chunk cache
+    cache *chunkCache
+}
+
+// layout holds the byte offset of each field within a record (-1 if absent).
+// Fields are packed in the fixed order of the fields_mask bits.
+type layout struct {
+    tideBPS   int64
+    tideRaw   int64
+    moonRkm   int64
+    sunRkm    int64
+    moonRinv3 int64
+    sunRinv3  int64
+    tideRawDt int64
+    size      int64
+}
+
+func newLayout(fields uint32) layout {
+    l := layout{tideBPS: -1, tideRaw: -1, moonRkm: -1, sunRkm: -1, moonRinv3: -1, sunRinv3: -1, tideRawDt: -1}
+    place := func(bit uint32, width int64) int64 {
+        if fields&bit == 0 {
+            return -1
+        }
+        off := l.size
+        l.size += width
+        return off
+    }
+    l.tideBPS = place(FieldTideBPS, 2)
+    l.tideRaw = place(FieldTideRawF32, 4)
+    l.moonRkm = place(FieldMoonRkmF32, 4)
+    l.sunRkm = place(FieldSunRkmF32, 4)
+    l.moonRinv3 = place(FieldMoonRinv3F32, 4)
+    l.sunRinv3 = place(FieldSunRinv3F32, 4)
+    l.tideRawDt = place(FieldTideRawDtF32, 4)
+    return l
+}
+
+// Options tunes how a GTAB file is opened. The zero value selects the defaults.
+type Options struct {
+    // DisableMmap forces the ReadAt path even on platforms that support mmap.
+    DisableMmap bool
+    // Interp selects the interpolation mode; the zero value is InterpLinear.
+    Interp Interp
+    // Verify selects when v2/v3 chunk checksums are checked; the zero value is VerifyLazy.
+    Verify VerifyMode
+    // ChunkCache is the number of decoded v3 chunks kept in memory; 0 selects DefaultChunkCache.
+    ChunkCache int
 }
 
-// Open opens a GTAB file and parses the header.
+// Open opens a GTAB file and parses the header. The file is memory-mapped
+// read-only when the platform supports it, falling back to ReadAt otherwise.
 func Open(path string) (*GTAB, error) {
+    return OpenWithOptions(path, Options{})
+}
+
+// OpenWithOptions is Open with explicit options.
+func OpenWithOptions(path string, opts Options) (*GTAB, error) {
     f, err := os.Open(path)
     if err != nil {
         return nil, err
     }
-    // Layout: magic[5] "GTAB1", version u16, epoch i64, dt_ns i64, n u32, fields_mask u32, reserved[16]
-    hdr := make([]byte, 5+2+8+8+4+4+16)
-    if _, err := io.ReadFull(f, hdr); err != nil {
+    st, err := f.Stat()
+    if err != nil {
         f.Close()
-        return nil, fmt.Errorf("read header: %w", err)
+        return nil, fmt.Errorf("stat file: %w", err)
     }
-    if string(hdr[:5]) != "GTAB1" {
+    g, err := openReader(f, st.Size(), path, opts)
+    if err != nil {
         f.Close()
+        return nil, err
+    }
+    g.closer = f
+    if !opts.DisableMmap {
+        // A failed mapping is not fatal: ReadAt serves the same bytes, just slower.
+        if data, err := mmapFile(f, st.Size()); err == nil {
+            g.data, g.unmap = data, true
+        }
+    }
+    return g.finishOpen(opts)
+}
+
+// OpenReader opens a table held by r, e.g. a file embedded with go:embed.
+// size is the table's length in bytes; name labels errors. Close does not
+// close r.
+func OpenReader(r io.ReaderAt, size int64, name string, opts Options) (*GTAB, error) {
+    g, err := openReader(r, size, name, opts)
+    if err != nil {
+        return nil, err
+    }
+    return g.finishOpen(opts)
+}
+
+// OpenReadCloser is OpenReader for a table that owns its reader: Close also
+// closes c, typically the file r reads from.
+func OpenReadCloser(r io.ReaderAt, c io.Closer, size int64, name string, opts Options) (*GTAB, error) {
+    g, err := openReader(r, size, name, opts)
+    if err != nil {
+        return nil, err
+    }
+    g.closer = c
+    return g.finishOpen(opts)
+}
+
+// OpenBytes opens a table held in memory and serves records from b in place,
+// like a mapping; b must not be modified while the table is open.
+func OpenBytes(b []byte, name string, opts Options) (*GTAB, error) {
+    g, err := openReader(bytes.NewReader(b), int64(len(b)), name, opts)
+    if err != nil {
+        return nil, err
+    }
+    if !opts.DisableMmap {
+        g.data = b
+    }
+    return g.finishOpen(opts)
+}
+
+// openReader parses and validates the header of a table of size bytes in r.
+func openReader(r io.ReaderAt, size int64, path string, opts Options) (*GTAB, error) {
+    // Layout: magic[5] "GTAB1", version u16, epoch i64, dt_ns i64, n u32, fields_mask u32, time_scale u8, reserved[15]
+    // (v2 extends the header in place; see gtab_v2.go)
+    sr := io.NewSectionReader(r, 0, size)
+    hdr := make([]byte, headerSizeV1)
+    if _, err := io.ReadFull(sr, hdr); err != nil {
+        return nil, fmt.Errorf("read header: %w", err)
+    }
+    magic := string(hdr[:5])
+    if magic != "GTAB1" && magic != "GTAB2" && magic != "GTAB3" {
         return nil, fmt.Errorf("%s: invalid GTAB magic", path)
     }
     ver := binary.LittleEndian.Uint16(hdr[5:7])
-    if ver != 1 {
-        f.Close()
+    if magic != fmt.Sprintf("GTAB%d", ver) {
         return nil, fmt.Errorf("%s: unsupported GTAB version: %d", path, ver)
     }
     epochSec := int64(binary.LittleEndian.Uint64(hdr[7:15]))
@@ -59,90 +189,132 @@ func Open(path string) (*GTAB, error) {
     fields := binary.LittleEndian.Uint32(hdr[27:31])
 
     if dtNS <= 0 {
-        f.Close()
         return nil, fmt.Errorf("%s: invalid dt_ns: %d", path, dtNS)
     }
     if n == 0 {
-        f.Close()
         return nil, fmt.Errorf("%s: empty table (n=0)", path)
     }
 
     // Determine record layout in fixed order
-    var recSize int64 = 0
-    offTideBPS := int64(-1)
-    if fields&FieldTideBPS != 0 {
-        offTideBPS = recSize
-        recSize += 2
-    }
-    if fields&FieldTideRawF32 != 0 {
-        recSize += 4
-    }
-    if fields&FieldMoonRkmF32 != 0 {
-        recSize += 4
-    }
-    if fields&FieldSunRkmF32 != 0 {
-        recSize += 4
+    lay := newLayout(fields)
+    recSize := lay.size
+    if recSize == 0 {
+        return nil, fmt.Errorf("%s: empty record layout (fields_mask=0)", path)
     }
-    if fields&FieldMoonRinv3F32 != 0 {
-        recSize += 4
+    dataStart := int64(len(hdr))
+    scale := TimeScale(hdr[31])
+    var ext v2ext
+    var trailer int64
+    if ver >= 2 {
+        var err error
+        if ext, err = readV2Ext(sr, hdr); err != nil {
+            return nil, fmt.Errorf("%s: %w", path, err)
+        }
+        dataStart = headerSizeV2 + ext.metaLen
+        scale = ext.scale
+        if ver == 2 {
+            trailer = 4 * numChunks(int64(n), ext.chunkRecords)
+        }
     }
-    if fields&FieldSunRinv3F32 != 0 {
-        recSize += 4
+    if !scale.valid() {
+        return nil, fmt.Errorf("%s: unsupported time_scale: %d", path, scale)
     }
-    if recSize == 0 {
-        f.Close()
-        return nil, fmt.Errorf("%s: empty record layout (fields_mask=0)", path)
+    // Validate file size matches header + n*recordSize (+ checksum trailer)
+    expected := dataStart + int64(n)*recSize + trailer
+    if ver == 3 {
+        // compressed: size is checked against the chunk index instead
+        expected = dataStart
     }
-    // Validate file size matches header + n*recordSize
-    st, err := f.Stat()
-    if err != nil {
-        f.Close()
-        return nil, fmt.Errorf("stat file: %w", err)
+    if size < expected {
+        return nil, fmt.Errorf("%s: truncated GTAB file: have %d bytes, expected %d", path, size, expected)
     }
-    expected := int64(len(hdr)) + int64(n)*recSize
-    if st.Size() < expected {
-        f.Close()
-        return nil, fmt.Errorf("%s: truncated GTAB file: have %d bytes, expected %d", path, st.Size(), expected)
+    if ver < 3 && size > expected {
+        return nil, fmt.Errorf("%s: %d trailing bytes after GTAB records", path, size-expected)
     }
 
     g := &GTAB{
-        f:          f,
-        path:       path,
+        store:      store{r: r, path: path, n: n, headerSize: dataStart, recordSize: recSize, verify: opts.Verify},
         epoch:      time.Unix(epochSec, 0).UTC(),
         dt:         time.Duration(dtNS),
-        n:          n,
         fieldsMask: fields,
-        headerSize: int64(len(hdr)),
-        recordSize: recSize,
-        offTideBPS: offTideBPS,
+        scale:      scale,
+        lay:        lay,
+        version:    ver,
+    }
+    if ver >= 2 {
+        g.prov = ext.prov
+        g.chunkRecords = ext.chunkRecords
+        var err error
+        if ver == 3 {
+            err = g.loadIndex(ext.indexOff, size, opts.ChunkCache)
+        } else {
+            err = g.loadChecksums()
+        }
+        if err != nil {
+            return nil, fmt.Errorf("%s: %w", path, err)
+        }
     }
     return g, nil
 }
 
-// Close closes the underlying file.
-func (g *GTAB) Close() error {
-    if g.f != nil {
-        return g.f.Close()
+// finishOpen applies the options that read records: eager verification and
+// the interpolation mode. The table is closed on error.
+func (g *GTAB) finishOpen(opts Options) (*GTAB, error) {
+    if g.crcs != nil && opts.Verify == VerifyOnOpen {
+        if err := g.Verify(); err != nil {
+            g.Close()
+            return nil, err
+        }
     }
-    return nil
+    g.SetInterp(opts.Interp)
+    return g, nil
 }
 
-// Coverage returns the inclusive time window covered by the table.
+// Header returns the table's epoch, step, fields_mask and time scale. Epoch
+// is on the table's scale; Coverage reports UTC.
+func (g *GTAB) Header() Header {
+    return Header{Epoch: g.epoch, Step: g.dt, Fields: g.fieldsMask, Scale: g.scale}
+}
+
+// Scale returns the time scale of the table's epoch and record grid.
+func (g *GTAB) Scale() TimeScale { return g.scale }
+
+// TimeAt returns the UTC instant of record i.
+func (g *GTAB) TimeAt(i int64) time.Time {
+    return FromScale(g.epoch.Add(time.Duration(i)*g.dt), g.scale)
+}
+
+// Len returns the number of samples in the table.
+func (g *GTAB) Len() int { return int(g.n) }
+
+// Fields returns the table's fields_mask, i.e. which fields each record carries.
+func (g *GTAB) Fields() uint32 { return g.fieldsMask }
+
+// Mapped reports whether records are served in place from memory (a mapping
+// or OpenBytes) rather than via ReadAt.
+func (g *GTAB) Mapped() bool { return g.data != nil }
+
+// Close releases the mapping (if any) and closes the underlying file.
+// Lookups after Close report ok=false; Close must not race with lookups.
+func (g *GTAB) Close() error { return g.close() }
+
+// Coverage returns the inclusive UTC time window covered by the table.
 func (g *GTAB) Coverage() (start, end time.Time) {
     if g.n == 0 {
-        return g.epoch, g.epoch
+        return g.TimeAt(0), g.TimeAt(0)
     }
-    end = g.epoch.Add(time.Duration(int64(g.n-1)) * g.dt)
-    return g.epoch, end
+    return g.TimeAt(0), g.TimeAt(int64(g.n - 1))
 }
 
-// IndexFor returns the index for timestamp t and fractional position within the interval.
-// If out of range, it returns -1 and false.
+// IndexFor returns the index for UTC timestamp t and fractional position
+// within the interval; t is mapped onto the table's time scale first, so TAI
+// and TT grids stay aligned across leap seconds. If out of range, it returns
+// -1 and false.
 func (g *GTAB) IndexFor(t time.Time) (int64, float64, bool) {
     if g.n == 0 {
         return -1, 0, false
     }
-    dt := t.UTC().Sub(g.epoch)
+    dt := ToScale(t, g.scale).Sub(g.epoch)
     pos := float64(dt) / float64(g.dt)
     if pos < 0 || pos > float64(g.n-1) {
         return -1, 0, false
@@ -152,26 +324,62 @@ func (g *GTAB) IndexFor(t time.Time) (int64, float64, bool) {
     return i, frac, true
 }
 
-// readTideBPS reads the tide_bps value at index i. ok=false if field absent or read fails.
+// readTideBPS reads the tide_bps value at index i. ok=false if field absent,
+// the record is a gap or the read fails.
 func (g *GTAB) readTideBPS(i int64) (uint16, bool) {
-    if g.offTideBPS < 0 {
+    if g.lay.tideBPS < 0 {
         return 0, false
     }
-    off := g.headerSize + i*g.recordSize + g.offTideBPS
-    buf := make([]byte, 2)
-    if _, err := g.f.ReadAt(buf, off); err != nil {
+    if g.index != nil {
+        rec, ok := g.record(i, nil)
+        if !ok {
+            return 0, false
+        }
+        v := binary.LittleEndian.Uint16(rec[g.lay.tideBPS : g.lay.tideBPS+2])
+        return v, v != MissingBPS
+    }
+    if !g.chunkOK(i) {
         return 0, false
     }
-    return binary.LittleEndian.Uint16(buf), true
+    off := g.headerSize + i*g.recordSize + g.lay.tideBPS
+    if g.data != nil {
+        v := binary.LittleEndian.Uint16(g.data[off : off+2])
+        return v, v != MissingBPS
+    }
+    if g.r == nil {
+        return 0, false
+    }
+    buf := bpsBufs.Get().(*[2]byte)
+    defer bpsBufs.Put(buf)
+    if _, err := g.r.ReadAt(buf[:], off); err != nil {
+        return 0, false
+    }
+    v := binary.LittleEndian.Uint16(buf[:])
+    return v, v != MissingBPS
 }
 
-// LookupTideBPS returns the linearly interpolated tide_bps at time t.
-// ok=false when t is out of range or field missing.
+// bpsBufs recycles readTideBPS's read buffers: a stack buffer would escape
+// through io.ReaderAt and cost an allocation per lookup.
+var bpsBufs = sync.Pool{New: func() any { return new([2]byte) }}
+
+// LookupTideBPS returns tide_bps at time t, interpolated according to Interp.
+// ok=false when t is out of range, the field is absent, or a neighbour is a
+// gap: values are never interpolated across missing records.
 func (g *GTAB) LookupTideBPS(t time.Time) (uint16, bool) {
+    if g.interp == InterpHermite {
+        s, ok := g.LookupSample(t)
+        return s.TideBPS, ok && s.Has(FieldTideBPS)
+    }
     i, frac, ok := g.IndexFor(t)
     if !ok {
         return 0, false
     }
+    if g.interp == InterpNearest {
+        if frac >= 0.5 {
+            i++
+        }
+        frac = 0
+    }
     if frac == 0 {
         v, ok := g.readTideBPS(i)
         return v, ok
@@ -181,6 +389,11 @@ func (g *GTAB) LookupTideBPS(t time.Time) (uint16, bool) {
     if !ok0 || !ok1 {
         return 0, false
     }
+    return lerpBPS(v0, v1, frac), true
+}
+
+// lerpBPS linearly interpolates two tide_bps samples, rounding half up and clamping to uint16.
+func lerpBPS(v0, v1 uint16, frac float64) uint16 {
     a := float64(v0)
     b := float64(v1)
     vf := a + (b-a)*frac
@@ -190,5 +403,5 @@ func (g *GTAB) LookupTideBPS(t time.Time) (uint16, bool) {
     if vf > 65535 {
         vf = 65535
     }
-    return uint16(vf + 0.5), true
+    return uint16(vf + 0.5)
 }
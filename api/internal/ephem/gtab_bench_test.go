@@ -30,8 +30,28 @@ func benchWriteGTAB(b *testing.B, n int, dtNS int64) string {
 }
 
 func BenchmarkGTAB_Lookup(b *testing.B) {
-    path := benchWriteGTAB(b, 60*60, 1_000_000_000) // 1 hour @1s
-    g, err := Open(path)
+    benchLookup(b, benchWriteGTAB(b, 60*60, 1_000_000_000), Options{}) // 1 hour @1s
+}
+
+// BenchmarkGTAB_LookupReadAt measures the non-mmap fallback for comparison.
+func BenchmarkGTAB_LookupReadAt(b *testing.B) {
+    benchLookup(b, benchWriteGTAB(b, 60*60, 1_000_000_000), Options{DisableMmap: true})
+}
+
+// BenchmarkGTAB_LookupCompressed measures v3 lookups with a warm chunk cache.
+func BenchmarkGTAB_LookupCompressed(b *testing.B) {
+    path := filepath.Join(b.TempDir(), "bench_v3.bin")
+    w, err := CreateWithOptions(path, Header{Epoch: time.Now().UTC().Truncate(time.Second), Step: time.Second, Fields: FieldTideBPS}, WriteOptions{Version: 3, ChunkRecords: 1024})
+    if err != nil { b.Fatalf("create: %v", err) }
+    for i := 0; i < 3600; i++ {
+        if err := w.Write(Sample{TideBPS: uint16(i * 10000 / 3599)}); err != nil { b.Fatalf("write: %v", err) }
+    }
+    if err := w.Close(); err != nil { b.Fatalf("close: %v", err) }
+    benchLookup(b, path, Options{})
+}
+
+func benchLookup(b *testing.B, path string, opts Options) {
+    g, err := OpenWithOptions(path, opts)
     if err != nil { b.Fatalf("open: %v", err) }
     defer g.Close()
     start, _ := g.Coverage()
@@ -47,3 +67,39 @@ func BenchmarkGTAB_Lookup(b *testing.B) {
     }
     _ = sum
 }
+
+// BenchmarkGTAB_SeriesReadAt streams a full hour without mmap; ns/op is per point.
+func BenchmarkGTAB_SeriesReadAt(b *testing.B) {
+    path := benchWriteGTAB(b, 60*60, 1_000_000_000)
+    g, err := OpenWithOptions(path, Options{DisableMmap: true})
+    if err != nil { b.Fatalf("open: %v", err) }
+    defer g.Close()
+    start, end := g.Coverage()
+    b.ResetTimer()
+    var sum uint32
+    for n := 0; n < b.N; {
+        it := g.Series(start, end, 500*time.Millisecond)
+        for n < b.N && it.Next() {
+            sum += uint32(it.TideBPS())
+            n++
+        }
+    }
+    _ = sum
+}
+
+// BenchmarkGTAB_LookupManyReadAt batch-looks up b.N unsorted points without
+// mmap; ns/op is per point.
+func BenchmarkGTAB_LookupManyReadAt(b *testing.B) {
+    path := benchWriteGTAB(b, 60*60, 1_000_000_000)
+    g, err := OpenWithOptions(path, Options{DisableMmap: true})
+    if err != nil { b.Fatalf("open: %v", err) }
+    defer g.Close()
+    start, _ := g.Coverage()
+    ts := make([]time.Time, b.N)
+    for i := 0; i < b.N; i++ {
+        ts[i] = start.Add(time.Duration((i*7919)%7200) * 500 * time.Millisecond)
+    }
+    out := make([]uint16, b.N)
+    b.ResetTimer()
+    g.LookupTideBPSMany(ts, out)
+}
@@ -68,3 +68,30 @@ func unusedPort() string {
     _ = l.Close()
     return addr
 }
+
+func TestMmapAndReadAtAgree(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Unix(1_726_001_000,0).UTC().Unix()
+    p := writeGTABValues(t, dir, "vals.bin", epoch, 1_000_000_000, []uint16{0, 250, 9999, 10000, 42})
+    mapped, err := Open(p); if err != nil { t.Fatalf("open mmap: %v", err) }
+    t.Cleanup(func(){ _ = mapped.Close() })
+    plain, err := OpenWithOptions(p, Options{DisableMmap: true}); if err != nil { t.Fatalf("open readat: %v", err) }
+    t.Cleanup(func(){ _ = plain.Close() })
+    if plain.Mapped() { t.Fatal("DisableMmap should force ReadAt path") }
+    for ms := int64(0); ms <= 4000; ms += 125 {
+        ts := time.Unix(epoch, ms*1_000_000).UTC()
+        a, okA := mapped.LookupTideBPS(ts)
+        b, okB := plain.LookupTideBPS(ts)
+        if a != b || okA != okB { t.Fatalf("mismatch at +%dms: mmap=%d/%v readat=%d/%v", ms, a, okA, b, okB) }
+    }
+}
+
+func TestMappedAccessAfterCloseSafe(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Unix(1_726_001_500,0).UTC().Unix()
+    p := writeGTABValues(t, dir, "vals.bin", epoch, 1_000_000_000, []uint16{10,20})
+    g, err := Open(p); if err != nil { t.Fatalf("open: %v", err) }
+    if err := g.Close(); err != nil { t.Fatalf("close: %v", err) }
+    if g.Mapped() { t.Fatal("mapping should be released on Close") }
+    if _, ok := g.LookupTideBPS(time.Unix(epoch,0).UTC()); ok { t.Fatal("expected ok=false after close") }
+}
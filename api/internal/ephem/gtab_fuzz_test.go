@@ -9,14 +9,17 @@ import (
 
 // FuzzLookupTideBPS exercises LookupTideBPS invariants with synthetic tables.
 // Invariants:
-//  - Returned ok implies value within [0,65535].
+//  - Returned ok implies value within [0,10000].
 //  - Query outside coverage returns ok=false.
 //  - Interpolation monotonic when underlying endpoints monotonic.
+//  - Gap records (MissingBPS) answer ok=false.
 func FuzzLookupTideBPS(f *testing.F) {
     // Seed with small deterministic cases
-    f.Add(uint32(5), int64(60))
-    f.Add(uint32(2), int64(30))
-    f.Fuzz(func(t *testing.T, n uint32, stepNS int64) {
+    f.Add(uint32(5), int64(60), false)
+    f.Add(uint32(2), int64(30), false)
+    // a table of gap sentinels only
+    f.Add(uint32(4), int64(60), true)
+    f.Fuzz(func(t *testing.T, n uint32, stepNS int64, gaps bool) {
         if n < 2 { // need at least two points to interpolate
             n = 2
         }
@@ -53,16 +56,20 @@ func FuzzLookupTideBPS(f *testing.F) {
         last := 0
         for i := uint32(0); i < n; i++ {
             var v int
-            if monotonic {
+            switch {
+            case gaps:
+                v = int(MissingBPS)
+            case monotonic:
                 last += rand.Intn(100) // non-decreasing
-                if last > 65535 {
-                    last = 65535
+                if last > 10000 {
+                    last = 10000
                 }
                 v = last
-            } else {
-                v = rand.Intn(70000) // allow >65535 to test clamp
-                if v > 65535 {
-                    v = 65535
+            default:
+                // allow >10000 to test clamp; 0xFFFF is reserved for gaps
+                v = rand.Intn(12000)
+                if v > 10000 {
+                    v = 10000
                 }
             }
             off := i * 2
@@ -91,15 +98,16 @@ func FuzzLookupTideBPS(f *testing.F) {
         for _, qt := range queries {
             v, ok := g.LookupTideBPS(qt)
             if ok {
-                if v > 65535 { t.Fatalf("value > 65535: %d", v) }
-            } else {
+                if gaps { t.Fatalf("gap record answered ok at %v: %d", qt, v) }
+                if v > 10000 { t.Fatalf("value > 10000: %d", v) }
+            } else if !gaps {
                 if !qt.Before(start) && !qt.After(end) {
                     t.Fatalf("expected in-range ok for %v", qt)
                 }
             }
         }
         // Monotonicity: sample sequential times and ensure non-decreasing if underlying was monotonic
-        if monotonic {
+        if monotonic && !gaps {
             prev := uint16(0)
             for i := 0; i < 20; i++ {
                 frac := float64(i) / 19.0
@@ -2,6 +2,7 @@ package ephem
 
 import (
 	"encoding/binary"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -92,3 +93,20 @@ func TestGTAB_Open_Truncated(t *testing.T) {
     os.Truncate(p, fi.Size()-2)
     if _, err := Open(p); err == nil { t.Fatal("expected truncated file error") }
 }
+
+func TestGTAB_Open_SizeMismatch(t *testing.T) {
+    dir := t.TempDir()
+    h := Header{Epoch: time.Date(2025,8,1,0,0,0,0,time.UTC), Step: time.Minute, Fields: FieldTideBPS | FieldTideRawF32}
+    for version := 1; version <= 3; version++ {
+        p := filepath.Join(dir, fmt.Sprintf("v%d.bin", version))
+        w, err := CreateWithOptions(p, h, WriteOptions{Version: version, ChunkRecords: 16})
+        if err != nil { t.Fatalf("create: %v", err) }
+        for i := 0; i < 40; i++ { w.Write(Sample{TideBPS: uint16(i), TideRaw: float32(i)}) }
+        if err := w.Close(); err != nil { t.Fatalf("close: %v", err) }
+        b, err := os.ReadFile(p)
+        if err != nil { t.Fatal(err) }
+        if g, err := OpenBytes(b, "exact", Options{}); err != nil { t.Fatalf("v%d exact: %v", version, err) } else { g.Close() }
+        if _, err := OpenBytes(b[:len(b)-1], "short", Options{}); err == nil { t.Fatalf("v%d truncated table accepted", version) }
+        if _, err := OpenBytes(append(b, 0), "padded", Options{}); err == nil { t.Fatalf("v%d trailing bytes accepted", version) }
+    }
+}
@@ -0,0 +1,135 @@
+package ephem
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+)
+
+// GTAB v2 keeps the v1 prefix (magic "GTAB2", version 2) and replaces the 16
+// reserved bytes with an extended 64-byte header:
+//
+//	31:35 chunk_records u32 — records per CRC32 chunk
+//	35:39 meta_len u32      — bytes of the JSON provenance block after the header
+//	39:43 meta_crc u32      — CRC32 (IEEE) of the provenance block
+//	43:51 reserved (v3: index_off)
+//	51    time_scale u8     — see TimeScale (v1 keeps it at offset 31)
+//	52:64 reserved
+//
+// Records follow the provenance block; a trailer of ceil(n/chunk_records) u32
+// CRC32 values, one per chunk of record bytes, follows the records.
+const (
+    headerSizeV2        = 64
+    DefaultChunkRecords = 4096
+    maxMetaLen          = 1 << 20
+)
+
+// ErrChecksum reports a CRC mismatch in a GTAB v2 chunk or provenance block.
+var ErrChecksum = errors.New("gtab: checksum mismatch")
+
+// ErrNoChecksums is returned by Verify for formats without embedded checksums (v1).
+var ErrNoChecksums = errors.New("gtab: table has no embedded checksums")
+
+// Provenance is the metadata block embedded in GTAB v2 tables. It mirrors the
+// keys of the sibling gtab.meta.json written by the generator.
+type Provenance struct {
+    DatasetID       string    `json:"dataset_id"`
+    GeneratorCommit string    `json:"generator_commit,omitempty"`
+    KernelHash      string    `json:"kernel_hash,omitempty"`
+    CreatedAt       time.Time `json:"created_at"`
+}
+
+// VerifyMode selects when chunk checksums of a v2 table are checked.
+type VerifyMode uint8
+
+const (
+    // VerifyLazy checks each chunk the first time a lookup touches it (default).
+    VerifyLazy VerifyMode = iota
+    // VerifyOnOpen checks every chunk before Open returns.
+    VerifyOnOpen
+    // VerifyOff never checks implicitly; Verify still works on demand.
+    VerifyOff
+)
+
+// v2ext is the parsed v2 extension of the header.
+type v2ext struct {
+    chunkRecords int64
+    prov         Provenance
+    metaLen      int64
+    indexOff     int64 // v3 only
+    scale        TimeScale
+}
+
+func readV2Ext(r io.Reader, prefix []byte) (v2ext, error) {
+    rest := make([]byte, headerSizeV2-len(prefix))
+    if _, err := io.ReadFull(r, rest); err != nil {
+        return v2ext{}, fmt.Errorf("read v2 header: %w", err)
+    }
+    hdr := append(append([]byte(nil), prefix...), rest...)
+    ext := v2ext{
+        chunkRecords: int64(binary.LittleEndian.Uint32(hdr[31:35])),
+        metaLen:      int64(binary.LittleEndian.Uint32(hdr[35:39])),
+        indexOff:     int64(binary.LittleEndian.Uint64(hdr[43:51])),
+        scale:        TimeScale(hdr[51]),
+    }
+    metaCRC := binary.LittleEndian.Uint32(hdr[39:43])
+    if ext.chunkRecords == 0 {
+        return v2ext{}, errors.New("invalid chunk_records: 0")
+    }
+    if ext.metaLen > maxMetaLen {
+        return v2ext{}, fmt.Errorf("provenance block too large: %d bytes", ext.metaLen)
+    }
+    meta := make([]byte, ext.metaLen)
+    if _, err := io.ReadFull(r, meta); err != nil {
+        return v2ext{}, fmt.Errorf("read provenance: %w", err)
+    }
+    if crc32.ChecksumIEEE(meta) != metaCRC {
+        return v2ext{}, fmt.Errorf("provenance block: %w", ErrChecksum)
+    }
+    if len(meta) > 0 {
+        if err := json.Unmarshal(meta, &ext.prov); err != nil {
+            return v2ext{}, fmt.Errorf("decode provenance: %w", err)
+        }
+    }
+    return ext, nil
+}
+
+// encodeHeaderV2 renders the extended header shared by v2 and v3 (indexOff is v3 only).
+func encodeHeaderV2(version int, h Header, n uint32, chunkRecords int64, meta []byte, indexOff int64) []byte {
+    hdr := make([]byte, headerSizeV2)
+    copy(hdr[:5], fmt.Sprintf("GTAB%d", version))
+    binary.LittleEndian.PutUint16(hdr[5:7], uint16(version))
+    binary.LittleEndian.PutUint64(hdr[7:15], uint64(h.Epoch.Unix()))
+    binary.LittleEndian.PutUint64(hdr[15:23], uint64(h.Step))
+    binary.LittleEndian.PutUint32(hdr[23:27], n)
+    binary.LittleEndian.PutUint32(hdr[27:31], h.Fields)
+    binary.LittleEndian.PutUint32(hdr[31:35], uint32(chunkRecords))
+    binary.LittleEndian.PutUint32(hdr[35:39], uint32(len(meta)))
+    binary.LittleEndian.PutUint32(hdr[39:43], crc32.ChecksumIEEE(meta))
+    binary.LittleEndian.PutUint64(hdr[43:51], uint64(indexOff))
+    hdr[51] = byte(h.Scale)
+    return hdr
+}
+
+func numChunks(n, chunkRecords int64) int64 {
+    return (n + chunkRecords - 1) / chunkRecords
+}
+
+// Version returns the on-disk format version (1, 2 or 3).
+func (g *GTAB) Version() int { return int(g.version) }
+
+// Provenance returns the embedded metadata block; ok=false for v1 tables.
+func (g *GTAB) Provenance() (Provenance, bool) {
+    if g.version < 2 {
+        return Provenance{}, false
+    }
+    return g.prov, true
+}
+
+// Verify checks every chunk checksum and returns the first mismatch, or
+// ErrNoChecksums for v1 tables. Results are cached for later lookups.
+func (g *GTAB) Verify() error { return g.verifyAll() }
@@ -0,0 +1,89 @@
+package ephem
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeV2(t *testing.T, path string, n, chunk int, prov Provenance) string {
+    t.Helper()
+    h := Header{Epoch: time.Date(2025,8,1,0,0,0,0,time.UTC), Step: time.Second, Fields: FieldTideBPS | FieldTideRawF32}
+    w, err := CreateWithOptions(path, h, WriteOptions{Version: 2, Provenance: prov, ChunkRecords: chunk})
+    if err != nil { t.Fatalf("create: %v", err) }
+    for i := 0; i < n; i++ {
+        if err := w.Write(Sample{TideBPS: uint16(i), TideRaw: float32(i) / 10}); err != nil { t.Fatalf("write: %v", err) }
+    }
+    if err := w.Close(); err != nil { t.Fatalf("close: %v", err) }
+    return path
+}
+
+func TestV2RoundTripWithProvenance(t *testing.T) {
+    dir := t.TempDir()
+    created := time.Date(2025,8,2,3,4,5,0,time.UTC)
+    prov := Provenance{DatasetID: "ds_v2", GeneratorCommit: "abc123", KernelHash: "sha256:feed", CreatedAt: created}
+    p := writeV2(t, filepath.Join(dir, "v2.bin"), 1000, 64, prov)
+    for _, opts := range []Options{{}, {DisableMmap: true}, {Verify: VerifyOnOpen}} {
+        g, err := OpenWithOptions(p, opts)
+        if err != nil { t.Fatalf("open %+v: %v", opts, err) }
+        if g.Version() != 2 { t.Fatalf("version: %d", g.Version()) }
+        got, ok := g.Provenance()
+        if !ok || got.DatasetID != "ds_v2" || got.GeneratorCommit != "abc123" || got.KernelHash != "sha256:feed" || !got.CreatedAt.Equal(created) { t.Fatalf("provenance: %+v", got) }
+        if g.Len() != 1000 { t.Fatalf("len: %d", g.Len()) }
+        if v, ok := g.LookupTideBPS(g.epoch.Add(999 * time.Second)); !ok || v != 999 { t.Fatalf("last sample: %d %v", v, ok) }
+        if s, ok := g.LookupSample(g.epoch.Add(500*time.Second + 500*time.Millisecond)); !ok || s.TideBPS != 501 { t.Fatalf("interp: %+v %v", s, ok) }
+        if err := g.Verify(); err != nil { t.Fatalf("verify: %v", err) }
+        _ = g.Close()
+    }
+}
+
+func TestV2DetectsCorruptChunk(t *testing.T) {
+    dir := t.TempDir()
+    p := writeV2(t, filepath.Join(dir, "v2.bin"), 256, 64, Provenance{DatasetID: "x"})
+    g, err := Open(p)
+    if err != nil { t.Fatalf("open: %v", err) }
+    dataStart := g.headerSize
+    recSize := g.recordSize
+    _ = g.Close()
+    // Flip a byte inside record 130 (chunk 2).
+    b, _ := os.ReadFile(p)
+    b[dataStart+130*recSize] ^= 0xff
+    if err := os.WriteFile(p, b, 0o644); err != nil { t.Fatalf("rewrite: %v", err) }
+
+    for _, opts := range []Options{{}, {DisableMmap: true}} {
+        g, err := OpenWithOptions(p, opts)
+        if err != nil { t.Fatalf("lazy open should succeed: %v", err) }
+        if v, ok := g.LookupTideBPS(g.epoch.Add(10 * time.Second)); !ok || v != 10 { t.Fatalf("intact chunk: %d %v", v, ok) }
+        if _, ok := g.LookupTideBPS(g.epoch.Add(140 * time.Second)); ok { t.Fatal("lookup in corrupt chunk should fail") }
+        if _, ok := g.LookupSample(g.epoch.Add(129 * time.Second)); ok { t.Fatal("sample in corrupt chunk should fail") }
+        if err := g.Verify(); !errors.Is(err, ErrChecksum) { t.Fatalf("verify: %v", err) }
+        _ = g.Close()
+    }
+    if _, err := OpenWithOptions(p, Options{Verify: VerifyOnOpen}); !errors.Is(err, ErrChecksum) { t.Fatalf("eager verify should fail: %v", err) }
+    g, err = OpenWithOptions(p, Options{Verify: VerifyOff})
+    if err != nil { t.Fatalf("open: %v", err) }
+    if _, ok := g.LookupTideBPS(g.epoch.Add(140 * time.Second)); !ok { t.Fatal("VerifyOff should serve unverified data") }
+    _ = g.Close()
+}
+
+func TestV2CorruptProvenanceAndV1Verify(t *testing.T) {
+    dir := t.TempDir()
+    p := writeV2(t, filepath.Join(dir, "v2.bin"), 10, 0, Provenance{DatasetID: "meta"})
+    b, _ := os.ReadFile(p)
+    b[headerSizeV2+2] ^= 0x01
+    bad := filepath.Join(dir, "badmeta.bin")
+    os.WriteFile(bad, b, 0o644)
+    if _, err := Open(bad); !errors.Is(err, ErrChecksum) { t.Fatalf("expected provenance checksum error: %v", err) }
+    // Truncated trailer
+    os.WriteFile(bad, b[:len(b)-2], 0o644)
+    if _, err := Open(bad); err == nil { t.Fatal("expected truncation error") }
+
+    v1 := writeConst(t, filepath.Join(dir, "v1.bin"), time.Unix(0, 0), time.Second, 3, 7)
+    g, err := Open(v1)
+    if err != nil { t.Fatalf("open v1: %v", err) }
+    t.Cleanup(func(){ _ = g.Close() })
+    if _, ok := g.Provenance(); ok { t.Fatal("v1 has no provenance") }
+    if err := g.Verify(); !errors.Is(err, ErrNoChecksums) { t.Fatalf("v1 verify: %v", err) }
+}
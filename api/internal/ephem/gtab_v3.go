@@ -0,0 +1,164 @@
+package ephem
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"sync"
+	"sync/atomic"
+)
+
+// GTAB v3 is the compressed chunked encoding. It shares the v2 header
+// (magic "GTAB3", version 3) and provenance block, and adds:
+//
+//	43:51 index_off u64 — file offset of the chunk index
+//
+// The body holds compressed chunks of chunk_records records each (see
+// codec.go). The chunk index holds one {offset u64, length u32, crc32 u32}
+// entry per chunk, so any record is reached with a single index lookup.
+const (
+    chunkIndexEntry   = 16
+    DefaultChunkCache = 8
+)
+
+type chunkRef struct {
+    off int64
+    len int64
+}
+
+// chunkCache keeps the most recently decoded chunks. Decoded slices are
+// immutable, so callers may keep using one after it is evicted.
+type chunkCache struct {
+    mu      sync.Mutex
+    cap     int
+    tick    uint64
+    entries []cacheEntry
+}
+
+type cacheEntry struct {
+    chunk int64
+    recs  []byte
+    used  uint64
+}
+
+func (c *chunkCache) get(chunk int64) ([]byte, bool) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    for i := range c.entries {
+        if c.entries[i].chunk == chunk {
+            c.tick++
+            c.entries[i].used = c.tick
+            return c.entries[i].recs, true
+        }
+    }
+    return nil, false
+}
+
+func (c *chunkCache) put(chunk int64, recs []byte) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.tick++
+    if len(c.entries) < c.cap {
+        c.entries = append(c.entries, cacheEntry{chunk: chunk, recs: recs, used: c.tick})
+        return
+    }
+    lru := 0
+    for i := range c.entries {
+        if c.entries[i].chunk == chunk {
+            return
+        }
+        if c.entries[i].used < c.entries[lru].used {
+            lru = i
+        }
+    }
+    c.entries[lru] = cacheEntry{chunk: chunk, recs: recs, used: c.tick}
+}
+
+// loadIndex reads the v3 chunk index and the CRC of each compressed chunk.
+func (g *GTAB) loadIndex(indexOff, fileSize int64, cacheSize int) error {
+    nc := numChunks(int64(g.n), g.chunkRecords)
+    if indexOff < g.headerSize || indexOff+nc*chunkIndexEntry > fileSize {
+        return fmt.Errorf("chunk index out of bounds: offset %d, %d chunks, file %d bytes", indexOff, nc, fileSize)
+    }
+    if end := indexOff + nc*chunkIndexEntry; end < fileSize {
+        return fmt.Errorf("%d trailing bytes after chunk index", fileSize-end)
+    }
+    buf := make([]byte, nc*chunkIndexEntry)
+    if _, err := g.r.ReadAt(buf, indexOff); err != nil {
+        return fmt.Errorf("read chunk index: %w", err)
+    }
+    g.index = make([]chunkRef, nc)
+    g.crcs = make([]uint32, nc)
+    for i := range g.index {
+        e := buf[i*chunkIndexEntry:]
+        ref := chunkRef{off: int64(binary.LittleEndian.Uint64(e[0:8])), len: int64(binary.LittleEndian.Uint32(e[8:12]))}
+        if ref.off < g.headerSize || ref.off+ref.len > indexOff {
+            return fmt.Errorf("chunk %d out of bounds", i)
+        }
+        g.index[i] = ref
+        g.crcs[i] = binary.LittleEndian.Uint32(e[12:16])
+    }
+    g.chunkState = make([]atomic.Uint32, nc)
+    if cacheSize <= 0 {
+        cacheSize = DefaultChunkCache
+    }
+    g.cache = &chunkCache{cap: cacheSize}
+    return nil
+}
+
+// Compressed reports whether records are stored in the v3 compressed encoding.
+func (g *GTAB) Compressed() bool { return g.index != nil }
+
+// chunkBytes returns the stored (compressed) bytes of chunk c.
+func (g *GTAB) chunkBytes(c int64) ([]byte, error) {
+    return g.bytes(g.index[c].off, g.index[c].len)
+}
+
+// compressedRecord returns record i from its decoded chunk, decoding and
+// caching the chunk on a miss.
+func (g *GTAB) compressedRecord(i int64) ([]byte, bool) {
+    if g.r == nil {
+        return nil, false // closed; the cache may still hold decoded chunks
+    }
+    c := i / g.chunkRecords
+    recs, ok := g.cache.get(c)
+    if !ok {
+        src, err := g.chunkBytes(c)
+        if err != nil {
+            return nil, false
+        }
+        count := g.chunkRecords
+        if rem := int64(g.n) - c*g.chunkRecords; rem < count {
+            count = rem
+        }
+        if recs, err = decodeChunk(&g.lay, src, int(count)); err != nil {
+            return nil, false
+        }
+        g.cache.put(c, recs)
+    }
+    off := (i - c*g.chunkRecords) * g.recordSize
+    return recs[off : off+g.recordSize], true
+}
+
+// chunkWriter accumulates records and emits compressed chunks for a v3 Writer.
+type chunkWriter struct {
+    pending []byte
+    index   []byte
+}
+
+func (cw *chunkWriter) flush(w *Writer) error {
+    if len(cw.pending) == 0 {
+        return nil
+    }
+    count := len(cw.pending) / int(w.lay.size)
+    enc := encodeChunk(&w.lay, cw.pending, count)
+    if _, err := w.bw.Write(enc); err != nil {
+        return err
+    }
+    cw.index = binary.LittleEndian.AppendUint64(cw.index, uint64(w.off))
+    cw.index = binary.LittleEndian.AppendUint32(cw.index, uint32(len(enc)))
+    cw.index = binary.LittleEndian.AppendUint32(cw.index, crc32.ChecksumIEEE(enc))
+    w.off += int64(len(enc))
+    cw.pending = cw.pending[:0]
+    return nil
+}
@@ -0,0 +1,110 @@
+package ephem
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeSmooth writes the same orbital-looking series as v1 and as v3.
+func writeSmooth(t *testing.T, dir string, n, chunk int) (v1, v3 string) {
+    t.Helper()
+    h := Header{Epoch: time.Date(2025,8,1,0,0,0,0,time.UTC), Step: time.Minute, Fields: FieldTideBPS | FieldTideRawF32 | FieldMoonRkmF32 | FieldSunRkmF32 | FieldTideRawDtF32}
+    v1, v3 = filepath.Join(dir, "v1.bin"), filepath.Join(dir, "v3.bin")
+    w1, err := Create(v1, h)
+    if err != nil { t.Fatalf("create v1: %v", err) }
+    w3, err := CreateWithOptions(v3, h, WriteOptions{Version: 3, Provenance: Provenance{DatasetID: "ds_v3"}, ChunkRecords: chunk})
+    if err != nil { t.Fatalf("create v3: %v", err) }
+    for i := 0; i < n; i++ {
+        ph := float64(i) / 700
+        raw := float32(math.Sin(ph))
+        s := Sample{TideBPS: uint16(5000 + 4000*math.Sin(ph)), TideRaw: raw, MoonRkm: float32(384400 + 20000*math.Cos(ph/27)), SunRkm: 149.6e6, TideRawDt: float32(math.Cos(ph) / 700)}
+        if err := w1.Write(s); err != nil { t.Fatalf("write v1: %v", err) }
+        if err := w3.Write(s); err != nil { t.Fatalf("write v3: %v", err) }
+    }
+    if err := w1.Close(); err != nil { t.Fatalf("close v1: %v", err) }
+    if err := w3.Close(); err != nil { t.Fatalf("close v3: %v", err) }
+    return v1, v3
+}
+
+func TestV3MatchesV1(t *testing.T) {
+    v1, v3 := writeSmooth(t, t.TempDir(), 10000, 512)
+    a, err := Open(v1)
+    if err != nil { t.Fatalf("open v1: %v", err) }
+    defer a.Close()
+    for _, opts := range []Options{{}, {DisableMmap: true}, {Verify: VerifyOnOpen, ChunkCache: 1}} {
+        b, err := OpenWithOptions(v3, opts)
+        if err != nil { t.Fatalf("open v3 %+v: %v", opts, err) }
+        if !b.Compressed() || b.Version() != 3 || b.Len() != a.Len() { t.Fatalf("v3 header: compressed=%v version=%d len=%d", b.Compressed(), b.Version(), b.Len()) }
+        if prov, ok := b.Provenance(); !ok || prov.DatasetID != "ds_v3" { t.Fatalf("provenance: %+v", prov) }
+        for i := 0; i < a.Len(); i += 7 {
+            at := a.epoch.Add(time.Duration(i)*a.dt + 13*time.Second)
+            va, _ := a.LookupTideBPS(at)
+            vb, ok := b.LookupTideBPS(at)
+            if !ok || va != vb { t.Fatalf("bps at %d: v1=%d v3=%d ok=%v", i, va, vb, ok) }
+            sa, _ := a.LookupSample(at)
+            sb, ok := b.LookupSample(at)
+            if !ok || sa != sb { t.Fatalf("sample at %d: v1=%+v v3=%+v", i, sa, sb) }
+        }
+        if err := b.Verify(); err != nil { t.Fatalf("verify: %v", err) }
+        _ = b.Close()
+    }
+}
+
+func TestV3Compresses(t *testing.T) {
+    v1, v3 := writeSmooth(t, t.TempDir(), 20000, 0)
+    s1, _ := os.Stat(v1)
+    s3, _ := os.Stat(v3)
+    if s3.Size()*4 > s1.Size()*3 { t.Fatalf("expected >25%% savings on smooth data: v1=%d v3=%d", s1.Size(), s3.Size()) }
+}
+
+func TestV3DetectsCorruptChunk(t *testing.T) {
+    _, p := writeSmooth(t, t.TempDir(), 2048, 256)
+    g, err := Open(p)
+    if err != nil { t.Fatalf("open: %v", err) }
+    ref := g.index[3]
+    _ = g.Close()
+    b, _ := os.ReadFile(p)
+    b[ref.off+ref.len/2] ^= 0xff
+    os.WriteFile(p, b, 0o644)
+    for _, opts := range []Options{{}, {DisableMmap: true}} {
+        g, err := OpenWithOptions(p, opts)
+        if err != nil { t.Fatalf("lazy open should succeed: %v", err) }
+        if _, ok := g.LookupTideBPS(g.epoch.Add(10 * time.Minute)); !ok { t.Fatal("intact chunk should serve") }
+        if _, ok := g.LookupSample(g.epoch.Add(800 * time.Minute)); ok { t.Fatal("corrupt chunk should fail") }
+        if err := g.Verify(); !errors.Is(err, ErrChecksum) { t.Fatalf("verify: %v", err) }
+        _ = g.Close()
+    }
+    // An index pointing past the file is rejected at open.
+    os.WriteFile(p, b[:len(b)-8], 0o644)
+    if _, err := Open(p); err == nil { t.Fatal("expected truncated index error") }
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+    rng := rand.New(rand.NewSource(7))
+    for _, n := range []int{1, 2, 5, 1000} {
+        floats := make([]uint32, n)
+        bps := make([]uint16, n)
+        for i := range floats {
+            if i%3 == 0 { floats[i] = rng.Uint32() } else { floats[i] = math.Float32bits(float32(math.Sin(float64(i) / 50))) }
+            bps[i] = uint16(rng.Intn(65536))
+        }
+        got := make([]uint32, n)
+        enc := encodeFloatColumn(nil, floats)
+        if used, err := decodeFloatColumn(enc, got); err != nil || used != len(enc) { t.Fatalf("n=%d decode floats: used=%d/%d err=%v", n, used, len(enc), err) }
+        for i := range floats { if got[i] != floats[i] { t.Fatalf("n=%d float %d: %08x != %08x", n, i, got[i], floats[i]) } }
+        gotBPS := make([]uint16, n)
+        encBPS := encodeBPSColumn(nil, bps)
+        if _, err := decodeBPSColumn(encBPS, gotBPS); err != nil { t.Fatalf("decode bps: %v", err) }
+        for i := range bps { if gotBPS[i] != bps[i] { t.Fatalf("bps %d: %d != %d", i, gotBPS[i], bps[i]) } }
+        // Truncated input must error rather than panic.
+        if n > 1 {
+            if _, err := decodeFloatColumn(enc[:len(enc)/2], got); err == nil && len(enc) > 8 { t.Fatalf("n=%d truncated floats decoded", n) }
+            if _, err := decodeBPSColumn(encBPS[:len(encBPS)-1], gotBPS); err == nil { t.Fatalf("n=%d truncated bps decoded", n) }
+        }
+    }
+}
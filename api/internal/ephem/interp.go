@@ -0,0 +1,134 @@
+package ephem
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Interp selects how lookups blend the two records surrounding a timestamp.
+type Interp uint8
+
+const (
+    // InterpLinear blends neighbouring records linearly (default).
+    InterpLinear Interp = iota
+    // InterpNearest returns the closest record without blending.
+    InterpNearest
+    // InterpHermite fits a cubic Hermite spline through tide_raw using the stored
+    // d(tide_raw)/dt field; tide_bps follows via the table's bps/raw scale. Tables
+    // without FieldTideRawF32 and FieldTideRawDtF32 degrade to InterpLinear.
+    InterpHermite
+)
+
+func (m Interp) String() string {
+    switch m {
+    case InterpLinear:
+        return "linear"
+    case InterpNearest:
+        return "nearest"
+    case InterpHermite:
+        return "hermite"
+    }
+    return fmt.Sprintf("interp(%d)", uint8(m))
+}
+
+// ParseInterp maps "nearest", "linear" or "hermite" (case-insensitive) to an Interp.
+func ParseInterp(s string) (Interp, error) {
+    switch strings.ToLower(strings.TrimSpace(s)) {
+    case "linear", "":
+        return InterpLinear, nil
+    case "nearest":
+        return InterpNearest, nil
+    case "hermite":
+        return InterpHermite, nil
+    }
+    return InterpLinear, fmt.Errorf("unknown interpolation mode %q", s)
+}
+
+// Interp returns the effective interpolation mode.
+func (g *GTAB) Interp() Interp { return g.interp }
+
+// SetInterp changes the interpolation mode. Hermite silently degrades to linear
+// when the table lacks tide_raw or its derivative. Not safe to call concurrently
+// with lookups; configure the table before sharing it.
+func (g *GTAB) SetInterp(m Interp) {
+    const need = FieldTideRawF32 | FieldTideRawDtF32
+    if m == InterpHermite && g.fieldsMask&need != need {
+        m = InterpLinear
+    }
+    if m == InterpHermite && g.bpsPerRaw == 0 && g.fieldsMask&FieldTideBPS != 0 {
+        g.bpsPerRaw = g.fitBPSScale()
+    }
+    g.interp = m
+}
+
+// fitBPSScale estimates d(tide_bps)/d(tide_raw) by least squares over evenly
+// spaced unclamped records. The generator maps raw to bps affinely, so the
+// fit recovers the normalization slope without it being stored. Returns 0 if
+// the table offers too little spread to fit.
+func (g *GTAB) fitBPSScale() float64 {
+    const probes = 256
+    step := int64(g.n) / probes
+    if step < 1 {
+        step = 1
+    }
+    var xs, ys []float64
+    for i := int64(0); i < int64(g.n); i += step {
+        s, ok := g.SampleAt(i)
+        if !ok || s.Missing() || s.TideBPS == 0 || s.TideBPS >= 10000 {
+            continue
+        }
+        xs = append(xs, float64(s.TideRaw))
+        ys = append(ys, float64(s.TideBPS))
+    }
+    if len(xs) < 2 {
+        return 0
+    }
+    var mx, my float64
+    for i := range xs {
+        mx += xs[i]
+        my += ys[i]
+    }
+    mx /= float64(len(xs))
+    my /= float64(len(ys))
+    var sxx, sxy float64
+    for i := range xs {
+        dx := xs[i] - mx
+        sxx += dx * dx
+        sxy += dx * (ys[i] - my)
+    }
+    if sxx == 0 || math.IsNaN(sxx) {
+        return 0
+    }
+    return sxy / sxx
+}
+
+// hermite evaluates the cubic Hermite basis on [0,1] for endpoint values p0,p1
+// and slopes m0,m1 already scaled to the interval length.
+func hermite(p0, p1, m0, m1, s float64) float64 {
+    s2 := s * s
+    s3 := s2 * s
+    return (2*s3-3*s2+1)*p0 + (s3-2*s2+s)*m0 + (-2*s3+3*s2)*p1 + (s3-s2)*m1
+}
+
+// hermiteSample interpolates tide_raw with stored derivatives and corrects the
+// linear tide_bps by the curvature the spline adds over the straight line.
+// Other fields remain linear.
+func (g *GTAB) hermiteSample(a, b Sample, frac float64) Sample {
+    out := lerpSample(a, b, frac)
+    h := g.dt.Seconds()
+    raw := hermite(float64(a.TideRaw), float64(b.TideRaw), float64(a.TideRawDt)*h, float64(b.TideRawDt)*h, frac)
+    lin := float64(out.TideRaw)
+    out.TideRaw = float32(raw)
+    if g.bpsPerRaw != 0 {
+        bps := float64(a.TideBPS) + (float64(b.TideBPS)-float64(a.TideBPS))*frac + (raw-lin)*g.bpsPerRaw
+        if bps < 0 {
+            bps = 0
+        }
+        if bps > 65535 {
+            bps = 65535
+        }
+        out.TideBPS = uint16(bps + 0.5)
+    }
+    return out
+}
@@ -0,0 +1,79 @@
+package ephem
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeSineGTAB writes tide_bps, tide_raw and d(tide_raw)/dt for raw(t)=sin(w*t) sampled every dtSec.
+func writeSineGTAB(t *testing.T, dir string, epoch int64, dtSec float64, n int, w float64, withDt bool) string {
+    t.Helper()
+    fields := FieldTideBPS | FieldTideRawF32
+    if withDt { fields |= FieldTideRawDtF32 }
+    p := filepath.Join(dir, "sine.bin")
+    wr, err := Create(p, Header{Epoch: time.Unix(epoch, 0), Step: time.Duration(dtSec * 1e9), Fields: fields})
+    if err != nil { t.Fatalf("create: %v", err) }
+    for i := 0; i < n; i++ {
+        x := w * float64(i) * dtSec
+        raw := math.Sin(x)
+        s := Sample{TideBPS: uint16(5000 + 4000*raw), TideRaw: float32(raw), TideRawDt: float32(w * math.Cos(x))}
+        if err := wr.Write(s); err != nil { t.Fatalf("write: %v", err) }
+    }
+    if err := wr.Close(); err != nil { t.Fatalf("close: %v", err) }
+    return p
+}
+
+func TestParseInterp(t *testing.T) {
+    for in, want := range map[string]Interp{"": InterpLinear, "linear": InterpLinear, "Nearest": InterpNearest, " hermite ": InterpHermite} {
+        got, err := ParseInterp(in)
+        if err != nil || got != want { t.Fatalf("ParseInterp(%q)=%v,%v want %v", in, got, err, want) }
+    }
+    if _, err := ParseInterp("cubic"); err == nil { t.Fatal("expected error for unknown mode") }
+}
+
+func TestHermiteBeatsLinearNearPeak(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC).Unix()
+    w := 2 * math.Pi / 40 // 40s period, 10 samples per quarter
+    p := writeSineGTAB(t, dir, epoch, 1, 81, w, true)
+    lin, err := Open(p); if err != nil { t.Fatalf("open: %v", err) }
+    t.Cleanup(func(){ _ = lin.Close() })
+    her, err := OpenWithOptions(p, Options{Interp: InterpHermite}); if err != nil { t.Fatalf("open: %v", err) }
+    t.Cleanup(func(){ _ = her.Close() })
+    if her.Interp() != InterpHermite { t.Fatalf("expected hermite mode, got %v", her.Interp()) }
+    var errLin, errHer float64
+    for ms := int64(9000); ms <= 11000; ms += 100 { // around the first maximum at t=10s
+        ts := time.Unix(epoch, ms*1_000_000).UTC()
+        want := math.Sin(w * float64(ms) / 1000)
+        sl, _ := lin.LookupSample(ts)
+        sh, _ := her.LookupSample(ts)
+        errLin = math.Max(errLin, math.Abs(float64(sl.TideRaw)-want))
+        errHer = math.Max(errHer, math.Abs(float64(sh.TideRaw)-want))
+        bps, ok := her.LookupTideBPS(ts)
+        if !ok { t.Fatalf("hermite bps lookup failed at %dms", ms) }
+        if d := math.Abs(float64(bps) - (5000 + 4000*want)); d > 2 { t.Fatalf("hermite bps off by %.1f at %dms", d, ms) }
+    }
+    if errHer >= errLin/10 { t.Fatalf("hermite not sharper than linear: hermite=%g linear=%g", errHer, errLin) }
+}
+
+func TestHermiteWithoutDerivativeDegrades(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC).Unix()
+    p := writeSineGTAB(t, dir, epoch, 1, 10, 0.3, false)
+    g, err := OpenWithOptions(p, Options{Interp: InterpHermite}); if err != nil { t.Fatalf("open: %v", err) }
+    t.Cleanup(func(){ _ = g.Close() })
+    if g.Interp() != InterpLinear { t.Fatalf("expected linear fallback, got %v", g.Interp()) }
+}
+
+func TestNearestInterp(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Unix(1_726_003_000,0).UTC().Unix()
+    p := writeGTABValues(t, dir, "vals.bin", epoch, 1_000_000_000, []uint16{100, 200})
+    g, err := OpenWithOptions(p, Options{Interp: InterpNearest}); if err != nil { t.Fatalf("open: %v", err) }
+    t.Cleanup(func(){ _ = g.Close() })
+    if v, _ := g.LookupTideBPS(time.Unix(epoch, 400_000_000).UTC()); v != 100 { t.Fatalf("nearest below half: %d", v) }
+    if v, _ := g.LookupTideBPS(time.Unix(epoch, 600_000_000).UTC()); v != 200 { t.Fatalf("nearest above half: %d", v) }
+    if s, _ := g.LookupSample(time.Unix(epoch, 600_000_000).UTC()); s.TideBPS != 200 { t.Fatalf("nearest sample: %+v", s) }
+}
@@ -0,0 +1,352 @@
+package ephem
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ITAB is the companion of GTAB for event-driven signals (funding rates,
+// sentiment, realized volatility) sampled at irregular times. It keeps the
+// 64-byte v2 header shape, provenance block and chunk checksums:
+//
+//	0:5   magic "ITAB1"
+//	5:7   version u16 = 1
+//	7:23  zero (GTAB epoch and dt_ns; dt_ns 0 marks an irregular table)
+//	23:27 n u32             — number of points
+//	27:31 fields_mask u32   — 0x01 value_f64, the only column defined
+//	31:35 chunk_records u32 — points per CRC32 chunk
+//	35:39 meta_len u32
+//	39:43 meta_crc u32
+//	43:51 reserved
+//	51    time_scale u8     — scale of the timestamps (see TimeScale)
+//	52:64 reserved
+//
+// The provenance block is followed by two columns, n strictly increasing i64
+// timestamps (unix nanoseconds on the table's scale) and n f64 values, then a
+// trailer of one CRC32 per chunk over the chunk's timestamp bytes followed by
+// its value bytes. A NaN value marks a missing observation.
+const itabValueF64 uint32 = 0x01
+
+// Fill selects how an ITAB answers times between observations.
+type Fill uint8
+
+const (
+    // FillPrevious carries the last observation forward (LOCF, default).
+    FillPrevious Fill = iota
+    // FillLinear interpolates between the observations either side; times
+    // after the last observation are not answered.
+    FillLinear
+)
+
+func (f Fill) String() string {
+    switch f {
+    case FillPrevious:
+        return "locf"
+    case FillLinear:
+        return "linear"
+    }
+    return fmt.Sprintf("fill(%d)", uint8(f))
+}
+
+// ParseFill maps "locf" (or "previous") and "linear" (case-insensitive) to a Fill.
+func ParseFill(s string) (Fill, error) {
+    switch strings.ToLower(strings.TrimSpace(s)) {
+    case "locf", "previous", "":
+        return FillPrevious, nil
+    case "linear":
+        return FillLinear, nil
+    }
+    return FillPrevious, fmt.Errorf("unknown fill mode %q", s)
+}
+
+// ITABOptions tunes how an ITAB answers lookups. The zero value selects LOCF
+// without an age limit.
+type ITABOptions struct {
+    Fill Fill
+    // MaxGap, if positive, is the longest stretch a lookup bridges: LOCF
+    // refuses observations older than MaxGap and linear mode refuses to
+    // interpolate between observations further apart.
+    MaxGap time.Duration
+}
+
+// ITAB is an irregular-timestamp series loaded into memory. Event series are
+// small, so the whole table is read and verified on open; lookups are a
+// binary search. Safe for concurrent lookups.
+type ITAB struct {
+    scale TimeScale
+    prov  Provenance
+    ts    []int64 // unix ns on scale, strictly increasing
+    vals  []float64
+    opts  ITABOptions
+}
+
+// OpenITAB reads and verifies the ITAB file at path.
+func OpenITAB(path string, opts ITABOptions) (*ITAB, error) {
+    b, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    return OpenITABBytes(b, path, opts)
+}
+
+// OpenITABBytes parses an ITAB held in memory; name labels errors. b is not
+// retained.
+func OpenITABBytes(b []byte, name string, opts ITABOptions) (*ITAB, error) {
+    s, err := parseITAB(b)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", name, err)
+    }
+    s.opts = opts
+    return s, nil
+}
+
+func parseITAB(b []byte) (*ITAB, error) {
+    if len(b) < headerSizeV2 {
+        return nil, fmt.Errorf("truncated ITAB header: %d bytes", len(b))
+    }
+    if string(b[:5]) != "ITAB1" {
+        return nil, errors.New("invalid ITAB magic")
+    }
+    if ver := binary.LittleEndian.Uint16(b[5:7]); ver != 1 {
+        return nil, fmt.Errorf("unsupported ITAB version: %d", ver)
+    }
+    n := int64(binary.LittleEndian.Uint32(b[23:27]))
+    if fields := binary.LittleEndian.Uint32(b[27:31]); fields != itabValueF64 {
+        return nil, fmt.Errorf("unsupported ITAB fields_mask: %#x", fields)
+    }
+    if n == 0 {
+        return nil, errors.New("empty table (n=0)")
+    }
+    s := &ITAB{scale: TimeScale(b[51])}
+    if !s.scale.valid() {
+        return nil, fmt.Errorf("unsupported time_scale: %d", s.scale)
+    }
+    // the v2 extension parser handles chunk_records and the provenance block
+    ext, err := readV2Ext(bytes.NewReader(b[31:]), b[:31])
+    if err != nil {
+        return nil, err
+    }
+    s.prov = ext.prov
+    data := headerSizeV2 + ext.metaLen
+    trailer := 4 * numChunks(n, ext.chunkRecords)
+    want := data + 16*n + trailer
+    if int64(len(b)) < want {
+        return nil, fmt.Errorf("truncated ITAB file: have %d bytes, expected %d", len(b), want)
+    }
+    if int64(len(b)) > want {
+        return nil, fmt.Errorf("%d trailing bytes after ITAB checksums", int64(len(b))-want)
+    }
+    tsb, vb := b[data:data+8*n], b[data+8*n:data+16*n]
+    crcs := b[data+16*n : data+16*n+trailer]
+    for c := int64(0); c < numChunks(n, ext.chunkRecords); c++ {
+        lo, hi := c*ext.chunkRecords, min((c+1)*ext.chunkRecords, n)
+        crc := crc32.Update(crc32.ChecksumIEEE(tsb[8*lo:8*hi]), crc32.IEEETable, vb[8*lo:8*hi])
+        if crc != binary.LittleEndian.Uint32(crcs[4*c:]) {
+            return nil, fmt.Errorf("chunk %d: %w", c, ErrChecksum)
+        }
+    }
+    s.ts, s.vals = make([]int64, n), make([]float64, n)
+    for i := range s.ts {
+        s.ts[i] = int64(binary.LittleEndian.Uint64(tsb[8*i:]))
+        s.vals[i] = math.Float64frombits(binary.LittleEndian.Uint64(vb[8*i:]))
+        if i > 0 && s.ts[i] <= s.ts[i-1] {
+            return nil, fmt.Errorf("timestamps not strictly increasing at point %d", i)
+        }
+    }
+    return s, nil
+}
+
+// Len returns the number of observations.
+func (s *ITAB) Len() int { return len(s.ts) }
+
+// Scale returns the time scale the timestamps are stored on.
+func (s *ITAB) Scale() TimeScale { return s.scale }
+
+// Provenance returns the embedded provenance block.
+func (s *ITAB) Provenance() (Provenance, bool) { return s.prov, true }
+
+// Options returns the lookup options the table was opened with.
+func (s *ITAB) Options() ITABOptions { return s.opts }
+
+// At returns observation i as a UTC time and value.
+func (s *ITAB) At(i int) (time.Time, float64) {
+    return FromScale(time.Unix(0, s.ts[i]).UTC(), s.scale), s.vals[i]
+}
+
+// Coverage returns the UTC times of the first and last observations.
+func (s *ITAB) Coverage() (start, end time.Time) {
+    start, _ = s.At(0)
+    end, _ = s.At(len(s.ts) - 1)
+    return start, end
+}
+
+// Search returns the index of the last observation at or before UTC time t,
+// or -1 if t precedes the first.
+func (s *ITAB) Search(t time.Time) int { return s.search(ToScale(t, s.scale).UnixNano()) }
+
+func (s *ITAB) search(x int64) int {
+    return sort.Search(len(s.ts), func(i int) bool { return s.ts[i] > x }) - 1
+}
+
+// Lookup returns the value at UTC time t according to the table's Fill.
+// ok=false before the first observation, on a missing (NaN) observation,
+// beyond MaxGap, and in linear mode after the last observation.
+func (s *ITAB) Lookup(t time.Time) (float64, bool) {
+    x := ToScale(t, s.scale).UnixNano()
+    i := s.search(x)
+    if i < 0 {
+        return 0, false
+    }
+    gap := int64(s.opts.MaxGap)
+    v0 := s.vals[i]
+    if math.IsNaN(v0) {
+        return 0, false
+    }
+    if s.ts[i] == x {
+        return v0, true
+    }
+    if s.opts.Fill == FillPrevious {
+        if gap > 0 && x-s.ts[i] > gap {
+            return 0, false
+        }
+        return v0, true
+    }
+    if i+1 == len(s.ts) {
+        return 0, false
+    }
+    t0, t1, v1 := s.ts[i], s.ts[i+1], s.vals[i+1]
+    if math.IsNaN(v1) || (gap > 0 && t1-t0 > gap) {
+        return 0, false
+    }
+    return v0 + (v1-v0)*float64(x-t0)/float64(t1-t0), true
+}
+
+// ITABWriteOptions configures a new ITAB.
+type ITABWriteOptions struct {
+    // Scale is the time scale timestamps are stored on; zero is UTC.
+    Scale TimeScale
+    // Provenance is embedded in the table; a zero CreatedAt is set to now.
+    Provenance Provenance
+    // ChunkRecords is the checksum chunk size; 0 selects DefaultChunkRecords.
+    ChunkRecords int
+}
+
+// ITABWriter collects observations and writes an ITAB on Close. Like Create,
+// the table is staged beside path and renamed into place.
+type ITABWriter struct {
+    path string
+    opts ITABWriteOptions
+    ts   []int64
+    vals []float64
+    err  error
+}
+
+// CreateITAB starts a new ITAB at path.
+func CreateITAB(path string, opts ITABWriteOptions) (*ITABWriter, error) {
+    if !opts.Scale.valid() {
+        return nil, fmt.Errorf("unknown time scale: %v", opts.Scale)
+    }
+    if opts.ChunkRecords <= 0 {
+        opts.ChunkRecords = DefaultChunkRecords
+    }
+    if opts.ChunkRecords > math.MaxUint32 {
+        return nil, fmt.Errorf("chunk_records too large: %d", opts.ChunkRecords)
+    }
+    if opts.Provenance.CreatedAt.IsZero() {
+        opts.Provenance.CreatedAt = time.Now().UTC().Truncate(time.Second)
+    }
+    return &ITABWriter{path: path, opts: opts}, nil
+}
+
+// Write appends the observation v at UTC time t; times must be strictly
+// increasing. A NaN v records a missing observation.
+func (w *ITABWriter) Write(t time.Time, v float64) error {
+    if w.err != nil {
+        return w.err
+    }
+    x := ToScale(t, w.opts.Scale).UnixNano()
+    if n := len(w.ts); n > 0 && x <= w.ts[n-1] {
+        return fmt.Errorf("observation at %s is not after the previous one", t.Format(time.RFC3339Nano))
+    }
+    if len(w.ts) == math.MaxUint32 {
+        w.err = errors.New("too many observations for ITAB")
+        return w.err
+    }
+    w.ts, w.vals = append(w.ts, x), append(w.vals, v)
+    return nil
+}
+
+// Len returns the number of observations written so far.
+func (w *ITABWriter) Len() int { return len(w.ts) }
+
+// Close writes the table. An empty table is discarded with an error.
+func (w *ITABWriter) Close() error {
+    if w.err != nil {
+        return w.err
+    }
+    w.err = errWriterClosed
+    if len(w.ts) == 0 {
+        return errors.New("empty table (n=0)")
+    }
+    f, err := os.Create(w.path + ".tmp")
+    if err != nil {
+        return err
+    }
+    err = w.encode(f)
+    if cerr := f.Close(); err == nil {
+        err = cerr
+    }
+    if err == nil {
+        err = os.Rename(f.Name(), w.path)
+    }
+    if err != nil {
+        os.Remove(f.Name())
+    }
+    return err
+}
+
+func (w *ITABWriter) encode(out io.Writer) error {
+    meta, err := json.Marshal(w.opts.Provenance)
+    if err != nil {
+        return fmt.Errorf("encode provenance: %w", err)
+    }
+    n, chunk := int64(len(w.ts)), int64(w.opts.ChunkRecords)
+    hdr := make([]byte, headerSizeV2)
+    copy(hdr[:5], "ITAB1")
+    binary.LittleEndian.PutUint16(hdr[5:7], 1)
+    binary.LittleEndian.PutUint32(hdr[23:27], uint32(n))
+    binary.LittleEndian.PutUint32(hdr[27:31], itabValueF64)
+    binary.LittleEndian.PutUint32(hdr[31:35], uint32(chunk))
+    binary.LittleEndian.PutUint32(hdr[35:39], uint32(len(meta)))
+    binary.LittleEndian.PutUint32(hdr[39:43], crc32.ChecksumIEEE(meta))
+    hdr[51] = byte(w.opts.Scale)
+    tsb, vb := make([]byte, 8*n), make([]byte, 8*n)
+    for i := range w.ts {
+        binary.LittleEndian.PutUint64(tsb[8*i:], uint64(w.ts[i]))
+        binary.LittleEndian.PutUint64(vb[8*i:], math.Float64bits(w.vals[i]))
+    }
+    trailer := make([]byte, 4*numChunks(n, chunk))
+    for c := int64(0); c < numChunks(n, chunk); c++ {
+        lo, hi := c*chunk, min((c+1)*chunk, n)
+        crc := crc32.Update(crc32.ChecksumIEEE(tsb[8*lo:8*hi]), crc32.IEEETable, vb[8*lo:8*hi])
+        binary.LittleEndian.PutUint32(trailer[4*c:], crc)
+    }
+    bw := bufio.NewWriter(out)
+    for _, part := range [][]byte{hdr, meta, tsb, vb, trailer} {
+        if _, err := bw.Write(part); err != nil {
+            return err
+        }
+    }
+    return bw.Flush()
+}
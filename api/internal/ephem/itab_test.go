@@ -0,0 +1,101 @@
+package ephem
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeITAB writes observations at base+offs[k] with values vals[k].
+func writeITAB(t *testing.T, path string, base time.Time, offs []time.Duration, vals []float64, opts ITABWriteOptions) {
+    t.Helper()
+    w, err := CreateITAB(path, opts)
+    if err != nil { t.Fatalf("create: %v", err) }
+    for k := range offs {
+        if err := w.Write(base.Add(offs[k]), vals[k]); err != nil { t.Fatalf("write %d: %v", k, err) }
+    }
+    if err := w.Close(); err != nil { t.Fatalf("close: %v", err) }
+}
+
+func TestITABLookupModes(t *testing.T) {
+    base := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    p := filepath.Join(t.TempDir(), "funding.itab")
+    offs := []time.Duration{0, 8 * time.Hour, 16 * time.Hour, 17 * time.Hour, 40 * time.Hour, 41 * time.Hour}
+    vals := []float64{0.0001, 0.0003, -0.0001, math.NaN(), 0.0002, 0.0004}
+    writeITAB(t, p, base, offs, vals, ITABWriteOptions{Provenance: Provenance{DatasetID: "funding_btc"}, ChunkRecords: 4})
+
+    locf, err := OpenITAB(p, ITABOptions{})
+    if err != nil { t.Fatalf("open: %v", err) }
+    if locf.Len() != 6 || locf.Scale() != ScaleUTC { t.Fatalf("len %d scale %s", locf.Len(), locf.Scale()) }
+    if prov, _ := locf.Provenance(); prov.DatasetID != "funding_btc" { t.Fatalf("provenance: %+v", prov) }
+    if start, end := locf.Coverage(); !start.Equal(base) || !end.Equal(base.Add(41*time.Hour)) { t.Fatalf("coverage %s..%s", start, end) }
+    lin, err := OpenITAB(p, ITABOptions{Fill: FillLinear})
+    if err != nil { t.Fatalf("open: %v", err) }
+    bounded, err := OpenITAB(p, ITABOptions{MaxGap: 9 * time.Hour})
+    if err != nil { t.Fatalf("open: %v", err) }
+
+    cases := []struct {
+        at            time.Duration
+        locf, linear  float64 // NaN: no data
+        bounded       float64
+    }{
+        {-time.Second, math.NaN(), math.NaN(), math.NaN()},
+        {0, 0.0001, 0.0001, 0.0001},
+        {4 * time.Hour, 0.0001, 0.0002, 0.0001},
+        {8 * time.Hour, 0.0003, 0.0003, 0.0003},
+        {16*time.Hour + 30*time.Minute, -0.0001, math.NaN(), -0.0001}, // next point is missing
+        {20 * time.Hour, math.NaN(), math.NaN(), math.NaN()},          // LOCF of a missing point
+        {40*time.Hour + 30*time.Minute, 0.0002, 0.0003, 0.0002},
+        {50 * time.Hour, 0.0004, math.NaN(), 0.0004},
+        {51 * time.Hour, 0.0004, math.NaN(), math.NaN()},
+    }
+    for _, c := range cases {
+        for _, m := range []struct {
+            s    *ITAB
+            want float64
+        }{{locf, c.locf}, {lin, c.linear}, {bounded, c.bounded}} {
+            v, ok := m.s.Lookup(base.Add(c.at))
+            if math.IsNaN(m.want) {
+                if ok { t.Fatalf("%+v at %v: got %g, want no data", m.s.Options(), c.at, v) }
+                continue
+            }
+            if !ok || math.Abs(v-m.want) > 1e-12 { t.Fatalf("%+v at %v: %g %v, want %g", m.s.Options(), c.at, v, ok, m.want) }
+        }
+    }
+    if i := locf.Search(base.Add(17 * time.Hour)); i != 3 { t.Fatalf("search exact: %d", i) }
+    if i := locf.Search(base.Add(-time.Hour)); i != -1 { t.Fatalf("search before: %d", i) }
+}
+
+func TestITABScaleAndValidation(t *testing.T) {
+    dir := t.TempDir()
+    leap := time.Date(2017,1,1,0,0,0,0,time.UTC)
+    p := filepath.Join(dir, "tai.itab")
+    writeITAB(t, p, leap, []time.Duration{-2 * time.Second, 2 * time.Second}, []float64{0, 4}, ITABWriteOptions{Scale: ScaleTAI})
+    s, err := OpenITAB(p, ITABOptions{Fill: FillLinear})
+    if err != nil { t.Fatalf("open: %v", err) }
+    // five SI seconds elapse across the leap second, so midnight is 3/5 of the way
+    if v, ok := s.Lookup(leap); !ok || math.Abs(v-2.4) > 1e-9 { t.Fatalf("TAI interpolation across leap second: %g %v", v, ok) }
+    if at, _ := s.At(1); !at.Equal(leap.Add(2 * time.Second)) { t.Fatalf("At(1) = %s", at) }
+
+    w, err := CreateITAB(filepath.Join(dir, "bad.itab"), ITABWriteOptions{})
+    if err != nil { t.Fatal(err) }
+    if err := w.Write(leap, 1); err != nil { t.Fatal(err) }
+    if err := w.Write(leap, 2); err == nil { t.Fatal("duplicate timestamp accepted") }
+    if _, err := CreateITAB(filepath.Join(dir, "x.itab"), ITABWriteOptions{Scale: 7}); err == nil { t.Fatal("unknown scale accepted") }
+    empty, _ := CreateITAB(filepath.Join(dir, "empty.itab"), ITABWriteOptions{})
+    if err := empty.Close(); err == nil { t.Fatal("empty table accepted") }
+    if _, err := os.Stat(filepath.Join(dir, "empty.itab")); !os.IsNotExist(err) { t.Fatal("empty table written") }
+
+    b, err := os.ReadFile(p)
+    if err != nil { t.Fatal(err) }
+    if _, err := OpenITABBytes(append(b[:len(b):len(b)], 0), "padded", ITABOptions{}); err == nil { t.Fatal("trailing bytes accepted") }
+    b[len(b)-5] ^= 0xff // last value byte
+    if _, err := OpenITABBytes(b, "corrupt", ITABOptions{}); !errors.Is(err, ErrChecksum) { t.Fatalf("corrupt value: %v", err) }
+    if _, err := OpenITABBytes(b[:70], "short", ITABOptions{}); err == nil { t.Fatal("truncated table accepted") }
+    if _, err := OpenITAB(writeGTABValues(t, dir, "g.bin", leap.Unix(), 1e9, []uint16{1}), ITABOptions{}); err == nil { t.Fatal("GTAB accepted as ITAB") }
+    if _, err := ParseFill("spline"); err == nil { t.Fatal("unknown fill accepted") }
+    if f, err := ParseFill("LOCF"); err != nil || f != FillPrevious { t.Fatalf("parse locf: %v %v", f, err) }
+}
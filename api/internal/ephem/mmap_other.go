@@ -0,0 +1,15 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package ephem
+
+import (
+	"errors"
+	"os"
+)
+
+// mmapFile is unavailable on this platform; callers fall back to ReadAt.
+func mmapFile(f *os.File, size int64) ([]byte, error) {
+    return nil, errors.New("mmap: unsupported platform")
+}
+
+func munmapFile(b []byte) error { return nil }
@@ -0,0 +1,19 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package ephem
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// mmapFile maps the first size bytes of f read-only and shared.
+func mmapFile(f *os.File, size int64) ([]byte, error) {
+    if size <= 0 || int64(int(size)) != size {
+        return nil, fmt.Errorf("mmap: unsupported size %d", size)
+    }
+    return syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func munmapFile(b []byte) error { return syscall.Munmap(b) }
@@ -0,0 +1,63 @@
+package ephem
+
+import (
+	"math"
+	"time"
+)
+
+// Named lunar phases. The four principal phases are instants; each name here
+// covers the 45° of elongation centred on its angle (new 0°, first quarter
+// 90°, full 180°, last quarter 270°), so "new" and "full" mark the syzygy
+// windows and the crescent/gibbous names the stretches between.
+const (
+	PhaseNew            = "new"
+	PhaseWaxingCrescent = "waxing_crescent"
+	PhaseFirstQuarter   = "first_quarter"
+	PhaseWaxingGibbous  = "waxing_gibbous"
+	PhaseFull           = "full"
+	PhaseWaningGibbous  = "waning_gibbous"
+	PhaseLastQuarter    = "last_quarter"
+	PhaseWaningCrescent = "waning_crescent"
+)
+
+var phaseNames = [8]string{
+    PhaseNew, PhaseWaxingCrescent, PhaseFirstQuarter, PhaseWaxingGibbous,
+    PhaseFull, PhaseWaningGibbous, PhaseLastQuarter, PhaseWaningCrescent,
+}
+
+// LunarPhase describes the Moon's phase at an instant.
+type LunarPhase struct {
+    // Angle is the Moon's ecliptic longitude minus the Sun's, in [0, 360):
+    // 0 new, 90 first quarter, 180 full, 270 last quarter.
+    Angle float64
+    // Illumination is the illuminated fraction of the disc, 0..1.
+    Illumination float64
+    // Name is one of the Phase* constants.
+    Name string
+}
+
+// Waxing reports whether the illuminated fraction is growing.
+func (p LunarPhase) Waxing() bool { return p.Angle < 180 }
+
+// PhaseName returns the named phase for an elongation angle in degrees.
+func PhaseName(angle float64) string {
+    return phaseNames[int(normDeg(angle+22.5)/45)%8]
+}
+
+// PhaseAt computes the lunar phase at t from the analytic Sun and Moon
+// positions. The Moon's ecliptic latitude is neglected, which overstates the
+// elongation near syzygy by up to ~5° and the illuminated fraction at new moon
+// by at most 0.002.
+func PhaseAt(t time.Time) LunarPhase {
+    sunLon, rs := SunPosition(t)
+    moonLon, rm := MoonPosition(t)
+    angle := normDeg(moonLon - sunLon)
+    // Meeus (48.3): the Sun–Moon–Earth angle from elongation and distances.
+    psi := deg2rad(angle)
+    i := math.Atan2(rs*math.Sin(psi), rm-rs*math.Cos(psi))
+    return LunarPhase{
+        Angle:        angle,
+        Illumination: (1 + math.Cos(i)) / 2,
+        Name:         PhaseName(angle),
+    }
+}
@@ -0,0 +1,27 @@
+package ephem
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPhaseMeeusExample48a(t *testing.T) {
+    // Meeus: k = 0.6786 at 1992 April 12, 0h TD, elongation ~111° (first quarter sector)
+    p := PhaseAt(tt(1992, time.April, 12))
+    if math.Abs(p.Illumination-0.6786) > 0.005 { t.Fatalf("illumination: %.4f", p.Illumination) }
+    if p.Name != PhaseFirstQuarter || !p.Waxing() { t.Fatalf("phase: %+v", p) }
+}
+
+func TestPhaseNamesAroundSyzygy(t *testing.T) {
+    // New moon 2025-07-24 19:11 UTC, full moon 2025-08-09 07:55 UTC
+    newMoon := time.Date(2025, 7, 24, 19, 11, 0, 0, time.UTC)
+    fullMoon := time.Date(2025, 8, 9, 7, 55, 0, 0, time.UTC)
+    if p := PhaseAt(newMoon); p.Name != PhaseNew || math.Min(p.Angle, 360-p.Angle) > 0.5 || p.Illumination > 0.002 { t.Fatalf("new moon: %+v", p) }
+    if p := PhaseAt(fullMoon); p.Name != PhaseFull || math.Abs(p.Angle-180) > 0.5 || p.Illumination < 0.99 { t.Fatalf("full moon: %+v", p) }
+    if p := PhaseAt(newMoon.Add(4 * 24 * time.Hour)); p.Name != PhaseWaxingCrescent { t.Fatalf("4 days after new: %+v", p) }
+    if p := PhaseAt(fullMoon.Add(7 * 24 * time.Hour)); p.Name != PhaseLastQuarter || p.Waxing() { t.Fatalf("7 days after full: %+v", p) }
+    for angle, want := range map[float64]string{0: PhaseNew, 359: PhaseNew, 22.4: PhaseNew, 22.6: PhaseWaxingCrescent, 90: PhaseFirstQuarter, 200: PhaseFull, 225: PhaseWaningGibbous, 250: PhaseLastQuarter, 280: PhaseLastQuarter, 330: PhaseWaningCrescent} {
+        if got := PhaseName(angle); got != want { t.Fatalf("PhaseName(%v) = %s, want %s", angle, got, want) }
+    }
+}
@@ -0,0 +1,198 @@
+package ephem
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Body is one of the seven classical bodies.
+type Body uint8
+
+const (
+    BodySun Body = iota
+    BodyMoon
+    BodyMercury
+    BodyVenus
+    BodyMars
+    BodyJupiter
+    BodySaturn
+)
+
+// Bodies lists the classical bodies in the conventional order.
+var Bodies = [...]Body{BodySun, BodyMoon, BodyMercury, BodyVenus, BodyMars, BodyJupiter, BodySaturn}
+
+var bodyNames = [...]string{"sun", "moon", "mercury", "venus", "mars", "jupiter", "saturn"}
+
+func (b Body) String() string {
+    if int(b) < len(bodyNames) {
+        return bodyNames[b]
+    }
+    return fmt.Sprintf("body(%d)", uint8(b))
+}
+
+// ParseBody maps a lower-case body name ("sun", "moon", "mercury", ...) to a Body.
+func ParseBody(s string) (Body, error) {
+    for i, n := range bodyNames {
+        if n == s {
+            return Body(i), nil
+        }
+    }
+    return 0, fmt.Errorf("unknown body %q", s)
+}
+
+// kepler holds J2000 mean orbital elements and their rates per Julian century
+// (Standish, "Keplerian Elements for Approximate Positions of the Major
+// Planets", table 1, valid 1800–2050): semi-major axis (AU), eccentricity,
+// inclination, mean longitude, longitude of perihelion and of the ascending
+// node (degrees), referred to the J2000 ecliptic and equinox.
+type kepler struct {
+    a, e, i, l, peri, node       float64
+    da, de, di, dl, dperi, dnode float64
+}
+
+var (
+    keplerEMB     = kepler{1.00000261, 0.01671123, -0.00001531, 100.46457166, 102.93768193, 0, 0.00000562, -0.00004392, -0.01294668, 35999.37244981, 0.32327364, 0}
+    keplerPlanets = map[Body]kepler{
+        BodyMercury: {0.38709927, 0.20563593, 7.00497902, 252.25032350, 77.45779628, 48.33076593, 0.00000037, 0.00001906, -0.00594749, 149472.67411175, 0.16047689, -0.12534081},
+        BodyVenus:   {0.72333566, 0.00677672, 3.39467605, 181.97909950, 131.60246718, 76.67984255, 0.00000390, -0.00004107, -0.00078890, 58517.81538729, 0.00268329, -0.27769418},
+        BodyMars:    {1.52371034, 0.09339410, 1.84969142, -4.55343205, -23.94362959, 49.55953891, 0.00001847, 0.00007882, -0.00813131, 19140.30268499, 0.44441088, -0.29257343},
+        BodyJupiter: {5.20288700, 0.04838624, 1.30439695, 34.39644051, 14.72847983, 100.47390909, -0.00011607, -0.00013253, -0.00183714, 3034.74612775, 0.21252668, 0.20469106},
+        BodySaturn:  {9.53667594, 0.05386179, 2.48599187, 49.95424423, 92.59887831, 113.66242448, -0.00125060, -0.00050991, 0.00193609, 1222.49362201, -0.41897216, -0.28867794},
+    }
+)
+
+// helio returns the heliocentric J2000 ecliptic position (AU) at T Julian
+// centuries from J2000 TT.
+func (k kepler) helio(T float64) (x, y, z float64) {
+    a, e := k.a+k.da*T, k.e+k.de*T
+    inc := deg2rad(k.i + k.di*T)
+    l, peri, node := k.l+k.dl*T, k.peri+k.dperi*T, k.node+k.dnode*T
+    w, om := deg2rad(peri-node), deg2rad(node)
+    M := deg2rad(normDeg(l - peri))
+    E := M + e*math.Sin(M)
+    for n := 0; n < 10; n++ {
+        dE := (E - e*math.Sin(E) - M) / (1 - e*math.Cos(E))
+        E -= dE
+        if math.Abs(dE) < 1e-12 {
+            break
+        }
+    }
+    xp, yp := a*(math.Cos(E)-e), a*math.Sqrt(1-e*e)*math.Sin(E)
+    cw, sw, co, so, ci, si := math.Cos(w), math.Sin(w), math.Cos(om), math.Sin(om), math.Cos(inc), math.Sin(inc)
+    x = (cw*co-sw*so*ci)*xp + (-sw*co-cw*so*ci)*yp
+    y = (cw*so+sw*co*ci)*xp + (-sw*so+cw*co*ci)*yp
+    z = sw*si*xp + cw*si*yp
+    return x, y, z
+}
+
+// precessionLon returns the general precession in longitude from J2000 to
+// T centuries (degrees), which moves J2000 longitudes to the equinox of date.
+func precessionLon(T float64) float64 { return (5029.0966*T + 1.11113*T*T) / 3600 }
+
+// EclipticPosition returns the geocentric ecliptic longitude (degrees [0,
+// 360), mean equinox of date) and latitude (degrees) of b at t. The Sun and
+// Moon use the Meeus series behind SunPosition and MoonPosition; the planets
+// use Keplerian elements, good to about an arcminute (a few for Saturn) over
+// 1800–2050. Positions are geometric: light time and aberration are ignored.
+func EclipticPosition(b Body, t time.Time) (lonDeg, latDeg float64) {
+    switch b {
+    case BodySun:
+        lon, _ := SunPosition(t)
+        return lon, 0
+    case BodyMoon:
+        lon, _ := MoonPosition(t)
+        return lon, MoonLatitude(t)
+    }
+    k, ok := keplerPlanets[b]
+    if !ok {
+        return math.NaN(), math.NaN()
+    }
+    T := julianCenturiesTT(t)
+    px, py, pz := k.helio(T)
+    ex, ey, ez := keplerEMB.helio(T)
+    x, y, z := px-ex, py-ey, pz-ez
+    lon := rad2deg(math.Atan2(y, x)) + precessionLon(T)
+    return normDeg(lon), rad2deg(math.Atan2(z, math.Hypot(x, y)))
+}
+
+// LongitudeSpeed returns the rate of change of b's geocentric longitude at t
+// in degrees per day (negative while retrograde), from a ±6h central
+// difference.
+func LongitudeSpeed(b Body, t time.Time) float64 {
+    const h = 6 * time.Hour
+    l0, _ := EclipticPosition(b, t.Add(-h))
+    l1, _ := EclipticPosition(b, t.Add(h))
+    d := math.Mod(l1-l0+540, 360) - 180
+    return d / (2 * h.Hours() / 24)
+}
+
+// moonLatTerm is one row of Meeus table 47.B: multiples of D, M, M', F and the
+// latitude coefficient (1e-6 deg, sine).
+type moonLatTerm struct {
+    d, m, mp, f int8
+    b           int32
+}
+
+// moonLatTerms holds the terms of table 47.B above 0.0008°, which keeps the
+// latitude within ~0.005° of the full series.
+var moonLatTerms = [...]moonLatTerm{
+    {0, 0, 0, 1, 5128122},
+    {0, 0, 1, 1, 280602},
+    {0, 0, 1, -1, 277693},
+    {2, 0, 0, -1, 173237},
+    {2, 0, -1, 1, 55413},
+    {2, 0, -1, -1, 46271},
+    {2, 0, 0, 1, 32573},
+    {0, 0, 2, 1, 17198},
+    {2, 0, 1, -1, 9266},
+    {0, 0, 2, -1, 8822},
+    {2, -1, 0, -1, 8216},
+    {2, 0, -2, -1, 4324},
+    {2, 0, 1, 1, 4200},
+    {2, 1, 0, -1, -3359},
+    {2, -1, -1, 1, 2463},
+    {2, -1, 0, 1, 2211},
+    {2, -1, -1, -1, 2065},
+    {0, 1, -1, -1, -1870},
+    {4, 0, -1, -1, 1828},
+    {0, 1, 0, 1, -1794},
+    {0, 0, 0, 3, -1749},
+    {0, 1, -1, 1, -1565},
+    {1, 0, 0, 1, -1491},
+    {0, 1, 1, 1, -1475},
+    {0, 1, 1, -1, -1410},
+    {0, 1, 0, -1, -1344},
+    {1, 0, 0, -1, -1335},
+    {0, 0, 3, 1, 1107},
+    {4, 0, 0, -1, 1021},
+    {4, 0, -1, 1, 833},
+}
+
+// MoonLatitude returns the Moon's geocentric ecliptic latitude (degrees) at t.
+func MoonLatitude(t time.Time) float64 {
+    T := julianCenturiesTT(t)
+    T2, T3, T4 := T*T, T*T*T, T*T*T*T
+    Lp := deg2rad(218.3164477 + 481267.88123421*T - 0.0015786*T2 + T3/538841 - T4/65194000)
+    D := deg2rad(297.8501921 + 445267.1114034*T - 0.0018819*T2 + T3/545868 - T4/113065000)
+    M := deg2rad(357.5291092 + 35999.0502909*T - 0.0001536*T2 + T3/24490000)
+    Mp := deg2rad(134.9633964 + 477198.8675055*T + 0.0087414*T2 + T3/69699 - T4/14712000)
+    F := deg2rad(93.2720950 + 483202.0175233*T - 0.0036539*T2 - T3/3526000 + T4/863310000)
+    E := 1 - 0.002516*T - 0.0000074*T2
+    var sb float64
+    for _, k := range moonLatTerms {
+        arg := float64(k.d)*D + float64(k.m)*M + float64(k.mp)*Mp + float64(k.f)*F
+        ecc := 1.0
+        if k.m != 0 {
+            ecc = E
+        }
+        sb += ecc * float64(k.b) * math.Sin(arg)
+    }
+    A1 := deg2rad(119.75 + 131.849*T)
+    A3 := deg2rad(313.45 + 481266.484*T)
+    sb += -2235*math.Sin(Lp) + 382*math.Sin(A3) + 175*math.Sin(A1-F) + 175*math.Sin(A1+F) +
+        127*math.Sin(Lp-Mp) - 115*math.Sin(Lp+Mp)
+    return sb / 1e6
+}
+
+func rad2deg(r float64) float64 { return r * 180 / math.Pi }
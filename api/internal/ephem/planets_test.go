@@ -0,0 +1,36 @@
+package ephem
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMoonLatitudeMeeusExample47a(t *testing.T) {
+    if b := MoonLatitude(tt(1992, time.April, 12)); math.Abs(b-(-3.229126)) > 0.005 { t.Fatalf("latitude: %.6f", b) }
+}
+
+func TestPlanetPositions(t *testing.T) {
+    // Meeus example 33.a: Venus 1992 Dec 20 0h TD, apparent λ 313.08102°, β −2.08474°
+    // (geometric positions differ by aberration and nutation, well under 0.05°).
+    lon, lat := EclipticPosition(BodyVenus, tt(1992, time.December, 20))
+    if math.Abs(lon-313.08102) > 0.05 || math.Abs(lat-(-2.08474)) > 0.05 { t.Fatalf("venus: %.5f %.5f", lon, lat) }
+    // Great conjunction of 2020 Dec 21: Jupiter and Saturn 0.1° apart near 300.3°.
+    at := time.Date(2020,12,21,18,0,0,0,time.UTC)
+    jl, _ := EclipticPosition(BodyJupiter, at)
+    sl, _ := EclipticPosition(BodySaturn, at)
+    if math.Abs(jl-300.3) > 0.3 || math.Abs(jl-sl) > 0.2 { t.Fatalf("great conjunction: jupiter %.3f saturn %.3f", jl, sl) }
+    // Mercury was retrograde 2024 Apr 1–25; Mars moves ~0.5°/day prograde in mid 2025.
+    if v := LongitudeSpeed(BodyMercury, time.Date(2024,4,10,0,0,0,0,time.UTC)); v >= 0 { t.Fatalf("mercury speed %.3f, want retrograde", v) }
+    if v := LongitudeSpeed(BodyMars, time.Date(2025,8,1,0,0,0,0,time.UTC)); v < 0.4 || v > 0.8 { t.Fatalf("mars speed %.3f", v) }
+    if v := LongitudeSpeed(BodyMoon, time.Date(2025,8,1,0,0,0,0,time.UTC)); v < 11 || v > 16 { t.Fatalf("moon speed %.3f", v) }
+    // Sun and Moon match the tide model.
+    sunLon, _ := SunPosition(at)
+    if l, b := EclipticPosition(BodySun, at); l != sunLon || b != 0 { t.Fatalf("sun: %v %v", l, b) }
+    for _, b := range Bodies {
+        p, err := ParseBody(b.String())
+        if err != nil || p != b { t.Fatalf("parse %s: %v %v", b, p, err) }
+        if l, _ := EclipticPosition(b, at); l < 0 || l >= 360 { t.Fatalf("%s longitude %v", b, l) }
+    }
+    if _, err := ParseBody("pluto"); err == nil { t.Fatal("unknown body accepted") }
+}
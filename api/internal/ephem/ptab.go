@@ -0,0 +1,483 @@
+package ephem
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// PTAB is the planetary sibling of GTAB: a regular grid of geocentric
+// ecliptic positions for a set of bodies, served from a read-only mapping.
+// It uses the 64-byte v2 header shape, provenance block and chunk checksums:
+//
+//	0:5   magic "PTAB1"
+//	5:7   version u16 = 1
+//	7:15  epoch i64         — seconds on time_scale
+//	15:23 dt_ns i64
+//	23:27 n u32             — number of records
+//	27:31 bodies_mask u32   — bit b set when Body(b) is present
+//	31:35 chunk_records u32
+//	35:39 meta_len u32
+//	39:43 meta_crc u32
+//	43:51 reserved
+//	51    time_scale u8
+//	52:64 reserved
+//
+// Each record holds, for every present body in Body order, longitude
+// (degrees [0, 360), equinox of date), latitude (degrees) and longitude speed
+// (degrees/day) as f32. A NaN marks a missing position. The CRC trailer
+// follows the records as in GTAB v2.
+const ptabBodyBytes = 12
+
+// BodyState is a body's position and longitude speed.
+type BodyState struct {
+    Lon   float64 `json:"lon"`   // degrees [0, 360)
+    Lat   float64 `json:"lat"`   // degrees
+    Speed float64 `json:"speed"` // degrees/day, negative while retrograde
+}
+
+// Missing reports whether s is a gap (any NaN field).
+func (s BodyState) Missing() bool { return s.Lon != s.Lon || s.Lat != s.Lat || s.Speed != s.Speed }
+
+// ComputeBodyState evaluates b at t with the analytic ephemeris
+// (EclipticPosition and LongitudeSpeed).
+func ComputeBodyState(b Body, t time.Time) BodyState {
+    lon, lat := EclipticPosition(b, t)
+    return BodyState{Lon: lon, Lat: lat, Speed: LongitudeSpeed(b, t)}
+}
+
+// PTABHeader describes a planetary table's time axis and bodies.
+type PTABHeader struct {
+    Epoch  time.Time     // time of record 0 on Scale, whole seconds
+    Step   time.Duration // dt between records
+    Bodies []Body        // bodies per record; stored in Body order
+    Scale  TimeScale
+}
+
+func bodyMask(bs []Body) uint32 {
+    var m uint32
+    for _, b := range bs {
+        m |= 1 << b
+    }
+    return m
+}
+
+func maskBodies(m uint32) []Body {
+    var bs []Body
+    for b := Body(0); int(b) < len(bodyNames); b++ {
+        if m&(1<<b) != 0 {
+            bs = append(bs, b)
+        }
+    }
+    return bs
+}
+
+// PTABOptions tunes how a PTAB is opened. The zero value maps the file and
+// verifies chunks lazily.
+type PTABOptions struct {
+    DisableMmap bool
+    Verify      VerifyMode
+}
+
+// PTAB is an open planetary table. Safe for concurrent lookups.
+type PTAB struct {
+    store
+    epoch time.Time
+    dt    time.Duration
+    mask  uint32
+    scale TimeScale
+    slot  [len(bodyNames)]int64 // byte offset of each body within a record, -1 if absent
+    prov  Provenance
+}
+
+// OpenPTAB opens the planetary table at path, mapping it read-only when the
+// platform supports it.
+func OpenPTAB(path string, opts PTABOptions) (*PTAB, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    st, err := f.Stat()
+    if err != nil {
+        f.Close()
+        return nil, fmt.Errorf("stat file: %w", err)
+    }
+    p, err := openPTAB(f, st.Size(), path, opts)
+    if err != nil {
+        f.Close()
+        return nil, err
+    }
+    p.closer = f
+    if !opts.DisableMmap {
+        if data, err := mmapFile(f, st.Size()); err == nil {
+            p.data, p.unmap = data, true
+        }
+    }
+    return p.finishOpen(opts)
+}
+
+// OpenPTABBytes opens a planetary table held in memory and serves records
+// from b in place; b must not be modified while the table is open.
+func OpenPTABBytes(b []byte, name string, opts PTABOptions) (*PTAB, error) {
+    p, err := openPTAB(bytes.NewReader(b), int64(len(b)), name, opts)
+    if err != nil {
+        return nil, err
+    }
+    if !opts.DisableMmap {
+        p.data = b
+    }
+    return p.finishOpen(opts)
+}
+
+func openPTAB(r io.ReaderAt, size int64, path string, opts PTABOptions) (*PTAB, error) {
+    sr := io.NewSectionReader(r, 0, size)
+    hdr := make([]byte, 31)
+    if _, err := io.ReadFull(sr, hdr); err != nil {
+        return nil, fmt.Errorf("%s: read header: %w", path, err)
+    }
+    if string(hdr[:5]) != "PTAB1" {
+        return nil, fmt.Errorf("%s: invalid PTAB magic", path)
+    }
+    if ver := binary.LittleEndian.Uint16(hdr[5:7]); ver != 1 {
+        return nil, fmt.Errorf("%s: unsupported PTAB version: %d", path, ver)
+    }
+    dtNS := int64(binary.LittleEndian.Uint64(hdr[15:23]))
+    n := binary.LittleEndian.Uint32(hdr[23:27])
+    mask := binary.LittleEndian.Uint32(hdr[27:31])
+    if dtNS <= 0 {
+        return nil, fmt.Errorf("%s: invalid dt_ns: %d", path, dtNS)
+    }
+    if n == 0 {
+        return nil, fmt.Errorf("%s: empty table (n=0)", path)
+    }
+    if mask == 0 || mask>>len(bodyNames) != 0 {
+        return nil, fmt.Errorf("%s: unsupported bodies_mask: %#x", path, mask)
+    }
+    ext, err := readV2Ext(sr, hdr)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+    if !ext.scale.valid() {
+        return nil, fmt.Errorf("%s: unsupported time_scale: %d", path, ext.scale)
+    }
+    p := &PTAB{
+        store: store{r: r, path: path, n: n, headerSize: headerSizeV2 + ext.metaLen, chunkRecords: ext.chunkRecords,
+            verify: opts.Verify},
+        epoch: time.Unix(int64(binary.LittleEndian.Uint64(hdr[7:15])), 0).UTC(),
+        dt:    time.Duration(dtNS),
+        mask:  mask,
+        scale: ext.scale,
+        prov:  ext.prov,
+    }
+    for i := range p.slot {
+        p.slot[i] = -1
+    }
+    for _, b := range maskBodies(mask) {
+        p.slot[b] = p.recordSize
+        p.recordSize += ptabBodyBytes
+    }
+    want := p.headerSize + int64(n)*p.recordSize + 4*numChunks(int64(n), p.chunkRecords)
+    if size < want {
+        return nil, fmt.Errorf("%s: truncated PTAB file: have %d bytes, expected %d", path, size, want)
+    }
+    if size > want {
+        return nil, fmt.Errorf("%s: %d trailing bytes after PTAB checksums", path, size-want)
+    }
+    if err := p.loadChecksums(); err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+    return p, nil
+}
+
+func (p *PTAB) finishOpen(opts PTABOptions) (*PTAB, error) {
+    if opts.Verify == VerifyOnOpen {
+        if err := p.Verify(); err != nil {
+            p.Close()
+            return nil, err
+        }
+    }
+    return p, nil
+}
+
+// Header returns the table's epoch, step, bodies and time scale.
+func (p *PTAB) Header() PTABHeader {
+    return PTABHeader{Epoch: p.epoch, Step: p.dt, Bodies: maskBodies(p.mask), Scale: p.scale}
+}
+
+// Bodies returns the bodies the table carries, in Body order.
+func (p *PTAB) Bodies() []Body { return maskBodies(p.mask) }
+
+// Has reports whether the table carries b.
+func (p *PTAB) Has(b Body) bool { return int(b) < len(p.slot) && p.slot[b] >= 0 }
+
+// Len returns the number of records.
+func (p *PTAB) Len() int { return int(p.n) }
+
+// Scale returns the time scale of the record grid.
+func (p *PTAB) Scale() TimeScale { return p.scale }
+
+// Provenance returns the embedded provenance block.
+func (p *PTAB) Provenance() (Provenance, bool) { return p.prov, true }
+
+// Mapped reports whether records are served in place from memory.
+func (p *PTAB) Mapped() bool { return p.data != nil }
+
+// TimeAt returns the UTC instant of record i.
+func (p *PTAB) TimeAt(i int64) time.Time {
+    return FromScale(p.epoch.Add(time.Duration(i)*p.dt), p.scale)
+}
+
+// Coverage returns the inclusive UTC time window covered by the table.
+func (p *PTAB) Coverage() (start, end time.Time) {
+    return p.TimeAt(0), p.TimeAt(int64(p.n - 1))
+}
+
+// Close releases the mapping (if any) and closes the underlying file.
+// Lookups after Close report ok=false; Close must not race with lookups.
+func (p *PTAB) Close() error { return p.close() }
+
+// Verify checks every chunk checksum and returns the first mismatch.
+func (p *PTAB) Verify() error { return p.verifyAll() }
+
+// StateAt decodes body b of record i without interpolation. ok=false when b
+// is absent, i is out of range or the chunk fails its checksum; a gap is
+// returned as is (see BodyState.Missing).
+func (p *PTAB) StateAt(b Body, i int64) (BodyState, bool) {
+    if !p.Has(b) || i < 0 || i >= int64(p.n) || !p.chunkOK(i) {
+        return BodyState{}, false
+    }
+    rec, err := p.bytes(p.headerSize+i*p.recordSize+p.slot[b], ptabBodyBytes)
+    if err != nil {
+        return BodyState{}, false
+    }
+    f := func(k int) float64 { return float64(math.Float32frombits(binary.LittleEndian.Uint32(rec[4*k:]))) }
+    return BodyState{Lon: f(0), Lat: f(1), Speed: f(2)}, true
+}
+
+// Lookup returns b's state at UTC time t. Longitude is interpolated with a
+// cubic Hermite spline using the stored speeds as derivatives, unwrapped
+// across 0°; latitude and speed linearly. ok=false when b is absent, t is out
+// of range or either neighbour is a gap.
+func (p *PTAB) Lookup(b Body, t time.Time) (BodyState, bool) {
+    pos := float64(ToScale(t, p.scale).Sub(p.epoch)) / float64(p.dt)
+    if !p.Has(b) || pos < 0 || pos > float64(p.n-1) {
+        return BodyState{}, false
+    }
+    i := int64(pos)
+    frac := pos - float64(i)
+    s0, ok := p.StateAt(b, i)
+    if !ok || s0.Missing() {
+        return BodyState{}, false
+    }
+    if frac == 0 {
+        return s0, true
+    }
+    s1, ok := p.StateAt(b, i+1)
+    if !ok || s1.Missing() {
+        return BodyState{}, false
+    }
+    days := p.dt.Hours() / 24
+    d := math.Mod(s1.Lon-s0.Lon+540, 360) - 180
+    return BodyState{
+        Lon:   normDeg(s0.Lon + hermite(0, d, s0.Speed*days, s1.Speed*days, frac)),
+        Lat:   s0.Lat + (s1.Lat-s0.Lat)*frac,
+        Speed: s0.Speed + (s1.Speed-s0.Speed)*frac,
+    }, true
+}
+
+// PTABWriteOptions configures a new PTAB.
+type PTABWriteOptions struct {
+    // Provenance is embedded in the table; a zero CreatedAt is set to now.
+    Provenance Provenance
+    // ChunkRecords is the checksum chunk size; 0 selects DefaultChunkRecords.
+    ChunkRecords int
+}
+
+// PTABWriter streams records into a PTAB. Like Create, records are staged
+// beside the final path and renamed into place on Close.
+type PTABWriter struct {
+    f       *os.File
+    bw      *bufio.Writer
+    path    string
+    hdr     PTABHeader
+    bodies  []Body
+    meta    []byte
+    chunk   int64
+    n       uint32
+    inChunk int64
+    crc     uint32
+    crcs    []uint32
+    rec     []byte
+    err     error
+}
+
+// CreatePTAB starts a new planetary table at path.
+func CreatePTAB(path string, h PTABHeader, opts PTABWriteOptions) (*PTABWriter, error) {
+    if h.Step <= 0 {
+        return nil, fmt.Errorf("invalid step: %v", h.Step)
+    }
+    if h.Epoch.Nanosecond() != 0 {
+        return nil, fmt.Errorf("epoch must be whole seconds: %v", h.Epoch)
+    }
+    if !h.Scale.valid() {
+        return nil, fmt.Errorf("unknown time scale: %v", h.Scale)
+    }
+    for _, b := range h.Bodies {
+        if int(b) >= len(bodyNames) {
+            return nil, fmt.Errorf("unknown body: %v", b)
+        }
+    }
+    bodies := maskBodies(bodyMask(h.Bodies))
+    if len(bodies) == 0 {
+        return nil, errors.New("no bodies")
+    }
+    chunk := int64(opts.ChunkRecords)
+    if chunk <= 0 {
+        chunk = DefaultChunkRecords
+    }
+    if chunk > math.MaxUint32 {
+        return nil, fmt.Errorf("chunk_records too large: %d", chunk)
+    }
+    prov := opts.Provenance
+    if prov.CreatedAt.IsZero() {
+        prov.CreatedAt = time.Now().UTC().Truncate(time.Second)
+    }
+    meta, err := json.Marshal(prov)
+    if err != nil {
+        return nil, fmt.Errorf("encode provenance: %w", err)
+    }
+    f, err := os.Create(path + ".tmp")
+    if err != nil {
+        return nil, err
+    }
+    h.Bodies = bodies
+    w := &PTABWriter{f: f, bw: bufio.NewWriterSize(f, 64<<10), path: path, hdr: h, bodies: bodies, meta: meta, chunk: chunk,
+        rec: make([]byte, ptabBodyBytes*len(bodies))}
+    if _, err := w.bw.Write(append(w.encodeHeader(), meta...)); err != nil {
+        f.Close()
+        os.Remove(f.Name())
+        return nil, fmt.Errorf("write header: %w", err)
+    }
+    return w, nil
+}
+
+func (w *PTABWriter) encodeHeader() []byte {
+    hdr := make([]byte, headerSizeV2)
+    copy(hdr[:5], "PTAB1")
+    binary.LittleEndian.PutUint16(hdr[5:7], 1)
+    binary.LittleEndian.PutUint64(hdr[7:15], uint64(w.hdr.Epoch.Unix()))
+    binary.LittleEndian.PutUint64(hdr[15:23], uint64(w.hdr.Step))
+    binary.LittleEndian.PutUint32(hdr[23:27], w.n)
+    binary.LittleEndian.PutUint32(hdr[27:31], bodyMask(w.bodies))
+    binary.LittleEndian.PutUint32(hdr[31:35], uint32(w.chunk))
+    binary.LittleEndian.PutUint32(hdr[35:39], uint32(len(w.meta)))
+    binary.LittleEndian.PutUint32(hdr[39:43], crc32.ChecksumIEEE(w.meta))
+    hdr[51] = byte(w.hdr.Scale)
+    return hdr
+}
+
+// Bodies returns the order Write expects states in.
+func (w *PTABWriter) Bodies() []Body { return w.bodies }
+
+// Write appends one record; states holds one entry per body of Bodies().
+// A state with any NaN field is stored as a gap for that body.
+func (w *PTABWriter) Write(states []BodyState) error {
+    if w.err != nil {
+        return w.err
+    }
+    if len(states) != len(w.bodies) {
+        return fmt.Errorf("record has %d bodies, table has %d", len(states), len(w.bodies))
+    }
+    if w.n == math.MaxUint32 {
+        w.err = errors.New("too many records for PTAB")
+        return w.err
+    }
+    nan := math.Float32frombits(0x7fc00000)
+    for k, s := range states {
+        v := [3]float32{float32(s.Lon), float32(s.Lat), float32(s.Speed)}
+        if s.Missing() {
+            v = [3]float32{nan, nan, nan}
+        }
+        for j, x := range v {
+            binary.LittleEndian.PutUint32(w.rec[ptabBodyBytes*k+4*j:], math.Float32bits(x))
+        }
+    }
+    if _, err := w.bw.Write(w.rec); err != nil {
+        w.err = err
+        return err
+    }
+    w.n++
+    w.crc = crc32.Update(w.crc, crc32.IEEETable, w.rec)
+    if w.inChunk++; w.inChunk == w.chunk {
+        w.crcs = append(w.crcs, w.crc)
+        w.crc, w.inChunk = 0, 0
+    }
+    return nil
+}
+
+// WriteAt appends the analytic-ephemeris record for the next grid time.
+func (w *PTABWriter) WriteAt() error {
+    t := FromScale(w.hdr.Epoch.Add(time.Duration(w.n)*w.hdr.Step), w.hdr.Scale)
+    states := make([]BodyState, len(w.bodies))
+    for k, b := range w.bodies {
+        states[k] = ComputeBodyState(b, t)
+    }
+    return w.Write(states)
+}
+
+// Len returns the number of records written so far.
+func (w *PTABWriter) Len() int { return int(w.n) }
+
+// Close writes the checksum trailer, patches the record count and renames
+// the table into place. An empty or failed table is discarded.
+func (w *PTABWriter) Close() error {
+    if w.f == nil {
+        return errWriterClosed
+    }
+    err := w.finish()
+    if cerr := w.f.Close(); err == nil {
+        err = cerr
+    }
+    if err == nil {
+        err = os.Rename(w.f.Name(), w.path)
+    }
+    if err != nil {
+        os.Remove(w.f.Name())
+    }
+    w.f, w.err = nil, errWriterClosed
+    return err
+}
+
+func (w *PTABWriter) finish() error {
+    if w.err != nil {
+        return w.err
+    }
+    if w.n == 0 {
+        return errors.New("empty table (n=0)")
+    }
+    if w.inChunk > 0 {
+        w.crcs = append(w.crcs, w.crc)
+    }
+    var b [4]byte
+    for _, c := range w.crcs {
+        binary.LittleEndian.PutUint32(b[:], c)
+        if _, err := w.bw.Write(b[:]); err != nil {
+            return err
+        }
+    }
+    if err := w.bw.Flush(); err != nil {
+        return err
+    }
+    if _, err := w.f.WriteAt(w.encodeHeader(), 0); err != nil {
+        return fmt.Errorf("patch header: %w", err)
+    }
+    return nil
+}
@@ -0,0 +1,122 @@
+package ephem
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writePTAB writes n analytic records from epoch at step for bodies.
+func writePTAB(t *testing.T, path string, h PTABHeader, n int, opts PTABWriteOptions) {
+    t.Helper()
+    w, err := CreatePTAB(path, h, opts)
+    if err != nil { t.Fatalf("create: %v", err) }
+    for i := 0; i < n; i++ {
+        if err := w.WriteAt(); err != nil { t.Fatalf("write %d: %v", i, err) }
+    }
+    if err := w.Close(); err != nil { t.Fatalf("close: %v", err) }
+}
+
+func angleDiff(a, b float64) float64 { return math.Abs(math.Mod(a-b+540, 360) - 180) }
+
+func TestPTABLookupMatchesAlgo(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2024,3,20,0,0,0,0,time.UTC)
+    p := filepath.Join(dir, "planets_1h.ptab")
+    // bodies out of order on purpose: records are stored in Body order
+    h := PTABHeader{Epoch: epoch, Step: time.Hour, Bodies: []Body{BodySaturn, BodyMoon, BodyMercury, BodySun}}
+    writePTAB(t, p, h, 24*40, PTABWriteOptions{Provenance: Provenance{DatasetID: "planets_2024"}, ChunkRecords: 100})
+    for _, opts := range []PTABOptions{{}, {DisableMmap: true, Verify: VerifyOnOpen}} {
+        tab, err := OpenPTAB(p, opts)
+        if err != nil { t.Fatalf("open: %v", err) }
+        if tab.Len() != 960 || tab.Has(BodyVenus) || !tab.Has(BodyMoon) || len(tab.Bodies()) != 4 || tab.Bodies()[0] != BodySun { t.Fatalf("header: %+v", tab.Header()) }
+        if prov, _ := tab.Provenance(); prov.DatasetID != "planets_2024" { t.Fatalf("provenance: %+v", prov) }
+        if start, end := tab.Coverage(); !start.Equal(epoch) || !end.Equal(epoch.Add(959*time.Hour)) { t.Fatalf("coverage %s..%s", start, end) }
+        for m := 0; m < 959*60; m += 617 {
+            at := epoch.Add(time.Duration(m) * time.Minute)
+            for _, b := range tab.Bodies() {
+                got, ok := tab.Lookup(b, at)
+                want := ComputeBodyState(b, at)
+                // the Moon moves ~0.5°/h; Hermite with stored speeds keeps it to arcseconds
+                if !ok || angleDiff(got.Lon, want.Lon) > 2e-4 || math.Abs(got.Lat-want.Lat) > 1e-3 || math.Abs(got.Speed-want.Speed) > 0.02 { t.Fatalf("%s at %s: %+v %v, algo %+v", b, at, got, ok, want) }
+            }
+        }
+        // Mercury stations retrograde on 2024-04-01 and direct on 2024-04-25
+        if s, _ := tab.Lookup(BodyMercury, time.Date(2024,4,10,0,0,0,0,time.UTC)); s.Speed >= 0 { t.Fatalf("mercury not retrograde: %+v", s) }
+        if _, ok := tab.Lookup(BodyVenus, epoch); ok { t.Fatal("absent body answered") }
+        if _, ok := tab.Lookup(BodySun, epoch.Add(-time.Second)); ok { t.Fatal("out of range answered") }
+        _ = tab.Close()
+        if _, ok := tab.Lookup(BodySun, epoch); ok { t.Fatal("lookup after close") }
+    }
+}
+
+func TestPTABWrapsLongitude(t *testing.T) {
+    // the Sun crosses 0° at the March equinox, 2024-03-20 03:06 UTC
+    p := filepath.Join(t.TempDir(), "sun.ptab")
+    epoch := time.Date(2024,3,19,0,0,0,0,time.UTC)
+    writePTAB(t, p, PTABHeader{Epoch: epoch, Step: 24 * time.Hour, Bodies: []Body{BodySun}}, 3, PTABWriteOptions{})
+    tab, err := OpenPTAB(p, PTABOptions{})
+    if err != nil { t.Fatalf("open: %v", err) }
+    defer tab.Close()
+    for h := 0; h <= 48; h += 3 {
+        at := epoch.Add(time.Duration(h) * time.Hour)
+        got, _ := tab.Lookup(BodySun, at)
+        if d := angleDiff(got.Lon, ComputeBodyState(BodySun, at).Lon); d > 1e-3 || got.Lon < 0 || got.Lon >= 360 { t.Fatalf("at %s: %g (off %g)", at, got.Lon, d) }
+    }
+}
+
+func TestPTABGapsAndValidation(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,1,1,0,0,0,0,time.UTC)
+    p := filepath.Join(dir, "gap.ptab")
+    w, err := CreatePTAB(p, PTABHeader{Epoch: ToScale(epoch, ScaleTT).Truncate(time.Second), Step: time.Hour, Bodies: []Body{BodyMars, BodyVenus}, Scale: ScaleTT}, PTABWriteOptions{ChunkRecords: 2})
+    if err != nil { t.Fatal(err) }
+    if bs := w.Bodies(); len(bs) != 2 || bs[0] != BodyVenus { t.Fatalf("write order: %v", bs) }
+    nan := math.NaN()
+    for i := 0; i < 4; i++ {
+        // Write takes Body order: Venus before Mars
+        st := []BodyState{{Lon: 100, Lat: 1, Speed: 0}, {Lon: 10 + float64(i), Speed: 24}}
+        if i == 2 { st[0].Lon = nan }
+        if err := w.Write(st); err != nil { t.Fatal(err) }
+    }
+    if err := w.Write([]BodyState{{}}); err == nil { t.Fatal("short record accepted") }
+    if err := w.Close(); err != nil { t.Fatal(err) }
+    tab, err := OpenPTAB(p, PTABOptions{})
+    if err != nil { t.Fatal(err) }
+    if tab.Scale() != ScaleTT || !tab.TimeAt(0).Before(epoch) { t.Fatalf("scale %s, record 0 at %s", tab.Scale(), tab.TimeAt(0)) }
+    mid := tab.TimeAt(1).Add(30 * time.Minute)
+    if s, ok := tab.Lookup(BodyVenus, mid); ok { t.Fatalf("interpolated into a gap: %+v", s) }
+    if s, ok := tab.Lookup(BodyMars, mid); !ok || math.Abs(s.Lon-11.5) > 1e-4 { t.Fatalf("mars next to venus gap: %+v %v", s, ok) }
+    if s, ok := tab.StateAt(BodyVenus, 2); !ok || !s.Missing() { t.Fatalf("gap record: %+v %v", s, ok) }
+    _ = tab.Close()
+
+    b, err := os.ReadFile(p)
+    if err != nil { t.Fatal(err) }
+    b[len(b)-4*2-3] ^= 0xff // inside the last record, chunk 1
+    tab, err = OpenPTABBytes(b, "corrupt", PTABOptions{})
+    if err != nil { t.Fatalf("lazy open: %v", err) }
+    if _, ok := tab.Lookup(BodyMars, tab.TimeAt(3)); ok { t.Fatal("corrupt chunk answered") }
+    if _, ok := tab.Lookup(BodyMars, tab.TimeAt(0)); !ok { t.Fatal("intact chunk refused") }
+    if err := tab.Verify(); !errors.Is(err, ErrChecksum) { t.Fatalf("verify: %v", err) }
+    if _, err := OpenPTABBytes(b, "corrupt", PTABOptions{Verify: VerifyOnOpen}); !errors.Is(err, ErrChecksum) { t.Fatalf("eager verify: %v", err) }
+    if _, err := OpenPTABBytes(b[:100], "short", PTABOptions{}); err == nil { t.Fatal("truncated table accepted") }
+    if _, err := OpenPTABBytes(append(b, 0), "padded", PTABOptions{}); err == nil { t.Fatal("trailing bytes accepted") }
+    if _, err := OpenPTAB(writeGTABValues(t, dir, "g.bin", epoch.Unix(), 1e9, []uint16{1}), PTABOptions{}); err == nil { t.Fatal("GTAB accepted as PTAB") }
+
+    for _, h := range []PTABHeader{
+        {Epoch: epoch, Step: time.Hour},
+        {Epoch: epoch, Step: 0, Bodies: []Body{BodySun}},
+        {Epoch: epoch.Add(time.Millisecond), Step: time.Hour, Bodies: []Body{BodySun}},
+        {Epoch: epoch, Step: time.Hour, Bodies: []Body{Body(9)}},
+        {Epoch: epoch, Step: time.Hour, Bodies: []Body{BodySun}, Scale: 5},
+    } {
+        if _, err := CreatePTAB(filepath.Join(dir, "bad.ptab"), h, PTABWriteOptions{}); err == nil { t.Fatalf("header accepted: %+v", h) }
+    }
+    empty, err := CreatePTAB(filepath.Join(dir, "empty.ptab"), PTABHeader{Epoch: epoch, Step: time.Hour, Bodies: []Body{BodySun}}, PTABWriteOptions{})
+    if err != nil { t.Fatal(err) }
+    if err := empty.Close(); err == nil { t.Fatal("empty table accepted") }
+    if _, err := os.Stat(filepath.Join(dir, "empty.ptab")); !os.IsNotExist(err) { t.Fatal("empty table written") }
+}
@@ -0,0 +1,133 @@
+package ephem
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// Pyramid groups tables of different cadences (e.g. gtab_1s.bin + gtab_60s.bin)
+// and routes each lookup to the finest table whose coverage includes t, so a
+// short 1s table answers intraday queries while a long 60s table keeps the
+// service fresh beyond it.
+type Pyramid struct {
+    levels []*GTAB // ascending by step; ties keep caller order
+}
+
+// NewPyramid builds a pyramid over already-open tables. It takes ownership:
+// Close closes every level.
+func NewPyramid(tables ...*GTAB) (*Pyramid, error) {
+    if len(tables) == 0 {
+        return nil, errors.New("pyramid: no tables")
+    }
+    levels := append([]*GTAB(nil), tables...)
+    sort.SliceStable(levels, func(i, j int) bool { return levels[i].dt < levels[j].dt })
+    return &Pyramid{levels: levels}, nil
+}
+
+// OpenPyramid opens every path with opts and builds a pyramid. Already-opened
+// tables are closed if any path fails.
+func OpenPyramid(paths []string, opts Options) (*Pyramid, error) {
+    var tables []*GTAB
+    for _, p := range paths {
+        g, err := OpenWithOptions(p, opts)
+        if err != nil {
+            for _, t := range tables {
+                t.Close()
+            }
+            return nil, err
+        }
+        tables = append(tables, g)
+    }
+    return NewPyramid(tables...)
+}
+
+// Levels returns the tables from finest to coarsest.
+func (p *Pyramid) Levels() []*GTAB { return p.levels }
+
+// Select returns the finest table covering t. ok=false if no level covers it.
+func (p *Pyramid) Select(t time.Time) (*GTAB, bool) {
+    for _, g := range p.levels {
+        if _, _, ok := g.IndexFor(t); ok {
+            return g, true
+        }
+    }
+    return nil, false
+}
+
+// Coverage returns the earliest start and latest end across all levels. Gaps
+// between levels are not reported here; Select returns ok=false inside them.
+func (p *Pyramid) Coverage() (start, end time.Time) {
+    for i, g := range p.levels {
+        s, e := g.Coverage()
+        if i == 0 || s.Before(start) {
+            start = s
+        }
+        if i == 0 || e.After(end) {
+            end = e
+        }
+    }
+    return start, end
+}
+
+// LookupTideBPS returns tide_bps at t from the finest covering table together
+// with that table's step. ok=false when no level covers t.
+func (p *Pyramid) LookupTideBPS(t time.Time) (uint16, time.Duration, bool) {
+    g, ok := p.Select(t)
+    if !ok {
+        return 0, 0, false
+    }
+    v, ok := g.LookupTideBPS(t)
+    return v, g.dt, ok
+}
+
+// LookupSample is LookupTideBPS for every field present in the answering table.
+func (p *Pyramid) LookupSample(t time.Time) (Sample, time.Duration, bool) {
+    g, ok := p.Select(t)
+    if !ok {
+        return Sample{}, 0, false
+    }
+    s, ok := g.LookupSample(t)
+    return s, g.dt, ok
+}
+
+// LookupTideBPSMany routes each timestamp to the finest covering level, like
+// LookupTideBPS, and looks up the points of each level as one batch (see
+// GTAB.LookupTideBPSMany). It returns the number answered.
+func (p *Pyramid) LookupTideBPSMany(ts []time.Time, out []uint16) int {
+    out = out[:len(ts)]
+    if len(p.levels) == 1 {
+        return p.levels[0].LookupTideBPSMany(ts, out)
+    }
+    groups := make([][]int, len(p.levels))
+    for k, t := range ts {
+        out[k] = MissingBPS
+        for l, g := range p.levels {
+            if _, _, ok := g.IndexFor(t); ok {
+                groups[l] = append(groups[l], k)
+                break
+            }
+        }
+    }
+    return lookupGroups(p.levels, groups, ts, out)
+}
+
+// TideAt is GTAB.TideAt on the finest covering table; it satisfies TideFunc.
+func (p *Pyramid) TideAt(t time.Time) (float64, bool) {
+    g, ok := p.Select(t)
+    if !ok {
+        return 0, false
+    }
+    return g.TideAt(t)
+}
+
+// Close closes every level and returns the first error.
+func (p *Pyramid) Close() error {
+    var err error
+    for _, g := range p.levels {
+        if cerr := g.Close(); err == nil {
+            err = cerr
+        }
+    }
+    return err
+}
@@ -0,0 +1,42 @@
+package ephem
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeConst writes n samples of a constant tide_bps at the given step.
+func writeConst(t *testing.T, path string, epoch time.Time, step time.Duration, n int, bps uint16) string {
+    t.Helper()
+    w, err := Create(path, Header{Epoch: epoch, Step: step, Fields: FieldTideBPS})
+    if err != nil { t.Fatalf("create: %v", err) }
+    for i := 0; i < n; i++ {
+        if err := w.Write(Sample{TideBPS: bps}); err != nil { t.Fatalf("write: %v", err) }
+    }
+    if err := w.Close(); err != nil { t.Fatalf("close: %v", err) }
+    return path
+}
+
+func TestPyramidRoutesFinestCovering(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    fine := writeConst(t, filepath.Join(dir, "gtab_1s.bin"), epoch, time.Second, 600, 1111)       // 10 min
+    coarse := writeConst(t, filepath.Join(dir, "gtab_60s.bin"), epoch, time.Minute, 24*60, 6000) // 1 day
+    // Pass coarse first to check ordering is by step, not argument order.
+    p, err := OpenPyramid([]string{coarse, fine}, Options{})
+    if err != nil { t.Fatalf("open pyramid: %v", err) }
+    t.Cleanup(func(){ _ = p.Close() })
+    if lv := p.Levels(); len(lv) != 2 || lv[0].Header().Step != time.Second { t.Fatalf("levels not sorted finest first") }
+    if v, res, ok := p.LookupTideBPS(epoch.Add(5 * time.Minute)); !ok || v != 1111 || res != time.Second { t.Fatalf("inside 1s window: %d %v %v", v, res, ok) }
+    if v, res, ok := p.LookupTideBPS(epoch.Add(3 * time.Hour)); !ok || v != 6000 || res != time.Minute { t.Fatalf("beyond 1s window: %d %v %v", v, res, ok) }
+    if _, _, ok := p.LookupTideBPS(epoch.Add(48 * time.Hour)); ok { t.Fatal("expected miss beyond all levels") }
+    start, end := p.Coverage()
+    if !start.Equal(epoch) || !end.Equal(epoch.Add(time.Duration(24*60-1) * time.Minute)) { t.Fatalf("coverage: %v..%v", start, end) }
+    if s, res, ok := p.LookupSample(epoch.Add(2 * time.Hour)); !ok || s.TideBPS != 6000 || res != time.Minute { t.Fatalf("sample: %+v %v %v", s, res, ok) }
+}
+
+func TestPyramidRequiresTables(t *testing.T) {
+    if _, err := NewPyramid(); err == nil { t.Fatal("expected error for empty pyramid") }
+    if _, err := OpenPyramid([]string{filepath.Join(t.TempDir(), "missing.bin")}, Options{}); err == nil { t.Fatal("expected open error") }
+}
@@ -0,0 +1,49 @@
+package ephem
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOpenReaderAndBytesMatchFile(t *testing.T) {
+    dir := t.TempDir()
+    v1, v3 := writeSmooth(t, dir, 3000, 256)
+    v2 := writeV2(t, filepath.Join(dir, "v2.bin"), 3000, 256, Provenance{DatasetID: "ds_v2"})
+    for _, p := range []string{v1, v2, v3} {
+        want, err := Open(p)
+        if err != nil { t.Fatalf("open %s: %v", p, err) }
+        b, err := os.ReadFile(p)
+        if err != nil { t.Fatal(err) }
+        fromReader, err := OpenReader(bytes.NewReader(b), int64(len(b)), "mem", Options{Verify: VerifyOnOpen})
+        if err != nil { t.Fatalf("OpenReader %s: %v", p, err) }
+        fromBytes, err := OpenBytes(b, "mem", Options{})
+        if err != nil { t.Fatalf("OpenBytes %s: %v", p, err) }
+        if fromReader.Mapped() || !fromBytes.Mapped() { t.Fatalf("%s: mapped reader=%v bytes=%v", p, fromReader.Mapped(), fromBytes.Mapped()) }
+        start, _ := want.Coverage()
+        for i := 0; i < 3000; i += 7 {
+            at := start.Add(time.Duration(i)*time.Second + 500*time.Millisecond)
+            w, _ := want.LookupSample(at)
+            r, _ := fromReader.LookupSample(at)
+            m, _ := fromBytes.LookupSample(at)
+            if r != w || m != w { t.Fatalf("%s at %s: file %+v reader %+v bytes %+v", p, at, w, r, m) }
+        }
+        if err := fromBytes.Verify(); err != nil && !errors.Is(err, ErrNoChecksums) { t.Fatalf("%s: verify: %v", p, err) }
+        want.Close()
+        fromReader.Close()
+        fromBytes.Close()
+        if _, ok := fromReader.LookupTideBPS(start); ok { t.Fatalf("%s: reader lookup after close", p) }
+        if _, ok := fromBytes.LookupTideBPS(start); ok { t.Fatalf("%s: bytes lookup after close", p) }
+    }
+}
+
+func TestOpenBytesRejectsTruncated(t *testing.T) {
+    v1, _ := writeSmooth(t, t.TempDir(), 100, 64)
+    b, err := os.ReadFile(v1)
+    if err != nil { t.Fatal(err) }
+    if _, err := OpenBytes(b[:len(b)-1], "short", Options{}); err == nil { t.Fatal("expected truncation error") }
+    if _, err := OpenBytes(b[:10], "short", Options{}); err == nil { t.Fatal("expected header error") }
+}
@@ -0,0 +1,167 @@
+package ephem
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// Sample is one decoded GTAB record. Only the fields present in the table's
+// fields_mask are populated; Fields reports which ones, the rest stay zero.
+type Sample struct {
+    Fields    uint32  `json:"fields_mask"`
+    TideBPS   uint16  `json:"tide_bps"`
+    TideRaw   float32 `json:"tide_raw"`
+    MoonRkm   float32 `json:"moon_r_km"`
+    SunRkm    float32 `json:"sun_r_km"`
+    MoonRinv3 float32 `json:"moon_rinv3"`
+    SunRinv3  float32 `json:"sun_rinv3"`
+    TideRawDt float32 `json:"tide_raw_dt"`
+}
+
+// Has reports whether the given field bit was present in the source table.
+func (s Sample) Has(field uint32) bool { return s.Fields&field != 0 }
+
+// MissingBPS is the tide_bps of a missing record (tide_bps never exceeds
+// 10000). Batch lookups also use it for slots they could not answer.
+const MissingBPS uint16 = 0xFFFF
+
+// Missing reports whether s is a gap record: tide_bps holds MissingBPS or a
+// float field is NaN. Writer.Write stores such a sample with every field set
+// to its sentinel, and lookups never interpolate across one.
+func (s Sample) Missing() bool {
+    if s.Has(FieldTideBPS) && s.TideBPS == MissingBPS {
+        return true
+    }
+    nan := func(bit uint32, v float32) bool { return s.Has(bit) && v != v }
+    return nan(FieldTideRawF32, s.TideRaw) || nan(FieldMoonRkmF32, s.MoonRkm) || nan(FieldSunRkmF32, s.SunRkm) ||
+        nan(FieldMoonRinv3F32, s.MoonRinv3) || nan(FieldSunRinv3F32, s.SunRinv3) || nan(FieldTideRawDtF32, s.TideRawDt)
+}
+
+// missingSample returns the gap record for a table with the given fields:
+// tide_bps MissingBPS and every float field a quiet NaN.
+func missingSample(fields uint32) Sample {
+    nan := math.Float32frombits(0x7fc00000)
+    return Sample{Fields: fields, TideBPS: MissingBPS, TideRaw: nan, MoonRkm: nan, SunRkm: nan,
+        MoonRinv3: nan, SunRinv3: nan, TideRawDt: nan}
+}
+
+// record returns the raw bytes of record i. Mapped tables return a zero-copy
+// slice; otherwise the record is read into buf, which must hold recordSize bytes.
+func (g *GTAB) record(i int64, buf []byte) ([]byte, bool) {
+    if i < 0 || i >= int64(g.n) || !g.chunkOK(i) {
+        return nil, false
+    }
+    if g.index != nil {
+        return g.compressedRecord(i)
+    }
+    off := g.headerSize + i*g.recordSize
+    if g.data != nil {
+        return g.data[off : off+g.recordSize], true
+    }
+    if g.r == nil {
+        return nil, false
+    }
+    buf = buf[:g.recordSize]
+    if _, err := g.r.ReadAt(buf, off); err != nil {
+        return nil, false
+    }
+    return buf, true
+}
+
+// decode unpacks a raw record according to the table layout.
+func (g *GTAB) decode(rec []byte) Sample {
+    l := &g.lay
+    s := Sample{Fields: g.fieldsMask}
+    f32 := func(off int64) float32 {
+        if off < 0 {
+            return 0
+        }
+        return math.Float32frombits(binary.LittleEndian.Uint32(rec[off : off+4]))
+    }
+    if l.tideBPS >= 0 {
+        s.TideBPS = binary.LittleEndian.Uint16(rec[l.tideBPS : l.tideBPS+2])
+    }
+    s.TideRaw = f32(l.tideRaw)
+    s.MoonRkm = f32(l.moonRkm)
+    s.SunRkm = f32(l.sunRkm)
+    s.MoonRinv3 = f32(l.moonRinv3)
+    s.SunRinv3 = f32(l.sunRinv3)
+    s.TideRawDt = f32(l.tideRawDt)
+    return s
+}
+
+// SampleAt decodes record i without interpolation. ok=false if i is out of
+// range or the read fails; a gap record is returned as is (see Sample.Missing).
+func (g *GTAB) SampleAt(i int64) (Sample, bool) {
+    var buf []byte
+    if g.data == nil {
+        buf = make([]byte, g.recordSize)
+    }
+    rec, ok := g.record(i, buf)
+    if !ok {
+        return Sample{}, false
+    }
+    return g.decode(rec), true
+}
+
+// LookupSample returns every present field at time t, interpolated between
+// neighbouring records according to Interp. ok=false when t is out of range
+// or either neighbour is a gap.
+func (g *GTAB) LookupSample(t time.Time) (Sample, bool) {
+    i, frac, ok := g.IndexFor(t)
+    if !ok {
+        return Sample{}, false
+    }
+    if g.interp == InterpNearest {
+        if frac >= 0.5 {
+            i++
+        }
+        frac = 0
+    }
+    if frac == 0 {
+        s, ok := g.SampleAt(i)
+        return s, ok && !s.Missing()
+    }
+    var b0, b1 []byte
+    if g.data == nil {
+        buf := make([]byte, 2*g.recordSize)
+        b0, b1 = buf[:g.recordSize], buf[g.recordSize:]
+    }
+    r0, ok0 := g.record(i, b0)
+    r1, ok1 := g.record(i+1, b1)
+    if !ok0 || !ok1 {
+        return Sample{}, false
+    }
+    a, b := g.decode(r0), g.decode(r1)
+    if a.Missing() || b.Missing() {
+        return Sample{}, false
+    }
+    return g.blend(a, b, frac), true
+}
+
+// blend interpolates between neighbouring records a and b with the table's
+// Interp (nearest is resolved by the caller before reading records).
+func (g *GTAB) blend(a, b Sample, frac float64) Sample {
+    if g.interp == InterpHermite {
+        return g.hermiteSample(a, b, frac)
+    }
+    return lerpSample(a, b, frac)
+}
+
+// lerpSample interpolates every field of two samples sharing the same fields mask.
+func lerpSample(a, b Sample, frac float64) Sample {
+    lerp := func(x, y float32) float32 {
+        return float32(float64(x) + (float64(y)-float64(x))*frac)
+    }
+    return Sample{
+        Fields:    a.Fields,
+        TideBPS:   lerpBPS(a.TideBPS, b.TideBPS, frac),
+        TideRaw:   lerp(a.TideRaw, b.TideRaw),
+        MoonRkm:   lerp(a.MoonRkm, b.MoonRkm),
+        SunRkm:    lerp(a.SunRkm, b.SunRkm),
+        MoonRinv3: lerp(a.MoonRinv3, b.MoonRinv3),
+        SunRinv3:  lerp(a.SunRinv3, b.SunRinv3),
+        TideRawDt: lerp(a.TideRawDt, b.TideRawDt),
+    }
+}
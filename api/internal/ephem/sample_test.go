@@ -0,0 +1,63 @@
+package ephem
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeFullGTAB writes records carrying every v1 field; field k of record i is base[k]+i*step[k].
+func writeFullGTAB(t *testing.T, dir string, epoch int64, n int, base, step [6]float64) string {
+    t.Helper()
+    fields := FieldTideBPS | FieldTideRawF32 | FieldMoonRkmF32 | FieldSunRkmF32 | FieldMoonRinv3F32 | FieldSunRinv3F32
+    p := filepath.Join(dir, "full.bin")
+    w, err := Create(p, Header{Epoch: time.Unix(epoch, 0), Step: time.Second, Fields: fields})
+    if err != nil { t.Fatalf("create: %v", err) }
+    for i := 0; i < n; i++ {
+        v := func(k int) float32 { return float32(base[k] + float64(i)*step[k]) }
+        s := Sample{TideBPS: uint16(base[0] + float64(i)*step[0]), TideRaw: v(1), MoonRkm: v(2), SunRkm: v(3), MoonRinv3: v(4), SunRinv3: v(5)}
+        if err := w.Write(s); err != nil { t.Fatalf("write: %v", err) }
+    }
+    if err := w.Close(); err != nil { t.Fatalf("close: %v", err) }
+    return p
+}
+
+func TestLookupSampleAllFields(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC).Unix()
+    base := [6]float64{1000, 1e-13, 384400, 1.496e8, 8.6e-14, 3.9e-14}
+    step := [6]float64{100, 1e-15, -10, 1000, 1e-16, -1e-16}
+    p := writeFullGTAB(t, dir, epoch, 3, base, step)
+    for _, opts := range []Options{{}, {DisableMmap: true}} {
+        g, err := OpenWithOptions(p, opts)
+        if err != nil { t.Fatalf("open: %v", err) }
+        if g.Fields() != 0x3f { t.Fatalf("fields mask: %#x", g.Fields()) }
+        s, ok := g.LookupSample(time.Unix(epoch+1,0).UTC())
+        if !ok { t.Fatal("exact lookup failed") }
+        if s.TideBPS != 1100 || s.MoonRkm != 384390 || s.SunRkm != float32(1.496e8+1000) { t.Fatalf("exact sample: %+v", s) }
+        for _, f := range []uint32{FieldTideBPS, FieldTideRawF32, FieldMoonRkmF32, FieldSunRkmF32, FieldMoonRinv3F32, FieldSunRinv3F32} {
+            if !s.Has(f) { t.Fatalf("expected field %#x present", f) }
+        }
+        mid, ok := g.LookupSample(time.Unix(epoch+1, 500_000_000).UTC())
+        if !ok { t.Fatal("interp lookup failed") }
+        if mid.TideBPS != 1150 { t.Fatalf("tide_bps interp: %d", mid.TideBPS) }
+        if math.Abs(float64(mid.MoonRkm)-384385) > 0.01 { t.Fatalf("moon_r_km interp: %v", mid.MoonRkm) }
+        if math.Abs(float64(mid.TideRaw)-(1e-13+1.5e-15))/1e-13 > 1e-6 { t.Fatalf("tide_raw interp: %v", mid.TideRaw) }
+        if v, _ := g.LookupTideBPS(time.Unix(epoch+1, 500_000_000).UTC()); v != mid.TideBPS { t.Fatalf("LookupTideBPS disagrees: %d vs %d", v, mid.TideBPS) }
+        if _, ok := g.LookupSample(time.Unix(epoch+3,0).UTC()); ok { t.Fatal("expected out of range") }
+        _ = g.Close()
+    }
+}
+
+func TestLookupSamplePartialFields(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Unix(1_726_002_000,0).UTC().Unix()
+    p := writeGTABValues(t, dir, "bps.bin", epoch, 1_000_000_000, []uint16{10, 30})
+    g, err := Open(p); if err != nil { t.Fatalf("open: %v", err) }
+    t.Cleanup(func(){ _ = g.Close() })
+    s, ok := g.LookupSample(time.Unix(epoch, 500_000_000).UTC())
+    if !ok || s.TideBPS != 20 { t.Fatalf("bps-only sample: %+v ok=%v", s, ok) }
+    if s.Has(FieldMoonRkmF32) || s.MoonRkm != 0 { t.Fatalf("absent field should be reported missing and zero: %+v", s) }
+    if _, ok := g.SampleAt(2); ok { t.Fatal("SampleAt past end should fail") }
+}
@@ -0,0 +1,189 @@
+package ephem
+
+import (
+	"fmt"
+	"time"
+)
+
+// seriesBlock is the number of records fetched per ReadAt by a SeriesIter on
+// unmapped tables.
+const seriesBlock = 512
+
+// SeriesIter streams interpolated samples at a fixed step. Use it like
+// bufio.Scanner:
+//
+//	it := g.Series(start, end, time.Minute)
+//	for it.Next() {
+//	    use(it.Time(), it.Sample())
+//	}
+//	if err := it.Err(); err != nil { ... }
+//
+// Mapped and compressed tables are read in place; unmapped tables are read in
+// blocks of records, so a series costs one syscall per block rather than per
+// point. When step spans more than a block of records (heavy decimation) only
+// the two records around each point are read.
+type SeriesIter struct {
+    blockReader
+    next time.Time
+    end  time.Time
+    step time.Duration
+    t    time.Time
+    cur  Sample
+    err  error
+}
+
+// Series returns an iterator over [start, end] every step, interpolated with
+// the table's Interp. The range is clipped to the table's coverage: points
+// before the first record are skipped (the grid stays anchored at start) and
+// iteration stops at the last record. The iterator must not outlive Close.
+func (g *GTAB) Series(start, end time.Time, step time.Duration) *SeriesIter {
+    it := &SeriesIter{blockReader: blockReader{g: g, winStart: -1}, step: step}
+    if step <= 0 {
+        it.err = fmt.Errorf("series: invalid step %v", step)
+        return it
+    }
+    covStart, covEnd := g.Coverage()
+    if start.Before(covStart) {
+        skip := (covStart.Sub(start) + step - 1) / step
+        start = start.Add(skip * step)
+    }
+    if end.After(covEnd) {
+        end = covEnd
+    }
+    it.next, it.end = start.UTC(), end.UTC()
+    return it
+}
+
+// Next advances to the next point and reports whether there is one. Points
+// that fall in a gap (a missing record on either side) are skipped. It
+// returns false at the end of the range or on a read error; see Err.
+func (it *SeriesIter) Next() bool {
+    for it.err == nil && !it.next.After(it.end) {
+        t := it.next
+        it.next = t.Add(it.step)
+        if s, ok := it.at(t); ok {
+            it.t, it.cur = t, s
+            return true
+        }
+    }
+    return false
+}
+
+// at computes the sample at t. ok=false with a nil it.err means t is in a gap.
+func (it *SeriesIter) at(t time.Time) (Sample, bool) {
+    g := it.g
+    i, frac, ok := g.IndexFor(t)
+    if !ok {
+        it.err = fmt.Errorf("%s: series point %s out of range", g.path, t.Format(time.RFC3339Nano))
+        return Sample{}, false
+    }
+    if g.interp == InterpNearest {
+        if frac >= 0.5 {
+            i++
+        }
+        frac = 0
+    }
+    want := int64(seriesBlock)
+    if int64(it.step/g.dt) > want {
+        want = 2 // decimating: neighbours only
+    }
+    if frac != 0 {
+        it.prefetch(i, i+1, want)
+    }
+    r0, ok := it.record(i, want)
+    if !ok {
+        it.err = fmt.Errorf("%s: no valid sample at %s", g.path, t.Format(time.RFC3339Nano))
+        return Sample{}, false
+    }
+    a := g.decode(r0)
+    if a.Missing() {
+        return Sample{}, false
+    }
+    if frac == 0 {
+        return a, true
+    }
+    r1, ok := it.record(i+1, want)
+    if !ok {
+        it.err = fmt.Errorf("%s: no valid sample at %s", g.path, t.Format(time.RFC3339Nano))
+        return Sample{}, false
+    }
+    b := g.decode(r1)
+    if b.Missing() {
+        return Sample{}, false
+    }
+    return g.blend(a, b, frac), true
+}
+
+// Time returns the timestamp of the current point.
+func (it *SeriesIter) Time() time.Time { return it.t }
+
+// Sample returns the interpolated sample at the current point.
+func (it *SeriesIter) Sample() Sample { return it.cur }
+
+// TideBPS returns tide_bps at the current point.
+func (it *SeriesIter) TideBPS() uint16 { return it.cur.TideBPS }
+
+// Err returns the first error encountered, if any.
+func (it *SeriesIter) Err() error { return it.err }
+
+// blockReader serves records of g. Mapped and compressed tables are read in
+// place; unmapped tables are read through a window of up to seriesBlock
+// records filled with a single ReadAt.
+type blockReader struct {
+    g        *GTAB
+    win      []byte
+    winStart int64
+    winLen   int64
+    reads    int // ReadAt calls issued
+}
+
+// record returns record i, refilling the window on a miss with want records
+// starting at i (clamped to 1..seriesBlock). The slice is valid until the
+// next refill.
+func (b *blockReader) record(i int64, want int64) ([]byte, bool) {
+    g := b.g
+    if g.data != nil || g.index != nil {
+        return g.record(i, nil)
+    }
+    if g.r == nil || i < 0 || i >= int64(g.n) || !g.chunkOK(i) {
+        return nil, false
+    }
+    if i < b.winStart || i >= b.winStart+b.winLen {
+        if err := b.fill(i, want); err != nil {
+            return nil, false
+        }
+    }
+    off := (i - b.winStart) * g.recordSize
+    return b.win[off : off+g.recordSize], true
+}
+
+// prefetch makes sure records i..j share the window, so reading both
+// neighbours of an interval never refills between them. Errors surface from
+// the following record call.
+func (b *blockReader) prefetch(i, j, want int64) {
+    g := b.g
+    if g.data != nil || g.index != nil || g.r == nil || i < 0 || j >= int64(g.n) {
+        return
+    }
+    if i >= b.winStart && j < b.winStart+b.winLen {
+        return
+    }
+    _ = b.fill(i, max(want, j-i+1))
+}
+
+// fill reads count records starting at i with a single ReadAt.
+func (b *blockReader) fill(i, count int64) error {
+    g := b.g
+    count = max(1, min(count, seriesBlock, int64(g.n)-i))
+    if b.win == nil {
+        b.win = make([]byte, seriesBlock*g.recordSize)
+    }
+    buf := b.win[:count*g.recordSize]
+    b.reads++
+    if _, err := g.r.ReadAt(buf, g.headerSize+i*g.recordSize); err != nil {
+        b.winStart, b.winLen = -1, 0
+        return err
+    }
+    b.winStart, b.winLen = i, count
+    return nil
+}
@@ -0,0 +1,55 @@
+package ephem
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSeriesMatchesLookupSample(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC).Unix()
+    p := writeSineGTAB(t, dir, epoch, 1, 3000, 0.01, true)
+    _, v3 := writeSmooth(t, dir, 3000, 256)
+    for _, path := range []string{p, v3} {
+        for _, opts := range []Options{{}, {DisableMmap: true}, {Interp: InterpNearest, DisableMmap: true}, {Interp: InterpHermite}} {
+            g, err := OpenWithOptions(path, opts)
+            if err != nil { t.Fatalf("open: %v", err) }
+            start, end := g.Coverage()
+            for _, step := range []time.Duration{g.dt / 4, g.dt, 7 * g.dt, 1000 * g.dt} {
+                it := g.Series(start.Add(g.dt/3), end, step)
+                n := 0
+                for it.Next() {
+                    want, ok := g.LookupSample(it.Time())
+                    if !ok || it.Sample() != want || it.TideBPS() != want.TideBPS { t.Fatalf("%s %+v step=%v at %s: got %+v want %+v", path, opts, step, it.Time(), it.Sample(), want) }
+                    n++
+                }
+                if it.Err() != nil { t.Fatalf("series err: %v", it.Err()) }
+                if wantN := int((end.Sub(start.Add(g.dt/3)))/step) + 1; n != wantN { t.Fatalf("step=%v: %d points, want %d", step, n, wantN) }
+            }
+            _ = g.Close()
+        }
+    }
+}
+
+func TestSeriesBlockReadsAndClipping(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    p := writeSineGTAB(t, dir, epoch.Unix(), 1, 5000, 0.01, false)
+    g, err := OpenWithOptions(p, Options{DisableMmap: true})
+    if err != nil { t.Fatalf("open: %v", err) }
+    defer g.Close()
+    it := g.Series(epoch, epoch.Add(4999*time.Second), time.Second)
+    n := 0
+    for it.Next() { n++ }
+    if it.Err() != nil || n != 5000 { t.Fatalf("n=%d err=%v", n, it.Err()) }
+    if it.reads > 5000/seriesBlock+1 { t.Fatalf("expected block reads, got %d ReadAt calls", it.reads) }
+    // Clipped to coverage, grid anchored at the requested start.
+    it = g.Series(epoch.Add(-10*time.Second+500*time.Millisecond), epoch.Add(time.Hour*10), time.Minute)
+    if !it.Next() || !it.Time().Equal(epoch.Add(50*time.Second+500*time.Millisecond)) { t.Fatalf("first point: %s", it.Time()) }
+    last := it.Time()
+    for it.Next() { last = it.Time() }
+    if _, end := g.Coverage(); last.After(end) || end.Sub(last) >= time.Minute { t.Fatalf("last point %s, coverage end %s", last, end) }
+    // Empty and invalid ranges.
+    if it := g.Series(epoch.Add(-time.Hour), epoch.Add(-time.Minute), time.Second); it.Next() || it.Err() != nil { t.Fatal("range before coverage should be empty") }
+    if it := g.Series(epoch, epoch.Add(time.Hour), 0); it.Next() || it.Err() == nil { t.Fatal("zero step should error") }
+}
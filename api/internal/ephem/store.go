@@ -0,0 +1,135 @@
+package ephem
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync/atomic"
+)
+
+// chunk verification states
+const (
+    chunkUnknown uint32 = iota
+    chunkGood
+    chunkBad
+)
+
+// store holds the bytes of a table of fixed-size records — a mapping, a
+// caller's buffer or a ReaderAt — and the per-chunk CRC32 state shared by
+// GTAB v2/v3 and PTAB.
+type store struct {
+    r          io.ReaderAt // nil after Close
+    closer     io.Closer   // the opened file; nil for in-memory and caller's readers
+    data       []byte      // the whole table in memory (mapping or caller's bytes); nil when using ReadAt
+    unmap      bool        // data is a mapping to release on Close
+    path       string
+    n          uint32
+    headerSize int64 // offset of record 0
+    recordSize int64
+    // chunk checksums; crcs is nil for tables without them (GTAB v1)
+    chunkRecords int64
+    crcs         []uint32
+    chunkState   []atomic.Uint32
+    verify       VerifyMode
+    // index locates each compressed chunk (GTAB v3); nil when records are stored raw
+    index []chunkRef
+}
+
+// bytes returns size bytes at off, in place when the table is in memory.
+func (s *store) bytes(off, size int64) ([]byte, error) {
+    if s.data != nil {
+        return s.data[off : off+size], nil
+    }
+    if s.r == nil {
+        return nil, fmt.Errorf("%s: closed", s.path)
+    }
+    buf := make([]byte, size)
+    if _, err := s.r.ReadAt(buf, off); err != nil {
+        return nil, err
+    }
+    return buf, nil
+}
+
+// close releases the mapping (if any) and closes the underlying file.
+func (s *store) close() error {
+    var err error
+    if s.data != nil && s.unmap {
+        err = munmapFile(s.data)
+    }
+    s.data, s.r = nil, nil
+    if s.closer != nil {
+        if cerr := s.closer.Close(); err == nil {
+            err = cerr
+        }
+        s.closer = nil
+    }
+    return err
+}
+
+// loadChecksums reads the CRC trailer that follows the records.
+func (s *store) loadChecksums() error {
+    nc := numChunks(int64(s.n), s.chunkRecords)
+    buf := make([]byte, 4*nc)
+    if _, err := s.r.ReadAt(buf, s.headerSize+int64(s.n)*s.recordSize); err != nil {
+        return fmt.Errorf("read checksums: %w", err)
+    }
+    s.crcs = make([]uint32, nc)
+    for i := range s.crcs {
+        s.crcs[i] = binary.LittleEndian.Uint32(buf[4*i:])
+    }
+    s.chunkState = make([]atomic.Uint32, nc)
+    return nil
+}
+
+// chunkOK reports whether the chunk holding record i is intact, verifying it
+// on first touch unless verification is off. Tables without checksums always pass.
+func (s *store) chunkOK(i int64) bool {
+    if s.crcs == nil {
+        return true
+    }
+    c := i / s.chunkRecords
+    switch s.chunkState[c].Load() {
+    case chunkGood:
+        return true
+    case chunkBad:
+        return false
+    }
+    return s.verify == VerifyOff || s.verifyChunk(c) == nil
+}
+
+// verifyChunk checks chunk c against its stored CRC and caches the outcome.
+// Compressed chunks are checked as stored.
+func (s *store) verifyChunk(c int64) error {
+    first := c * s.chunkRecords
+    count := min(s.chunkRecords, int64(s.n)-first)
+    off, size := s.headerSize+first*s.recordSize, count*s.recordSize
+    if s.index != nil {
+        off, size = s.index[c].off, s.index[c].len
+    }
+    src, err := s.bytes(off, size)
+    if err != nil {
+        return err
+    }
+    if crc32.ChecksumIEEE(src) != s.crcs[c] {
+        s.chunkState[c].Store(chunkBad)
+        return fmt.Errorf("%s: chunk %d (records %d..%d): %w", s.path, c, first, first+count-1, ErrChecksum)
+    }
+    s.chunkState[c].Store(chunkGood)
+    return nil
+}
+
+// verifyAll checks every chunk and returns the first mismatch, or
+// ErrNoChecksums for tables without checksums.
+func (s *store) verifyAll() error {
+    if s.crcs == nil {
+        return ErrNoChecksums
+    }
+    var first error
+    for c := range s.crcs {
+        if err := s.verifyChunk(int64(c)); err != nil && first == nil {
+            first = err
+        }
+    }
+    return first
+}
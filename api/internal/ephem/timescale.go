@@ -0,0 +1,118 @@
+package ephem
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeScale identifies the scale a table's epoch and record grid are counted
+// in. Times on a scale are carried as time.Time values whose UTC wall clock
+// reads the scale's clock (e.g. the TT label of an instant), so differences
+// between them are elapsed seconds on that scale.
+type TimeScale uint8
+
+const (
+    // ScaleUTC counts civil time like Unix time: every day has 86400 steps and
+    // leap seconds are not represented. The zero value, and the scale of
+    // tables written before the field existed.
+    ScaleUTC TimeScale = iota
+    // ScaleTAI is International Atomic Time: uniform SI seconds, TAI−UTC
+    // growing by one at each leap second.
+    ScaleTAI
+    // ScaleTT is Terrestrial Time, TAI + 32.184s; it stays within 2ms of TDB,
+    // the scale ephemerides are computed in.
+    ScaleTT
+)
+
+// TTMinusTAI is the fixed offset of Terrestrial Time from TAI.
+const TTMinusTAI = 32184 * time.Millisecond
+
+func (s TimeScale) String() string {
+    switch s {
+    case ScaleUTC:
+        return "UTC"
+    case ScaleTAI:
+        return "TAI"
+    case ScaleTT:
+        return "TT"
+    }
+    return fmt.Sprintf("TimeScale(%d)", uint8(s))
+}
+
+// ParseTimeScale parses "utc", "tai" or "tt" (any case).
+func ParseTimeScale(s string) (TimeScale, error) {
+    switch strings.ToLower(s) {
+    case "utc":
+        return ScaleUTC, nil
+    case "tai":
+        return ScaleTAI, nil
+    case "tt":
+        return ScaleTT, nil
+    }
+    return 0, fmt.Errorf("unknown time scale %q (want utc, tai or tt)", s)
+}
+
+func (s TimeScale) valid() bool { return s <= ScaleTT }
+
+// leapSeconds lists the UTC instants at which TAI−UTC changed, with the new
+// offset in seconds (IERS Bulletin C). Extend it when a leap second is
+// announced.
+var leapSeconds = []struct {
+    at     int64 // unix seconds
+    offset int64
+}{
+    {date(1972, 7), 11}, {date(1973, 1), 12}, {date(1974, 1), 13}, {date(1975, 1), 14},
+    {date(1976, 1), 15}, {date(1977, 1), 16}, {date(1978, 1), 17}, {date(1979, 1), 18},
+    {date(1980, 1), 19}, {date(1981, 7), 20}, {date(1982, 7), 21}, {date(1983, 7), 22},
+    {date(1985, 7), 23}, {date(1988, 1), 24}, {date(1990, 1), 25}, {date(1991, 1), 26},
+    {date(1992, 7), 27}, {date(1993, 7), 28}, {date(1994, 7), 29}, {date(1996, 1), 30},
+    {date(1997, 7), 31}, {date(1999, 1), 32}, {date(2006, 1), 33}, {date(2009, 1), 34},
+    {date(2012, 7), 35}, {date(2015, 7), 36}, {date(2017, 1), 37},
+}
+
+func date(y int, m time.Month) int64 { return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC).Unix() }
+
+// TAIMinusUTC returns TAI−UTC at UTC instant t. Before 1972, when UTC was
+// steered by rate offsets rather than leap seconds, it returns the initial
+// 10s.
+func TAIMinusUTC(t time.Time) time.Duration {
+    u := t.Unix()
+    off := int64(10)
+    for _, ls := range leapSeconds {
+        if u < ls.at {
+            break
+        }
+        off = ls.offset
+    }
+    return time.Duration(off) * time.Second
+}
+
+// offset returns scale−UTC at UTC instant t.
+func (s TimeScale) offset(t time.Time) time.Duration {
+    switch s {
+    case ScaleTAI:
+        return TAIMinusUTC(t)
+    case ScaleTT:
+        return TAIMinusUTC(t) + TTMinusTAI
+    }
+    return 0
+}
+
+// ToScale returns the label of UTC instant t on scale s.
+func ToScale(t time.Time, s TimeScale) time.Time {
+    t = t.UTC()
+    return t.Add(s.offset(t))
+}
+
+// FromScale is the inverse of ToScale: it returns the UTC instant labelled x
+// on scale s. Labels inside an inserted leap second, which UTC as carried by
+// time.Time cannot express, map into the second that follows it.
+func FromScale(x time.Time, s TimeScale) time.Time {
+    x = x.UTC()
+    u := x.Add(-s.offset(x))
+    if off := s.offset(u); !u.Add(off).Equal(x) {
+        u = x.Add(-off)
+    }
+    return u
+}
@@ -0,0 +1,113 @@
+package ephem
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTAIMinusUTC(t *testing.T) {
+    cases := []struct {
+        at   time.Time
+        want time.Duration
+    }{
+        {time.Date(1970,1,1,0,0,0,0,time.UTC), 10 * time.Second},
+        {time.Date(1972,6,30,23,59,59,0,time.UTC), 10 * time.Second},
+        {time.Date(1972,7,1,0,0,0,0,time.UTC), 11 * time.Second},
+        {time.Date(1992,4,12,0,0,0,0,time.UTC), 26 * time.Second},
+        {time.Date(2016,12,31,23,59,59,0,time.UTC), 36 * time.Second},
+        {time.Date(2017,1,1,0,0,0,0,time.UTC), 37 * time.Second},
+        {time.Date(2026,10,16,0,0,0,0,time.UTC), 37 * time.Second},
+    }
+    for _, c := range cases {
+        if got := TAIMinusUTC(c.at); got != c.want { t.Fatalf("TAI-UTC at %s: %v want %v", c.at, got, c.want) }
+    }
+    if got := ToScale(time.Date(2025,8,1,0,0,0,0,time.UTC), ScaleTT); !got.Equal(time.Date(2025,8,1,0,1,9,184_000_000,time.UTC)) { t.Fatalf("TT label: %s", got) }
+}
+
+func TestScaleRoundTrip(t *testing.T) {
+    leap := time.Date(2017,1,1,0,0,0,0,time.UTC)
+    for _, s := range []TimeScale{ScaleUTC, ScaleTAI, ScaleTT} {
+        for d := -90 * time.Second; d <= 90*time.Second; d += 250 * time.Millisecond {
+            u := leap.Add(d)
+            if got := FromScale(ToScale(u, s), s); !got.Equal(u) { t.Fatalf("%s: %s -> %s", s, u, got) }
+        }
+        // the inserted second 23:59:60 UTC exists on the uniform scales only
+        if s != ScaleUTC {
+            x := ToScale(leap, s).Add(-500 * time.Millisecond)
+            if got := FromScale(x, s); !got.Equal(leap.Add(500 * time.Millisecond)) { t.Fatalf("%s: leap second label %s -> %s", s, x, got) }
+        }
+        p, err := ParseTimeScale(s.String())
+        if err != nil || p != s { t.Fatalf("parse %q: %v %v", s.String(), p, err) }
+    }
+    if _, err := ParseTimeScale("gps"); err == nil { t.Fatal("expected error for unknown scale") }
+}
+
+func TestTAITableSpansLeapSecond(t *testing.T) {
+    dir := t.TempDir()
+    utc0 := time.Date(2016,12,31,23,59,0,0,time.UTC)
+    // tide_bps counts elapsed SI seconds since utc0: 60 before midnight plus the leap second
+    h := Header{Epoch: ToScale(utc0, ScaleTAI), Step: time.Second, Fields: FieldTideBPS, Scale: ScaleTAI}
+    for _, version := range []int{1, 2, 3} {
+        p := filepath.Join(dir, "tai.bin")
+        w, err := CreateWithOptions(p, h, WriteOptions{Version: version})
+        if err != nil { t.Fatalf("create: %v", err) }
+        for i := 0; i < 200; i++ {
+            if err := w.Write(Sample{TideBPS: uint16(i)}); err != nil { t.Fatalf("write: %v", err) }
+        }
+        if err := w.Close(); err != nil { t.Fatalf("close: %v", err) }
+        g, err := Open(p)
+        if err != nil { t.Fatalf("v%d open: %v", version, err) }
+        if g.Scale() != ScaleTAI || g.Header() != h { t.Fatalf("v%d header: %+v", version, g.Header()) }
+        if start, _ := g.Coverage(); !start.Equal(utc0) { t.Fatalf("v%d coverage starts %s, want %s", version, start, utc0) }
+        for _, c := range []struct {
+            at   time.Time
+            want uint16
+        }{
+            {utc0.Add(30 * time.Second), 30},
+            {time.Date(2016,12,31,23,59,59,0,time.UTC), 59},
+            {time.Date(2017,1,1,0,0,0,0,time.UTC), 61},
+            {time.Date(2017,1,1,0,1,0,0,time.UTC), 121},
+        } {
+            if v, ok := g.LookupTideBPS(c.at); !ok || v != c.want { t.Fatalf("v%d at %s: %d %v, want %d", version, c.at, v, ok, c.want) }
+        }
+        if got := g.TimeAt(61); !got.Equal(time.Date(2017,1,1,0,0,0,0,time.UTC)) { t.Fatalf("v%d TimeAt(61) = %s", version, got) }
+        _ = g.Close()
+    }
+}
+
+func TestTTTableAlignsWithAlgo(t *testing.T) {
+    // A table on TT, as an ephemeris generator produces natively, answers UTC
+    // lookups at the right instant rather than 69s off.
+    utc0 := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    epoch := ToScale(utc0, ScaleTT).Truncate(time.Second)
+    p := filepath.Join(t.TempDir(), "tt.bin")
+    w, err := Create(p, Header{Epoch: epoch, Step: time.Minute, Fields: AlgoFields, Scale: ScaleTT})
+    if err != nil { t.Fatalf("create: %v", err) }
+    for i := 0; i < 120; i++ {
+        if err := w.Write(ComputeSample(FromScale(epoch.Add(time.Duration(i)*time.Minute), ScaleTT), DefaultScale)); err != nil { t.Fatalf("write: %v", err) }
+    }
+    if err := w.Close(); err != nil { t.Fatalf("close: %v", err) }
+    g, err := Open(p)
+    if err != nil { t.Fatalf("open: %v", err) }
+    defer g.Close()
+    for i := int64(0); i < 120; i += 7 {
+        at := g.TimeAt(i)
+        s, ok := g.LookupSample(at)
+        if want := ComputeSample(at, DefaultScale); !ok || s.TideRaw != want.TideRaw { t.Fatalf("record %d at %s: %g, algo %g", i, at, s.TideRaw, want.TideRaw) }
+    }
+}
+
+func TestUnknownTimeScaleRejected(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    if _, err := Create(filepath.Join(dir, "x.bin"), Header{Epoch: epoch, Step: time.Second, Fields: FieldTideBPS, Scale: 9}); err == nil { t.Fatal("writer accepted unknown scale") }
+    p := writeGTABValues(t, dir, "v1.bin", epoch.Unix(), 1_000_000_000, []uint16{1, 2})
+    b, err := os.ReadFile(p)
+    if err != nil { t.Fatal(err) }
+    if b[31] != byte(ScaleUTC) || binary.LittleEndian.Uint16(b[5:7]) != 1 { t.Fatalf("unexpected v1 header bytes: % x", b[:headerSizeV1]) }
+    b[31] = 9
+    if _, err := OpenBytes(b, "bad", Options{}); err == nil { t.Fatal("reader accepted unknown scale") }
+}
@@ -0,0 +1,302 @@
+package ephem
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+var errWriterClosed = errors.New("gtab writer closed")
+
+// Writer streams records into a GTAB table. The header is written up front
+// with n=0 and patched on Close once the record count is known.
+type Writer struct {
+    ws   io.WriteSeeker
+    bw   *bufio.Writer
+    f    *os.File // owned file when created via Create; nil otherwise
+    path string   // final path; records land in path+".tmp" until Close
+    hdr  Header
+    lay  layout
+    n    uint32
+    rec  []byte
+    err  error
+    // v2/v3 only
+    version      int
+    meta         []byte
+    chunkRecords int64
+    inChunk      int64
+    crc          uint32
+    crcs         []uint32
+    // v3 only
+    off int64 // file offset of the next chunk
+    cw  chunkWriter
+}
+
+// WriteOptions selects the on-disk format produced by a Writer.
+type WriteOptions struct {
+    // Version is 1 (default), 2 (checksummed) or 3 (compressed).
+    Version int
+    // Provenance is embedded in v2/v3 tables; a zero CreatedAt is set to now.
+    Provenance Provenance
+    // ChunkRecords is the v2 checksum / v3 compression chunk size; 0 selects DefaultChunkRecords.
+    ChunkRecords int
+}
+
+// Create writes a new v1 table at path. Records are staged in a temporary file
+// beside it and renamed into place on Close, so readers never observe a
+// partially written table.
+func Create(path string, h Header) (*Writer, error) {
+    return CreateWithOptions(path, h, WriteOptions{})
+}
+
+// CreateWithOptions is Create with an explicit format version and provenance.
+func CreateWithOptions(path string, h Header, opts WriteOptions) (*Writer, error) {
+    if err := validateHeader(h); err != nil {
+        return nil, err
+    }
+    f, err := os.Create(path + ".tmp")
+    if err != nil {
+        return nil, err
+    }
+    w, err := NewWriterWithOptions(f, h, opts)
+    if err != nil {
+        f.Close()
+        os.Remove(f.Name())
+        return nil, err
+    }
+    w.f = f
+    w.path = path
+    return w, nil
+}
+
+// NewWriter writes a v1 table to ws, which must be positioned at the start of the table.
+func NewWriter(ws io.WriteSeeker, h Header) (*Writer, error) {
+    return NewWriterWithOptions(ws, h, WriteOptions{})
+}
+
+// NewWriterWithOptions is NewWriter with an explicit format version and provenance.
+func NewWriterWithOptions(ws io.WriteSeeker, h Header, opts WriteOptions) (*Writer, error) {
+    if err := validateHeader(h); err != nil {
+        return nil, err
+    }
+    w := &Writer{ws: ws, bw: bufio.NewWriterSize(ws, 64<<10), hdr: h, lay: newLayout(h.Fields), version: 1}
+    w.rec = make([]byte, w.lay.size)
+    switch opts.Version {
+    case 0, 1:
+    case 2, 3:
+        w.version = opts.Version
+        w.chunkRecords = int64(opts.ChunkRecords)
+        if w.chunkRecords <= 0 {
+            w.chunkRecords = DefaultChunkRecords
+        }
+        if w.chunkRecords > math.MaxUint32 {
+            return nil, fmt.Errorf("chunk_records too large: %d", w.chunkRecords)
+        }
+        prov := opts.Provenance
+        if prov.CreatedAt.IsZero() {
+            prov.CreatedAt = time.Now().UTC().Truncate(time.Second)
+        }
+        meta, err := json.Marshal(prov)
+        if err != nil {
+            return nil, fmt.Errorf("encode provenance: %w", err)
+        }
+        w.meta = meta
+        w.off = headerSizeV2 + int64(len(meta))
+    default:
+        return nil, fmt.Errorf("unsupported GTAB version: %d", opts.Version)
+    }
+    if _, err := w.bw.Write(w.encodeHeader(0)); err != nil {
+        return nil, fmt.Errorf("write header: %w", err)
+    }
+    return w, nil
+}
+
+// encodeHeader renders the header (and v2/v3 provenance block) for the current
+// record count; indexOff is the v3 chunk index offset.
+func (w *Writer) encodeHeader(indexOff int64) []byte {
+    if w.version >= 2 {
+        return append(encodeHeaderV2(w.version, w.hdr, w.n, w.chunkRecords, w.meta, indexOff), w.meta...)
+    }
+    return encodeHeaderV1(w.hdr, w.n)
+}
+
+func validateHeader(h Header) error {
+    if h.Step <= 0 {
+        return fmt.Errorf("invalid step: %v", h.Step)
+    }
+    if h.Epoch.Nanosecond() != 0 {
+        return fmt.Errorf("epoch must be whole seconds: %v", h.Epoch)
+    }
+    if h.Fields == 0 {
+        return errors.New("empty record layout (fields_mask=0)")
+    }
+    if h.Fields&^fieldsKnown != 0 {
+        return fmt.Errorf("unknown fields_mask bits: %#x", h.Fields&^fieldsKnown)
+    }
+    if !h.Scale.valid() {
+        return fmt.Errorf("unknown time scale: %v", h.Scale)
+    }
+    return nil
+}
+
+func encodeHeaderV1(h Header, n uint32) []byte {
+    hdr := make([]byte, headerSizeV1)
+    copy(hdr[:5], "GTAB1")
+    binary.LittleEndian.PutUint16(hdr[5:7], 1)
+    binary.LittleEndian.PutUint64(hdr[7:15], uint64(h.Epoch.Unix()))
+    binary.LittleEndian.PutUint64(hdr[15:23], uint64(h.Step))
+    binary.LittleEndian.PutUint32(hdr[23:27], n)
+    binary.LittleEndian.PutUint32(hdr[27:31], h.Fields)
+    hdr[31] = byte(h.Scale)
+    return hdr
+}
+
+// Write appends one record. Fields absent from the header's fields_mask are
+// ignored. A sample with tide_bps MissingBPS or any NaN field is written as a
+// gap record, every field set to its sentinel (see Sample.Missing).
+func (w *Writer) Write(s Sample) error {
+    if w.err != nil {
+        return w.err
+    }
+    if s.Fields = w.hdr.Fields; s.Missing() {
+        s = missingSample(w.hdr.Fields)
+    }
+    if w.n == math.MaxUint32 {
+        w.err = errors.New("too many records for GTAB")
+        return w.err
+    }
+    l := &w.lay
+    put32 := func(off int64, v float32) {
+        if off >= 0 {
+            binary.LittleEndian.PutUint32(w.rec[off:off+4], math.Float32bits(v))
+        }
+    }
+    if l.tideBPS >= 0 {
+        binary.LittleEndian.PutUint16(w.rec[l.tideBPS:l.tideBPS+2], s.TideBPS)
+    }
+    put32(l.tideRaw, s.TideRaw)
+    put32(l.moonRkm, s.MoonRkm)
+    put32(l.sunRkm, s.SunRkm)
+    put32(l.moonRinv3, s.MoonRinv3)
+    put32(l.sunRinv3, s.SunRinv3)
+    put32(l.tideRawDt, s.TideRawDt)
+    if w.version == 3 {
+        w.n++
+        w.cw.pending = append(w.cw.pending, w.rec...)
+        if int64(len(w.cw.pending)) == w.chunkRecords*l.size {
+            if err := w.cw.flush(w); err != nil {
+                w.err = err
+                return err
+            }
+        }
+        return nil
+    }
+    if _, err := w.bw.Write(w.rec); err != nil {
+        w.err = err
+        return err
+    }
+    w.n++
+    if w.version == 2 {
+        w.crc = crc32.Update(w.crc, crc32.IEEETable, w.rec)
+        if w.inChunk++; w.inChunk == w.chunkRecords {
+            w.crcs = append(w.crcs, w.crc)
+            w.crc, w.inChunk = 0, 0
+        }
+    }
+    return nil
+}
+
+// WriteMissing appends a gap record for a step with no data.
+func (w *Writer) WriteMissing() error { return w.Write(missingSample(w.hdr.Fields)) }
+
+// Len returns the number of records written so far.
+func (w *Writer) Len() int { return int(w.n) }
+
+// Close flushes buffered records and patches the header with the final count.
+// Tables from Create are renamed into place; an empty or failed table is
+// discarded and an error returned.
+func (w *Writer) Close() error {
+    err := w.finish()
+    if w.f != nil {
+        if cerr := w.f.Close(); err == nil {
+            err = cerr
+        }
+        if err == nil {
+            err = os.Rename(w.f.Name(), w.path)
+        }
+        if err != nil {
+            os.Remove(w.f.Name())
+        }
+        w.f = nil
+    }
+    if err == nil {
+        w.err = errWriterClosed
+    } else if w.err == nil {
+        w.err = err
+    }
+    return err
+}
+
+// Abort discards the table: the temporary file of a table from Create is
+// closed and removed, and nothing is renamed over path. The Writer is closed
+// afterwards; Abort after Close is a no-op.
+func (w *Writer) Abort() error {
+    var err error
+    if w.f != nil {
+        err = w.f.Close()
+        if rerr := os.Remove(w.f.Name()); err == nil {
+            err = rerr
+        }
+        w.f = nil
+    }
+    w.err = errWriterClosed
+    return err
+}
+
+func (w *Writer) finish() error {
+    if w.err != nil {
+        return w.err
+    }
+    if w.n == 0 {
+        return errors.New("empty table (n=0)")
+    }
+    var indexOff int64
+    if w.version == 3 {
+        if err := w.cw.flush(w); err != nil {
+            return err
+        }
+        indexOff = w.off
+        if _, err := w.bw.Write(w.cw.index); err != nil {
+            return err
+        }
+    }
+    if w.version == 2 {
+        if w.inChunk > 0 {
+            w.crcs = append(w.crcs, w.crc)
+        }
+        var b [4]byte
+        for _, c := range w.crcs {
+            binary.LittleEndian.PutUint32(b[:], c)
+            if _, err := w.bw.Write(b[:]); err != nil {
+                return err
+            }
+        }
+    }
+    if err := w.bw.Flush(); err != nil {
+        return err
+    }
+    if _, err := w.ws.Seek(0, io.SeekStart); err != nil {
+        return fmt.Errorf("seek header: %w", err)
+    }
+    if _, err := w.ws.Write(w.encodeHeader(indexOff)); err != nil {
+        return fmt.Errorf("patch header: %w", err)
+    }
+    return nil
+}
@@ -0,0 +1,53 @@
+package ephem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriterRoundTrip(t *testing.T) {
+    dir := t.TempDir()
+    p := filepath.Join(dir, "rt.bin")
+    h := Header{Epoch: time.Date(2025,8,1,0,0,0,0,time.UTC), Step: 60 * time.Second, Fields: FieldTideBPS | FieldMoonRkmF32 | FieldTideRawDtF32}
+    w, err := Create(p, h)
+    if err != nil { t.Fatalf("create: %v", err) }
+    for i := 0; i < 100; i++ {
+        if err := w.Write(Sample{TideBPS: uint16(i * 100), MoonRkm: 384000 + float32(i), SunRkm: 99, TideRawDt: float32(i) / 2}); err != nil { t.Fatalf("write: %v", err) }
+    }
+    if _, err := os.Stat(p); !os.IsNotExist(err) { t.Fatal("table should not appear before Close") }
+    if err := w.Close(); err != nil { t.Fatalf("close: %v", err) }
+    if err := w.Write(Sample{}); err == nil { t.Fatal("write after close should fail") }
+    fi, err := os.Stat(p)
+    if err != nil { t.Fatalf("stat: %v", err) }
+    if want := int64(headerSizeV1 + 100*(2+4+4)); fi.Size() != want { t.Fatalf("size %d want %d", fi.Size(), want) }
+
+    g, err := Open(p)
+    if err != nil { t.Fatalf("open: %v", err) }
+    t.Cleanup(func(){ _ = g.Close() })
+    if got := g.Header(); !got.Epoch.Equal(h.Epoch) || got.Step != h.Step || got.Fields != h.Fields { t.Fatalf("header mismatch: %+v", got) }
+    if g.Len() != 100 { t.Fatalf("len %d", g.Len()) }
+    s, ok := g.SampleAt(42)
+    if !ok || s.TideBPS != 4200 || s.MoonRkm != 384042 || s.TideRawDt != 21 { t.Fatalf("sample 42: %+v", s) }
+    if s.SunRkm != 0 || s.Has(FieldSunRkmF32) { t.Fatalf("field outside mask leaked: %+v", s) }
+}
+
+func TestWriterRejectsBadHeaders(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    bad := []Header{
+        {Epoch: epoch, Step: 0, Fields: FieldTideBPS},
+        {Epoch: epoch.Add(time.Millisecond), Step: time.Second, Fields: FieldTideBPS},
+        {Epoch: epoch, Step: time.Second, Fields: 0},
+        {Epoch: epoch, Step: time.Second, Fields: 0x8000},
+    }
+    for i, h := range bad {
+        if _, err := Create(filepath.Join(dir, "bad.bin"), h); err == nil { t.Fatalf("case %d: expected error for %+v", i, h) }
+    }
+    w, err := Create(filepath.Join(dir, "empty.bin"), Header{Epoch: epoch, Step: time.Second, Fields: FieldTideBPS})
+    if err != nil { t.Fatalf("create: %v", err) }
+    if err := w.Close(); err == nil { t.Fatal("expected error closing empty table") }
+    if _, err := os.Stat(filepath.Join(dir, "empty.bin")); !os.IsNotExist(err) { t.Fatal("empty table should be discarded") }
+    if _, err := os.Stat(filepath.Join(dir, "empty.bin.tmp")); !os.IsNotExist(err) { t.Fatal("temp file should be removed") }
+}
@@ -0,0 +1,19 @@
+//go:build embedephem
+
+package ephemdata
+
+import (
+    "embed"
+    "io/fs"
+)
+
+//go:embed data
+var files embed.FS
+
+func init() {
+    sub, err := fs.Sub(files, "data")
+    if err != nil {
+        panic(err)
+    }
+    FS = sub
+}
@@ -0,0 +1,13 @@
+// Package ephemdata exposes a GTAB dataset compiled into the binary, for
+// single-file deployments with no ephem directory beside them.
+//
+// Default builds embed nothing and FS is nil. To embed a dataset, copy the
+// tables (e.g. gtab_1s.bin, gtab_60s.bin) and gtab.meta.json into data/ and
+// build with -tags embedephem.
+package ephemdata
+
+import "io/fs"
+
+// FS holds the embedded tables and gtab.meta.json at its root, or is nil when
+// the binary was built without -tags embedephem.
+var FS fs.FS
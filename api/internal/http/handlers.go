@@ -3,10 +3,12 @@ package httpapi
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"os"
 	"time"
 
+	"github.com/Jthora/autoBotTrader/api/internal/accuracy"
 	"github.com/Jthora/autoBotTrader/api/internal/normalize"
 	"github.com/Jthora/autoBotTrader/api/internal/providers"
 )
@@ -16,10 +18,28 @@ func writeJSONError(w http.ResponseWriter, code int, msg string) {
     _ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
 }
 
+// writeGravError reports a gravimetric fetch failure. A dataset gap
+// (providers.ErrNoData) gets its own code so clients can treat the signal as
+// degraded rather than the service as down.
+func writeGravError(w http.ResponseWriter, err error) {
+    if errors.Is(err, providers.ErrNoData) {
+        writeJSONError(w, http.StatusServiceUnavailable, "gravimetrics_no_data")
+        return
+    }
+    writeJSONError(w, http.StatusServiceUnavailable, "gravimetrics_fetch_failed")
+}
+
 type Handlers struct {
     Astro providers.AstrologyProvider
     Grav  providers.GravimetricProvider
     Chain ChainClient
+    // Signals, if set, are the inputs of the /predict composite; nil means
+    // providers.DefaultRegistry(Astro, Grav).
+    Signals *providers.Registry
+    // FetchTimeout bounds each provider fetch in Predict and Push; 0 means 3s.
+    FetchTimeout time.Duration
+    // Accuracy, if set, is the scheduled dataset self-check reported on /health.
+    Accuracy *accuracy.SelfCheck
 }
 
 // ChainClient abstraction for Starknet interactions.
@@ -36,6 +56,14 @@ type AstrologyResponse struct {
     CalcVersion     string                  `json:"calc_version"`
 }
 
+type AspectsResponse struct {
+    Provider    string                           `json:"provider"`
+    Aspects     []providers.Aspect               `json:"aspects"`
+    Orbs        map[providers.AspectKind]float64 `json:"orbs"`
+    AspectIndex float64                          `json:"aspect_index"`
+    CalcVersion string                           `json:"calc_version"`
+}
+
 type GravResponse struct {
     Provider        string                    `json:"provider"`
     Raw             providers.GravimetricData `json:"raw"`
@@ -44,14 +72,28 @@ type GravResponse struct {
     Mode            string                    `json:"mode,omitempty"`
     DatasetID       string                    `json:"dataset_id,omitempty"`
     Stale           bool                      `json:"stale,omitempty"`
+    Resolution      string                    `json:"resolution,omitempty"`
 }
 
+// SignalResponse is one signal's input to the composite.
+type SignalResponse struct {
+    Provider        string `json:"provider"`
+    Raw             any    `json:"raw"`
+    NormalizedScore uint32 `json:"normalized_score"`
+    Weight          uint32 `json:"weight"`
+    CalcVersion     string `json:"calc_version"`
+    providers.SignalMeta
+}
+
+// PredictResponse carries every enabled signal under Signals. The astrology
+// and gravity signals are also reported in their original sections.
 type PredictResponse struct {
-    Astrology        AstrologyResponse `json:"astrology"`
-    Gravimetrics     GravResponse      `json:"gravimetrics"`
-    CompositePreview uint32            `json:"composite_preview"`
-    Weights          map[string]uint32 `json:"weights"`
-    Version          string            `json:"version"`
+    Astrology        *AstrologyResponse        `json:"astrology,omitempty"`
+    Gravimetrics     *GravResponse             `json:"gravimetrics,omitempty"`
+    Signals          map[string]SignalResponse `json:"signals"`
+    CompositePreview uint32                    `json:"composite_preview"`
+    Weights          map[string]uint32         `json:"weights"`
+    Version          string                    `json:"version"`
 }
 
 type PushRequest struct {
@@ -62,15 +104,28 @@ type PushResponse struct {
     TxHash string `json:"tx_hash"`
     DryRun bool   `json:"dry_run"`
     Composite uint32 `json:"composite"`
+    // Warnings lists inputs pushed from a provider's last good reading
+    // ("astrology_stale", "gravimetrics_stale").
+    Warnings []string `json:"warnings,omitempty"`
+}
+
+// gravResolution reports the cadence of the table answering at now for providers
+// backed by a multi-resolution pyramid; "" when the provider has no such notion.
+func gravResolution(p providers.GravimetricProvider, now time.Time) string {
+    if r, ok := providers.Unwrap(p).(interface{ Resolution(time.Time) time.Duration }); ok {
+        if d := r.Resolution(now); d > 0 { return d.String() }
+    }
+    return ""
 }
 
 func (h *Handlers) Health(w http.ResponseWriter, r *http.Request) {
     w.Header().Set("Content-Type", "application/json")
-    mode, dataset, stale := "", "", false
+    mode, dataset, stale, resolution := "", "", false, ""
     if h != nil && h.Grav != nil {
         if m, ok := any(h.Grav).(interface{ Mode() string }); ok { mode = m.Mode() }
         if d, ok := any(h.Grav).(interface{ DatasetID() string }); ok { dataset = d.DatasetID() }
         if s, ok := any(h.Grav).(interface{ Stale(time.Time) bool }); ok { stale = s.Stale(time.Now().UTC()) }
+        resolution = gravResolution(h.Grav, time.Now().UTC())
     }
     resp := map[string]any{
         "status": "ok",
@@ -79,6 +134,40 @@ func (h *Handlers) Health(w http.ResponseWriter, r *http.Request) {
     if mode != "" { resp["grav_mode"] = mode }
     if dataset != "" { resp["grav_dataset_id"] = dataset }
     if mode != "" { resp["grav_stale"] = stale }
+    if resolution != "" { resp["grav_resolution"] = resolution }
+    if h != nil && h.Astro != nil {
+        astro := providers.Unwrap(h.Astro)
+        if m, ok := astro.(interface{ Mode() string }); ok {
+            resp["astro_mode"] = m.Mode()
+            if s, ok := astro.(interface{ Stale(time.Time) bool }); ok { resp["astro_stale"] = s.Stale(time.Now().UTC()) }
+        }
+        if d, ok := astro.(interface{ DatasetID() string }); ok && d.DatasetID() != "" { resp["astro_dataset_id"] = d.DatasetID() }
+    }
+    if h != nil {
+        weights := map[string]uint32{}
+        for _, s := range h.signals().Enabled() { weights[s.Name] = s.Weight }
+        resp["signals"] = weights
+        // circuit breakers of resilient providers; any not closed degrades the service
+        for key, p := range map[string]any{"astro_circuit": h.Astro, "grav_circuit": h.Grav} {
+            if c, ok := p.(interface{ Status() providers.ResilienceStatus }); ok {
+                st := c.Status()
+                resp[key] = st
+                if st.State != providers.CircuitClosed { resp["status"] = "degraded" }
+            }
+        }
+        if m, ok := h.Accuracy.Last(); ok && m.Skipped {
+            resp["grav_accuracy"] = map[string]any{"skipped": true, "generated_at": m.GeneratedAt, "notes": m.Notes}
+        } else if ok {
+            acc := map[string]any{
+                "passes": m.Passes,
+                "generated_at": m.GeneratedAt,
+                "rel_error_p99": m.Stats.RelErrorP99,
+                "peak_drift_seconds_p99": m.Stats.PeakDriftSecondsP99,
+            }
+            if m.Reason != "" { acc["reason"] = m.Reason }
+            resp["grav_accuracy"] = acc
+        }
+    }
     _ = json.NewEncoder(w).Encode(resp)
 }
 
@@ -97,11 +186,40 @@ func (h *Handlers) Astrology(w http.ResponseWriter, r *http.Request) {
     json.NewEncoder(w).Encode(resp)
 }
 
+// Aspects lists the major aspects between the astrology provider's planet
+// positions. Orbs default to the provider's (if it has AspectOptions) and can
+// be overridden per request with ?orbs=square:6,sextile:4.
+func (h *Handlers) Aspects(w http.ResponseWriter, r *http.Request) {
+    opts := providers.AspectOptions{}
+    if p, ok := providers.Unwrap(h.Astro).(interface{ AspectOptions() providers.AspectOptions }); ok { opts = p.AspectOptions() }
+    if q := r.URL.Query().Get("orbs"); q != "" {
+        over, err := providers.ParseOrbs(q)
+        if err != nil { writeJSONError(w, http.StatusBadRequest, "invalid_orbs"); return }
+        opts = opts.With(over)
+    }
+    ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+    defer cancel()
+    data, err := h.Astro.Fetch(ctx)
+    if err != nil { writeJSONError(w, http.StatusServiceUnavailable, "astrology_fetch_failed"); return }
+    // the mock provider reports an index without positions
+    if len(data.Planets) == 0 { writeJSONError(w, http.StatusServiceUnavailable, "astrology_no_positions"); return }
+    aspects := providers.FindAspects(data, opts)
+    resp := AspectsResponse{
+        Provider: h.Astro.Name(),
+        Aspects: aspects,
+        Orbs: opts.Effective(),
+        AspectIndex: providers.AspectIndex(aspects),
+        CalcVersion: "v1",
+    }
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(resp)
+}
+
 func (h *Handlers) Gravimetrics(w http.ResponseWriter, r *http.Request) {
     ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
     defer cancel()
     data, err := h.Grav.Fetch(ctx)
-    if err != nil { writeJSONError(w, http.StatusServiceUnavailable, "gravimetrics_fetch_failed"); return }
+    if err != nil { writeGravError(w, err); return }
     // Optional meta if provider supports it
     mode, dataset, stale := "", "", false
     if m, ok := any(h.Grav).(interface{ Mode() string }); ok { mode = m.Mode() }
@@ -115,53 +233,108 @@ func (h *Handlers) Gravimetrics(w http.ResponseWriter, r *http.Request) {
         Mode: mode,
         DatasetID: dataset,
         Stale: stale,
+        Resolution: gravResolution(h.Grav, time.Now().UTC()),
     }
     w.Header().Set("Content-Type", "application/json")
     json.NewEncoder(w).Encode(resp)
 }
 
-func composite(a, b, aw, bw uint32) uint32 {
-    total := aw + bw
+// composite is the weighted mean of scores; 0 when all weights are 0.
+func composite(scores, weights []uint32) uint32 {
+    var sum, total uint64
+    for i, s := range scores {
+        sum += uint64(s) * uint64(weights[i])
+        total += uint64(weights[i])
+    }
     if total == 0 { return 0 }
-    return (a*aw + b*bw) / total
+    return uint32(sum / total)
 }
 
+func (h *Handlers) fetchTimeout() time.Duration {
+    if h.FetchTimeout > 0 { return h.FetchTimeout }
+    return 3 * time.Second
+}
+
+func (h *Handlers) signals() *providers.Registry {
+    if h.Signals != nil { return h.Signals }
+    return providers.DefaultRegistry(h.Astro, h.Grav)
+}
+
+// Predict fetches the enabled signals in registration order and previews
+// their weighted composite. Each fetch gets its own FetchTimeout, so a slow
+// signal cannot spend the others' budget. The first failing signal fails the
+// request with its error code; later signals are not fetched.
 func (h *Handlers) Predict(w http.ResponseWriter, r *http.Request) {
-    ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
-    defer cancel()
-    aData, aErr := h.Astro.Fetch(ctx); if aErr != nil { writeJSONError(w, http.StatusServiceUnavailable, "astrology_fetch_failed"); return }
-    gData, gErr := h.Grav.Fetch(ctx); if gErr != nil { writeJSONError(w, http.StatusServiceUnavailable, "gravimetrics_fetch_failed"); return }
-    aScore := normalize.AstrologyScore(aData.VolatilityIndex)
-    gScore := normalize.GravimetricScore(gData.LunarTideForce)
-    aw, gw := uint32(50), uint32(50)
-    // Include meta from Grav provider in Predict as well
-    mode, dataset, stale := "", "", false
-    if m, ok := any(h.Grav).(interface{ Mode() string }); ok { mode = m.Mode() }
-    if d, ok := any(h.Grav).(interface{ DatasetID() string }); ok { dataset = d.DatasetID() }
-    if s, ok := any(h.Grav).(interface{ Stale(time.Time) bool }); ok { stale = s.Stale(time.Now().UTC()) }
+    signals := h.signals().Enabled()
     resp := PredictResponse{
-        Astrology: AstrologyResponse{Provider: h.Astro.Name(), Raw: aData, NormalizedScore: aScore, CalcVersion: "v1"},
-        Gravimetrics: GravResponse{Provider: h.Grav.Name(), Raw: gData, NormalizedScore: gScore, CalcVersion: "v1", Mode: mode, DatasetID: dataset, Stale: stale},
-        CompositePreview: composite(aScore, gScore, aw, gw),
-        Weights: map[string]uint32{"astrology": aw, "gravity": gw, "ml": 0},
+        Signals: make(map[string]SignalResponse, len(signals)),
+        // the on-chain weights always carry an ml term
+        Weights: map[string]uint32{"ml": 0},
         Version: "v1",
     }
+    scores, weights := make([]uint32, 0, len(signals)), make([]uint32, 0, len(signals))
+    for _, sig := range signals {
+        ctx, cancel := context.WithTimeout(r.Context(), h.fetchTimeout())
+        rd, err := sig.Fetch(ctx)
+        cancel()
+        if err != nil { writeJSONError(w, http.StatusServiceUnavailable, sig.Code(err)); return }
+        sr := SignalResponse{Provider: sig.Provider, Raw: rd.Raw, NormalizedScore: sig.Normalize(rd.Value), Weight: sig.Weight, CalcVersion: sig.CalcVersion}
+        if sig.Meta != nil { sr.SignalMeta = sig.Meta(time.Now().UTC()) }
+        resp.Signals[sig.Name] = sr
+        resp.Weights[sig.Name] = sig.Weight
+        scores, weights = append(scores, sr.NormalizedScore), append(weights, sig.Weight)
+        switch raw := rd.Raw.(type) {
+        case providers.AstrologyData:
+            resp.Astrology = &AstrologyResponse{Provider: sr.Provider, Raw: raw, NormalizedScore: sr.NormalizedScore, CalcVersion: sr.CalcVersion}
+        case providers.GravimetricData:
+            resp.Gravimetrics = &GravResponse{Provider: sr.Provider, Raw: raw, NormalizedScore: sr.NormalizedScore, CalcVersion: sr.CalcVersion,
+                Mode: sr.Mode, DatasetID: sr.DatasetID, Stale: sr.Stale, Resolution: sr.Resolution}
+        }
+    }
+    resp.CompositePreview = composite(scores, weights)
     w.Header().Set("Content-Type", "application/json")
     json.NewEncoder(w).Encode(resp)
 }
 
 // Push simulates pushing prediction inputs on-chain (stubbed until Starknet client wired in).
+// A failing provider's input falls back to its last good reading when it
+// keeps one (see providers.ResilientGravimetric), with a warning; without a
+// fallback, and for gravimetric dataset gaps, nothing is pushed. Each provider
+// gets its own FetchTimeout, so a slow source cannot spend the other's budget.
 func (h *Handlers) Push(w http.ResponseWriter, r *http.Request) {
-    ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
-    defer cancel()
-    aData, _ := h.Astro.Fetch(ctx)
-    gData, _ := h.Grav.Fetch(ctx)
+    fetchTimeout := h.fetchTimeout()
+    var warnings []string
+    now := time.Now().UTC()
+    actx, acancel := context.WithTimeout(r.Context(), fetchTimeout)
+    aData, aErr := h.Astro.Fetch(actx)
+    acancel()
+    if aErr != nil {
+        lg, ok := any(h.Astro).(interface{ LastGood(time.Time) (providers.AstrologyData, bool) })
+        if !ok { writeJSONError(w, http.StatusServiceUnavailable, "astrology_fetch_failed"); return }
+        if aData, ok = lg.LastGood(now); !ok { writeJSONError(w, http.StatusServiceUnavailable, "astrology_fetch_failed"); return }
+        warnings = append(warnings, "astrology_stale")
+    }
+    gctx, gcancel := context.WithTimeout(r.Context(), fetchTimeout)
+    gData, gErr := h.Grav.Fetch(gctx)
+    gcancel()
+    if errors.Is(gErr, providers.ErrNoData) { // never push a zero for a gap
+        writeGravError(w, gErr)
+        return
+    }
+    if gErr != nil {
+        lg, ok := any(h.Grav).(interface{ LastGood(time.Time) (providers.GravimetricData, bool) })
+        if !ok { writeGravError(w, gErr); return }
+        if gData, ok = lg.LastGood(now); !ok { writeGravError(w, gErr); return }
+        warnings = append(warnings, "gravimetrics_stale")
+    }
     aScore := normalize.AstrologyScore(aData.VolatilityIndex)
     gScore := normalize.GravimetricScore(gData.LunarTideForce)
     if aScore > 100 || gScore > 100 { // defensive, normalization should clamp but guard anyway
         writeJSONError(w, http.StatusBadRequest, "score_out_of_range")
         return
     }
+    ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+    defer cancel()
     txHash := "0xDRYRUN"
     dry := true
     if h.Chain != nil && (os.Getenv("PUSH_REAL") == "1") {
@@ -174,7 +347,7 @@ func (h *Handlers) Push(w http.ResponseWriter, r *http.Request) {
     if h.Chain != nil {
         if c, err := h.Chain.GetComposite(ctx); err == nil { composite = c }
     }
-    resp := PushResponse{TxHash: txHash, DryRun: dry, Composite: composite}
+    resp := PushResponse{TxHash: txHash, DryRun: dry, Composite: composite, Warnings: warnings}
     w.Header().Set("Content-Type", "application/json")
     _ = json.NewEncoder(w).Encode(resp)
 }
@@ -4,11 +4,14 @@ import (
     "context"
     "encoding/json"
     "errors"
+    "fmt"
     "net/http"
     "net/http/httptest"
+    "strings"
     "testing"
     "time"
 
+    "github.com/Jthora/autoBotTrader/api/internal/accuracy"
     "github.com/Jthora/autoBotTrader/api/internal/providers"
 )
 
@@ -41,6 +44,12 @@ func TestHealthIncludesMeta(t *testing.T) {
     if body["status"] != "ok" { t.Fatalf("missing status ok: %+v", body) }
     if _, ok := body["grav_mode"]; !ok { t.Fatalf("expected grav_mode meta present") }
     if _, ok := body["ts"]; !ok { t.Fatalf("expected ts present") }
+    if _, ok := body["astro_mode"]; ok { t.Fatalf("mock astrology has no mode: %+v", body) }
+    h.Astro = providers.AlgoAstrology{}
+    rr = httptest.NewRecorder()
+    NewRouter(h).ServeHTTP(rr, req)
+    body = nil
+    if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil || body["astro_mode"] != "algo" { t.Fatalf("astro_mode: %+v %v", body, err) }
 }
 
 func TestGravimetricsSuccessSchema(t *testing.T) {
@@ -52,7 +61,7 @@ func TestGravimetricsSuccessSchema(t *testing.T) {
     if ct := rr.Header().Get("Content-Type"); ct != "application/json" { t.Fatalf("content-type: %s", ct) }
     var body struct {
         Provider string `json:"provider"`
-        Raw struct { LunarTideForce float64 `json:"lunar_tide_force"` } `json:"raw"`
+        Raw struct { LunarTideForce float64 `json:"lunar_tide_force"`; Phase string `json:"phase"`; Illumination float64 `json:"illumination"` } `json:"raw"`
         NormalizedScore uint32 `json:"normalized_score"`
         CalcVersion string `json:"calc_version"`
         Mode string `json:"mode"`
@@ -60,6 +69,7 @@ func TestGravimetricsSuccessSchema(t *testing.T) {
     }
     if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil { t.Fatalf("decode: %v", err) }
     if body.Provider == "" || body.CalcVersion == "" { t.Fatalf("missing fields: %+v", body) }
+    if body.Raw.Phase == "" || body.Raw.Illumination <= 0 { t.Fatalf("missing lunar phase: %+v", body.Raw) }
     if body.NormalizedScore == 0 { t.Fatalf("expected non-zero normalized score") }
     if body.Mode == "" { t.Fatalf("expected mode meta") }
 }
@@ -75,6 +85,55 @@ func TestGravimetricsErrorPath(t *testing.T) {
     if body["error"] != "gravimetrics_fetch_failed" { t.Fatalf("unexpected error body: %+v", body) }
 }
 
+// gapGrav fails like a file provider inside a dataset gap.
+type gapGrav struct{ failGrav }
+func (g gapGrav) Fetch(ctx context.Context) (providers.GravimetricData, error) { return providers.GravimetricData{}, fmt.Errorf("grav_file_v1: %w", providers.ErrNoData) }
+
+func TestGravimetricsNoData(t *testing.T) {
+    h := &Handlers{Astro: providers.MockAstrology{}, Grav: gapGrav{}, Chain: mockChain{hash: "0xabc"}}
+    for _, c := range []struct{ method, path string }{{http.MethodGet, "/gravimetrics"}, {http.MethodGet, "/predict"}, {http.MethodPost, "/push"}} {
+        rr := httptest.NewRecorder()
+        NewRouter(h).ServeHTTP(rr, httptest.NewRequest(c.method, c.path, nil))
+        if rr.Code != http.StatusServiceUnavailable { t.Fatalf("%s: expected 503 got %d", c.path, rr.Code) }
+        var body map[string]string
+        if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil { t.Fatalf("%s decode: %v", c.path, err) }
+        if body["error"] != "gravimetrics_no_data" { t.Fatalf("%s: unexpected error body: %+v", c.path, body) }
+    }
+}
+
+// astrology provider with fixed positions and provider-level orbs
+type fixedAstro struct{ orbs map[providers.AspectKind]float64 }
+func (f fixedAstro) Name() string { return "fixed" }
+func (f fixedAstro) Fetch(ctx context.Context) (providers.AstrologyData, error) {
+    return providers.AstrologyData{VolatilityIndex: 360, Planets: []providers.PlanetPosition{
+        {Name: "sun", Longitude: 10, Speed: 1}, {Name: "mars", Longitude: 104, Speed: 0.5}, {Name: "jupiter", Longitude: 132, Speed: 0.1},
+    }}, nil
+}
+func (f fixedAstro) AspectOptions() providers.AspectOptions { return providers.AspectOptions{Orbs: f.orbs} }
+
+func TestAstrologyAspectsEndpoint(t *testing.T) {
+    get := func(h *Handlers, url string) (*httptest.ResponseRecorder, AspectsResponse) {
+        rr := httptest.NewRecorder()
+        NewRouter(h).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, url, nil))
+        var body AspectsResponse
+        if rr.Code == 200 {
+            if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil { t.Fatalf("decode: %v", err) }
+        }
+        return rr, body
+    }
+    // Sun–Mars 94° (square, 4° off), Sun–Jupiter 122° (trine, 2° off)
+    h := &Handlers{Astro: fixedAstro{orbs: map[providers.AspectKind]float64{providers.Square: 3}}, Grav: providers.MockGravimetric{}}
+    rr, body := get(h, "/astrology/aspects")
+    if rr.Code != 200 || rr.Header().Get("Content-Type") != "application/json" { t.Fatalf("status %d %s", rr.Code, rr.Body) }
+    if body.Provider != "fixed" || body.Orbs[providers.Square] != 3 || body.Orbs[providers.Trine] != providers.DefaultOrbs[providers.Trine] { t.Fatalf("orbs: %+v", body) }
+    if len(body.Aspects) != 1 || body.Aspects[0].Kind != providers.Trine || !body.Aspects[0].Applying || body.AspectIndex != 0 { t.Fatalf("aspects: %+v", body) }
+    _, body = get(h, "/astrology/aspects?orbs=square:5")
+    if len(body.Aspects) != 2 || body.Aspects[0].Kind != providers.Square || !body.Aspects[0].Applying { t.Fatalf("query orbs: %+v", body.Aspects) }
+    if rr, _ := get(h, "/astrology/aspects?orbs=square:40"); rr.Code != http.StatusBadRequest { t.Fatalf("bad orbs: %d", rr.Code) }
+    if rr, _ := get(newHandlers(nil), "/astrology/aspects"); rr.Code != http.StatusServiceUnavailable || !strings.Contains(rr.Body.String(), "astrology_no_positions") { t.Fatalf("mock provider: %d %s", rr.Code, rr.Body) }
+    if rr, body := get(&Handlers{Astro: providers.AlgoAstrology{}}, "/astrology/aspects"); rr.Code != 200 || len(body.Orbs) != 5 || body.Aspects == nil { t.Fatalf("algo provider: %d %+v", rr.Code, body) }
+}
+
 func TestPredictEndpoint(t *testing.T) {
     h := newHandlers(nil)
     srv := httptest.NewServer(NewRouter(h))
@@ -82,9 +141,65 @@ func TestPredictEndpoint(t *testing.T) {
     resp, err := http.Get(srv.URL + "/predict")
     if err != nil { t.Fatalf("request error: %v", err) }
     if resp.StatusCode != 200 { t.Fatalf("expected 200 got %d", resp.StatusCode) }
-    var body struct { CompositePreview uint32 `json:"composite_preview"`; Weights map[string]uint32 `json:"weights"` }
+    var body struct {
+        CompositePreview uint32 `json:"composite_preview"`
+        Weights map[string]uint32 `json:"weights"`
+        Gravimetrics struct { Raw struct { Phase string `json:"phase"` } `json:"raw"` } `json:"gravimetrics"`
+    }
     if err := json.NewDecoder(resp.Body).Decode(&body); err != nil { t.Fatalf("decode: %v", err) }
     if body.Weights["astrology"]+body.Weights["gravity"] == 0 { t.Fatal("weights not set") }
+    if body.Gravimetrics.Raw.Phase == "" { t.Fatal("predict gravimetrics missing phase") }
+}
+
+func TestPredictRegistrySignals(t *testing.T) {
+    h := newHandlers(nil)
+    h.Signals = providers.DefaultRegistry(h.Astro, h.Grav)
+    ml := providers.Signal{
+        Name: "ml", Provider: "fixed_ml",
+        Fetch: func(ctx context.Context) (providers.Reading, error) { return providers.Reading{Value: 0.9, Raw: map[string]float64{"p_up": 0.9}}, nil },
+        Normalize: func(v float64) uint32 { return uint32(v * 100) },
+        Weight: 20,
+    }
+    if err := h.Signals.Register(ml); err != nil { t.Fatalf("register: %v", err) }
+    if err := h.Signals.Configure("gravity:30,ml"); err != nil { t.Fatalf("configure: %v", err) }
+    rr := httptest.NewRecorder()
+    NewRouter(h).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/predict", nil))
+    if rr.Code != 200 { t.Fatalf("expected 200 got %d", rr.Code) }
+    var body PredictResponse
+    if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil { t.Fatalf("decode: %v", err) }
+    if body.Astrology != nil || body.Signals["astrology"].Provider != "" { t.Fatalf("disabled astrology reported: %s", rr.Body) }
+    g, m := body.Signals["gravity"], body.Signals["ml"]
+    if body.Gravimetrics == nil || g.NormalizedScore != body.Gravimetrics.NormalizedScore || g.Mode != "mock" { t.Fatalf("gravity signal: %s", rr.Body) }
+    if m.Provider != "fixed_ml" || m.NormalizedScore != 90 || m.Weight != 20 { t.Fatalf("ml signal: %+v", m) }
+    if body.Weights["gravity"] != 30 || body.Weights["ml"] != 20 || body.Weights["astrology"] != 0 { t.Fatalf("weights: %v", body.Weights) }
+    if want := (g.NormalizedScore*30 + 90*20) / 50; body.CompositePreview != want { t.Fatalf("composite %d want %d", body.CompositePreview, want) }
+}
+
+func TestPredictGivesEachSignalItsOwnTimeout(t *testing.T) {
+    const budget = 200 * time.Millisecond
+    // "slow" spends most of its budget; "next" must still get a full one
+    slow := providers.Signal{Name: "slow", Normalize: func(float64) uint32 { return 10 }, Weight: 1,
+        Fetch: func(ctx context.Context) (providers.Reading, error) {
+            select {
+            case <-time.After(budget * 3 / 4): return providers.Reading{}, nil
+            case <-ctx.Done(): return providers.Reading{}, ctx.Err()
+            }
+        }}
+    var left time.Duration
+    next := providers.Signal{Name: "next", Normalize: func(float64) uint32 { return 30 }, Weight: 1,
+        Fetch: func(ctx context.Context) (providers.Reading, error) {
+            deadline, _ := ctx.Deadline()
+            left = time.Until(deadline)
+            return providers.Reading{}, nil
+        }}
+    reg := providers.NewRegistry()
+    if err := reg.Register(slow); err != nil { t.Fatal(err) }
+    if err := reg.Register(next); err != nil { t.Fatal(err) }
+    h := &Handlers{Signals: reg, FetchTimeout: budget}
+    rr := httptest.NewRecorder()
+    NewRouter(h).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/predict", nil))
+    if rr.Code != 200 { t.Fatalf("expected 200 got %d: %s", rr.Code, rr.Body) }
+    if left <= budget/2 || left > budget { t.Fatalf("second signal had %v of a %v budget", left, budget) }
 }
 
 func TestPushDryRun(t *testing.T) {
@@ -127,3 +242,96 @@ func TestPushChainErrorFallsBack(t *testing.T) {
     if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil { t.Fatalf("decode: %v", err) }
     if !body.DryRun { t.Fatalf("expected dryrun on error") }
 }
+
+// toggleGrav is a mock source that can be taken down.
+type toggleGrav struct {
+    providers.MockGravimetric
+    down bool
+}
+func (g *toggleGrav) Fetch(ctx context.Context) (providers.GravimetricData, error) {
+    if err := ctx.Err(); err != nil { return providers.GravimetricData{}, err }
+    if g.down { return providers.GravimetricData{}, errors.New("upstream 502") }
+    return g.MockGravimetric.Fetch(ctx)
+}
+
+func TestPushFallsBackToLastGood(t *testing.T) {
+    src := &toggleGrav{}
+    h := &Handlers{Astro: providers.MockAstrology{}, Grav: providers.NewResilientGravimetric(src, providers.Policy{Attempts: 1, FailureThreshold: 1})}
+    serve := func(method, path string) (int, map[string]any) {
+        rr := httptest.NewRecorder()
+        NewRouter(h).ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+        var body map[string]any
+        if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil { t.Fatalf("%s decode: %v", path, err) }
+        return rr.Code, body
+    }
+    if code, body := serve(http.MethodPost, "/push"); code != 200 || body["warnings"] != nil { t.Fatalf("healthy push: %d %v", code, body) }
+    src.down = true
+    code, body := serve(http.MethodPost, "/push")
+    if code != 200 || fmt.Sprint(body["warnings"]) != "[gravimetrics_stale]" { t.Fatalf("degraded push: %d %v", code, body) }
+    _, body = serve(http.MethodGet, "/health")
+    circuit, _ := body["grav_circuit"].(map[string]any)
+    if body["status"] != "degraded" || circuit["state"] != "open" || circuit["last_error"] != "upstream 502" { t.Fatalf("health: %v", body) }
+    if code, body := serve(http.MethodGet, "/predict"); code != 503 || body["error"] != "gravimetrics_fetch_failed" { t.Fatalf("predict: %d %v", code, body) }
+    // without a last good reading nothing is pushed
+    h.Grav = providers.NewResilientGravimetric(src, providers.Policy{Attempts: 1})
+    if code, body := serve(http.MethodPost, "/push"); code != 503 || body["error"] != "gravimetrics_fetch_failed" { t.Fatalf("push without fallback: %d %v", code, body) }
+}
+
+// slowAstro answers only when its caller's context expires, once slow is set.
+type slowAstro struct {
+    providers.MockAstrology
+    slow bool
+}
+func (a *slowAstro) Fetch(ctx context.Context) (providers.AstrologyData, error) {
+    if a.slow {
+        <-ctx.Done()
+        return providers.AstrologyData{}, ctx.Err()
+    }
+    return a.MockAstrology.Fetch(ctx)
+}
+
+func TestPushSlowAstrologySparesGravimetrics(t *testing.T) {
+    astro := &slowAstro{}
+    policy := providers.Policy{Attempts: 1, FailureThreshold: 1}
+    ra, rg := providers.NewResilientAstrology(astro, policy), providers.NewResilientGravimetric(&toggleGrav{}, policy)
+    h := &Handlers{Astro: ra, Grav: rg, FetchTimeout: 20 * time.Millisecond}
+    push := func() (int, PushResponse) {
+        rr := httptest.NewRecorder()
+        NewRouter(h).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/push", nil))
+        var body PushResponse
+        json.Unmarshal(rr.Body.Bytes(), &body)
+        return rr.Code, body
+    }
+    if code, body := push(); code != 200 || len(body.Warnings) != 0 { t.Fatalf("healthy push: %d %+v", code, body) }
+    astro.slow = true
+    if code, body := push(); code != 200 || fmt.Sprint(body.Warnings) != "[astrology_stale]" { t.Fatalf("slow astrology push: %d %+v", code, body) }
+    if st := rg.Status(); st.State != providers.CircuitClosed || st.ConsecutiveFailures != 0 { t.Fatalf("gravimetric breaker charged for astrology: %+v", st) }
+    // the astrology source timed out on its own budget: no verdict either
+    if st := ra.Status(); st.State != providers.CircuitClosed { t.Fatalf("astrology breaker: %+v", st) }
+}
+
+func TestHealthReportsSelfCheck(t *testing.T) {
+    h := newHandlers(nil)
+    get := func() map[string]any {
+        rr := httptest.NewRecorder()
+        NewRouter(h).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/health", nil))
+        var body map[string]any
+        if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil { t.Fatalf("decode: %v", err) }
+        return body
+    }
+    if _, ok := get()["grav_accuracy"]; ok { t.Fatalf("grav_accuracy without a self-check") }
+    // an empty table never has data: the check is skipped, not failed
+    none := func(time.Time) (float64, bool) { return 0, false }
+    h.Accuracy = accuracy.NewSelfCheck(none, none, accuracy.Config{Cadence: time.Hour}, 24*time.Hour, nil)
+    if _, ok := get()["grav_accuracy"]; ok { t.Fatalf("grav_accuracy before the first run") }
+    h.Accuracy.Check(time.Now().UTC())
+    acc, ok := get()["grav_accuracy"].(map[string]any)
+    if !ok || acc["skipped"] != true || acc["passes"] != nil { t.Fatalf("grav_accuracy: %v", acc) }
+    // a partial gap still fails
+    now := time.Now().UTC()
+    half := func(t time.Time) (float64, bool) { return 1, t.After(now.Add(-12 * time.Hour)) }
+    h.Accuracy = accuracy.NewSelfCheck(half, half, accuracy.Config{Cadence: time.Hour}, 24*time.Hour, nil)
+    h.Accuracy.Check(now)
+    acc, ok = get()["grav_accuracy"].(map[string]any)
+    if !ok || acc["passes"] != false || acc["reason"] != accuracy.ReasonCoverageGap { t.Fatalf("grav_accuracy: %v", acc) }
+}
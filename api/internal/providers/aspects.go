@@ -0,0 +1,169 @@
+package providers
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+// AspectKind names a major (Ptolemaic) aspect.
+type AspectKind string
+
+const (
+    Conjunction AspectKind = "conjunction"
+    Opposition  AspectKind = "opposition"
+    Square      AspectKind = "square"
+    Trine       AspectKind = "trine"
+    Sextile     AspectKind = "sextile"
+)
+
+// AspectKinds lists the detected aspects.
+var AspectKinds = [...]AspectKind{Conjunction, Opposition, Square, Trine, Sextile}
+
+// Angle returns the exact separation of the aspect in degrees, or NaN for an
+// unknown kind.
+func (k AspectKind) Angle() float64 {
+    switch k {
+    case Conjunction:
+        return 0
+    case Opposition:
+        return 180
+    case Square:
+        return 90
+    case Trine:
+        return 120
+    case Sextile:
+        return 60
+    }
+    return math.NaN()
+}
+
+// hard reports whether the aspect counts as tension in AspectIndex.
+func (k AspectKind) hard() bool { return k == Opposition || k == Square }
+
+// DefaultOrbs are the orbs (degrees either side of exact) used for kinds an
+// AspectOptions does not set.
+var DefaultOrbs = map[AspectKind]float64{Conjunction: 8, Opposition: 8, Square: 7, Trine: 7, Sextile: 5}
+
+// MaxOrb bounds configured orbs so that no separation can fall within two
+// aspects at once (the closest aspects, sextile and square, are 30° apart).
+const MaxOrb = 15
+
+// AspectOptions configures FindAspects.
+type AspectOptions struct {
+    // Orbs overrides DefaultOrbs per kind.
+    Orbs map[AspectKind]float64
+}
+
+// Orb returns the orb in effect for k.
+func (o AspectOptions) Orb(k AspectKind) float64 {
+    if v, ok := o.Orbs[k]; ok { return v }
+    return DefaultOrbs[k]
+}
+
+// With returns o with the orbs in over taking precedence.
+func (o AspectOptions) With(over map[AspectKind]float64) AspectOptions {
+    m := make(map[AspectKind]float64, len(o.Orbs)+len(over))
+    for k, v := range o.Orbs { m[k] = v }
+    for k, v := range over { m[k] = v }
+    return AspectOptions{Orbs: m}
+}
+
+// Effective returns the orb of every kind, defaults filled in.
+func (o AspectOptions) Effective() map[AspectKind]float64 {
+    m := make(map[AspectKind]float64, len(AspectKinds))
+    for _, k := range AspectKinds { m[k] = o.Orb(k) }
+    return m
+}
+
+// ParseOrbs parses a comma-separated list of kind:degrees pairs, e.g.
+// "square:6,sextile:4". A bare number sets every kind.
+func ParseOrbs(s string) (map[AspectKind]float64, error) {
+    m := map[AspectKind]float64{}
+    for _, part := range strings.Split(s, ",") {
+        if part = strings.TrimSpace(part); part == "" { continue }
+        name, val, ok := strings.Cut(part, ":")
+        if !ok { val, name = name, "" }
+        v, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+        if err != nil || !(v > 0 && v <= MaxOrb) { return nil, fmt.Errorf("orb %q: want degrees in (0, %d]", part, MaxOrb) }
+        if name == "" {
+            for _, k := range AspectKinds { m[k] = v }
+            continue
+        }
+        k := AspectKind(strings.ToLower(strings.TrimSpace(name)))
+        if math.IsNaN(k.Angle()) { return nil, fmt.Errorf("unknown aspect %q", name) }
+        m[k] = v
+    }
+    return m, nil
+}
+
+// Aspect is a major aspect between two positions, A preceding B in the
+// positions' order.
+type Aspect struct {
+    A          string     `json:"a"`
+    B          string     `json:"b"`
+    Kind       AspectKind `json:"kind"`
+    Angle      float64    `json:"angle"`      // exact aspect angle, degrees
+    Separation float64    `json:"separation"` // angular distance of A and B, degrees [0, 180]
+    Orb        float64    `json:"orb"`        // |Separation − Angle|, degrees
+    Exactness  float64    `json:"exactness"`  // 100 when exact, 0 at the edge of the orb
+    // Applying is true while the orb is shrinking, from the positions'
+    // longitude speeds; false when separating or when speeds are unknown.
+    Applying bool `json:"applying"`
+}
+
+// FindAspects returns the aspects within orb between every pair of d.Planets,
+// ordered by pair. A pair forms at most one aspect.
+func FindAspects(d AstrologyData, opts AspectOptions) []Aspect {
+    ps := d.Planets
+    out := []Aspect{}
+    for i := 0; i < len(ps); i++ {
+        for j := i + 1; j < len(ps); j++ {
+            if a, ok := aspectBetween(ps[i], ps[j], opts); ok { out = append(out, a) }
+        }
+    }
+    return out
+}
+
+func aspectBetween(p, q PlanetPosition, opts AspectOptions) (Aspect, bool) {
+    // d is q's longitude relative to p in (−180, 180]; the separation is |d|
+    d := 180 - math.Mod(540-(q.Longitude-p.Longitude), 360)
+    sep := math.Abs(d)
+    var best Aspect
+    found := false
+    for _, k := range AspectKinds {
+        orb, dev := opts.Orb(k), math.Abs(sep-k.Angle())
+        if dev > orb || (found && dev >= best.Orb) { continue }
+        best = Aspect{A: p.Name, B: q.Name, Kind: k, Angle: k.Angle(), Separation: sep, Orb: dev, Exactness: 100 * (1 - dev/orb)}
+        found = true
+    }
+    if !found { return Aspect{}, false }
+    // the separation changes at ±(q.Speed − p.Speed) depending on which side q is
+    rate := q.Speed - p.Speed
+    if d < 0 { rate = -rate }
+    best.Applying = (sep-best.Angle)*rate < 0
+    return best, true
+}
+
+// AspectIndex scores aspects on the volatility_index range [0, 720] by the
+// exactness-weighted share of tension among them: squares and oppositions are
+// hard, trines and sextiles soft, conjunctions count half to each. With no
+// aspects it is 360, the midpoint.
+func AspectIndex(as []Aspect) float64 {
+    var hard, soft float64
+    for _, a := range as {
+        w := a.Exactness / 100
+        switch {
+        case a.Kind == Conjunction:
+            hard += w / 2
+            soft += w / 2
+        case a.Kind.hard():
+            hard += w
+        default:
+            soft += w
+        }
+    }
+    if hard+soft == 0 { return 360 }
+    return 720 * hard / (hard + soft)
+}
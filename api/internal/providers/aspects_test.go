@@ -0,0 +1,74 @@
+package providers
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFindAspects(t *testing.T) {
+    pos := func(name string, lon, speed float64) PlanetPosition { return PlanetPosition{Name: name, Longitude: lon, Speed: speed} }
+    d := AstrologyData{Planets: []PlanetPosition{
+        pos("sun", 10, 1),
+        pos("moon", 355, 13),
+        pos("mars", 103, 0.5),
+        pos("jupiter", 190, -0.1),
+        pos("venus", 247, 1.2), // 123° behind the Sun across 0°
+    }}
+    want := []struct {
+        a, b     string
+        kind     AspectKind
+        orb      float64
+        applying bool
+    }{
+        {"sun", "mars", Square, 3, true},      // the Sun gains on Mars, separation 93° → 90°
+        {"sun", "jupiter", Opposition, 0, false},
+        {"sun", "venus", Trine, 3, true},      // Venus gains on the Sun, 123° → 120°
+        {"mars", "jupiter", Square, 3, false}, // 87° and shrinking, away from 90°
+        {"jupiter", "venus", Sextile, 3, true},
+    }
+    got := FindAspects(d, AspectOptions{})
+    if len(got) != len(want) { t.Fatalf("got %d aspects: %+v", len(got), got) }
+    for i, w := range want {
+        a := got[i]
+        if a.A != w.a || a.B != w.b || a.Kind != w.kind || math.Abs(a.Orb-w.orb) > 1e-9 || a.Applying != w.applying { t.Fatalf("aspect %d: %+v, want %+v", i, a, w) }
+        if ex := 100 * (1 - w.orb/DefaultOrbs[w.kind]); math.Abs(a.Exactness-ex) > 1e-9 || a.Angle != w.kind.Angle() { t.Fatalf("aspect %d exactness %g want %g", i, a.Exactness, ex) }
+    }
+    // hard: two squares at 3/7 off plus an exact opposition; soft: a trine and a sextile
+    hard, soft := 2*(1-3./7)+1, (1-3./7)+(1-3./5)
+    if got, want := AspectIndex(got), 720*hard/(hard+soft); math.Abs(got-want) > 1e-9 { t.Fatalf("aspect index %g want %g", got, want) }
+    if AspectIndex(nil) != 360 { t.Fatal("empty aspect index should be the midpoint") }
+
+    // narrower sextile orb drops Jupiter–Venus; a wide conjunction orb picks up the Sun and Moon 15° apart
+    orbs, err := ParseOrbs("sextile:2, Conjunction:15")
+    if err != nil { t.Fatalf("parse: %v", err) }
+    got = FindAspects(d, AspectOptions{}.With(orbs))
+    if len(got) != 5 || got[0].Kind != Conjunction || got[0].Exactness != 0 || !got[0].Applying || got[4].B != "jupiter" { t.Fatalf("custom orbs: %+v", got) }
+    if FindAspects(AstrologyData{VolatilityIndex: 100}, AspectOptions{}) == nil { t.Fatal("no positions should give an empty list") }
+}
+
+func TestParseOrbs(t *testing.T) {
+    m, err := ParseOrbs("6")
+    if err != nil || len(m) != len(AspectKinds) || m[Trine] != 6 { t.Fatalf("bare orb: %v %v", m, err) }
+    o := AspectOptions{Orbs: map[AspectKind]float64{Square: 4}}.With(map[AspectKind]float64{Trine: 5})
+    if eff := o.Effective(); eff[Square] != 4 || eff[Trine] != 5 || eff[Sextile] != DefaultOrbs[Sextile] { t.Fatalf("effective: %v", eff) }
+    for _, bad := range []string{"quincunx:3", "square:0", "square:16", "trine:x", "-1"} {
+        if _, err := ParseOrbs(bad); err == nil { t.Fatalf("%q accepted", bad) }
+    }
+}
+
+func TestAspectWeightFeedsVolatility(t *testing.T) {
+    at := time.Date(2020,12,21,18,0,0,0,time.UTC) // Jupiter–Saturn conjunction
+    plain, _ := AlgoAstrology{}.FetchAt(at)
+    if plain.Dispersion != 0 || plain.AspectIndex != 0 { t.Fatalf("components reported without blending: %+v", plain) }
+    full, _ := AlgoAstrology{AspectWeight: 1}.FetchAt(at)
+    idx := AspectIndex(FindAspects(plain, AspectOptions{}))
+    if full.Dispersion != plain.VolatilityIndex || full.AspectIndex != idx || full.VolatilityIndex != idx { t.Fatalf("weight 1: %+v, aspect index %g", full, idx) }
+    half, _ := AlgoAstrology{AspectWeight: 0.5}.FetchAt(at)
+    if math.Abs(half.VolatilityIndex-(plain.VolatilityIndex+idx)/2) > 1e-9 { t.Fatalf("weight 0.5: %g", half.VolatilityIndex) }
+    found := false
+    for _, a := range FindAspects(plain, AspectOptions{}) {
+        if a.A == "jupiter" && a.B == "saturn" { found = a.Kind == Conjunction && a.Exactness > 99 }
+    }
+    if !found { t.Fatal("great conjunction not detected") }
+}
@@ -0,0 +1,91 @@
+package providers
+
+import (
+    "context"
+    "math"
+    "time"
+
+    "github.com/Jthora/autoBotTrader/api/internal/ephem"
+)
+
+// AlgoAstrology implements AstrologyProvider from the analytic ephemeris of
+// the seven classical bodies (see ephem.EclipticPosition), so it is offline,
+// deterministic for a given instant and covers any timestamp. The zero value
+// reports the pure dispersion index.
+type AlgoAstrology struct {
+    // Aspects configures the orbs used for aspect detection.
+    Aspects AspectOptions
+    // AspectWeight in [0, 1] blends AspectIndex into VolatilityIndex:
+    // (1−w)·dispersion + w·aspect index.
+    AspectWeight float64
+}
+
+func (a AlgoAstrology) Name() string { return "algo_planets_v1" }
+
+func (a AlgoAstrology) Fetch(ctx context.Context) (AstrologyData, error) {
+    select { case <-ctx.Done(): return AstrologyData{}, ctx.Err(); default: }
+    return a.FetchAt(time.Now().UTC())
+}
+
+// FetchAt fetches using a specific timestamp (testability and determinism). Not part of the interface.
+func (a AlgoAstrology) FetchAt(at time.Time) (AstrologyData, error) {
+    return astrologyData(PlanetPositions(at), a.Aspects, a.AspectWeight), nil
+}
+
+func (a AlgoAstrology) Mode() string { return "algo" }
+
+// AspectOptions reports the orbs /astrology/aspects uses by default.
+func (a AlgoAstrology) AspectOptions() AspectOptions { return a.Aspects }
+
+// astrologyData derives the volatility index of ps, blending in the aspect
+// index with weight w (clamped to [0, 1]) when positive.
+func astrologyData(ps []PlanetPosition, opts AspectOptions, w float64) AstrologyData {
+    d := AstrologyData{VolatilityIndex: VolatilityIndex(ps), Planets: ps}
+    if w = math.Max(0, math.Min(1, w)); w > 0 {
+        d.Dispersion = d.VolatilityIndex
+        d.AspectIndex = AspectIndex(FindAspects(d, opts))
+        d.VolatilityIndex = (1-w)*d.Dispersion + w*d.AspectIndex
+    }
+    return d
+}
+
+// PlanetPositions returns the positions of ephem.Bodies at t, in that order.
+func PlanetPositions(t time.Time) []PlanetPosition {
+    ps := make([]PlanetPosition, 0, len(ephem.Bodies))
+    for _, b := range ephem.Bodies {
+        ps = append(ps, planetPosition(b, ephem.ComputeBodyState(b, t)))
+    }
+    return ps
+}
+
+func planetPosition(b ephem.Body, s ephem.BodyState) PlanetPosition {
+    return PlanetPosition{Name: b.String(), Longitude: s.Lon, Latitude: s.Lat, Speed: s.Speed, Retrograde: s.Speed < 0, Sign: Sign(s.Lon)}
+}
+
+var signs = [...]string{"aries", "taurus", "gemini", "cancer", "leo", "virgo", "libra", "scorpio", "sagittarius", "capricorn", "aquarius", "pisces"}
+
+// Sign returns the tropical zodiac sign containing ecliptic longitude lon.
+func Sign(lon float64) string {
+    lon = math.Mod(lon, 360)
+    if lon < 0 { lon += 360 }
+    return signs[int(lon/30)%12]
+}
+
+// VolatilityIndex is the combined angular dispersion of the positions'
+// longitudes, in [0, 720]: 360·(1−R₁) + 360·(1−R₂), where Rₖ is the mean
+// resultant length of the k-th harmonic (the length of the average of the unit
+// vectors at k·λ). R₁ measures clustering around one point of the zodiac and
+// R₂ alignment along one axis, so conjunctions and oppositions both lower the
+// index; 0 means every body is conjunct, 720 that they are spread evenly.
+func VolatilityIndex(ps []PlanetPosition) float64 {
+    if len(ps) == 0 { return 0 }
+    var c1, s1, c2, s2 float64
+    for _, p := range ps {
+        r := p.Longitude * math.Pi / 180
+        c1 += math.Cos(r); s1 += math.Sin(r)
+        c2 += math.Cos(2 * r); s2 += math.Sin(2 * r)
+    }
+    n := float64(len(ps))
+    r1, r2 := math.Hypot(c1, s1)/n, math.Hypot(c2, s2)/n
+    return math.Max(0, math.Min(720, 360*(1-r1)+360*(1-r2)))
+}
@@ -0,0 +1,65 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAlgoAstrologyDeterministic(t *testing.T) {
+    a := AlgoAstrology{}
+    at := time.Date(2024,4,10,12,0,0,0,time.UTC)
+    d1, err := a.FetchAt(at)
+    if err != nil { t.Fatalf("fetch: %v", err) }
+    d2, _ := a.FetchAt(at)
+    if d1.VolatilityIndex != d2.VolatilityIndex || len(d1.Planets) != 7 { t.Fatalf("not deterministic: %v %v", d1, d2) }
+    if d1.VolatilityIndex <= 0 || d1.VolatilityIndex >= 720 { t.Fatalf("index out of range: %g", d1.VolatilityIndex) }
+    byName := map[string]PlanetPosition{}
+    for _, p := range d1.Planets { byName[p.Name] = p }
+    if p := byName["mercury"]; !p.Retrograde || p.Speed >= 0 || p.Sign != "aries" { t.Fatalf("mercury: %+v", p) }
+    if p := byName["sun"]; p.Retrograde || p.Sign != "aries" || math.Abs(p.Speed-0.98) > 0.02 { t.Fatalf("sun: %+v", p) }
+    b, _ := json.Marshal(d1)
+    var raw struct {
+        Planets []map[string]any `json:"planets"`
+    }
+    if err := json.Unmarshal(b, &raw); err != nil || len(raw.Planets) != 7 { t.Fatalf("json: %s", b) }
+    for _, k := range []string{"name", "longitude", "latitude", "speed", "retrograde", "sign"} {
+        if _, ok := raw.Planets[0][k]; !ok { t.Fatalf("planet json lacks %q: %s", k, b) }
+    }
+    for _, at := range []time.Time{time.Date(1900,1,1,0,0,0,0,time.UTC), time.Now(), time.Date(2100,1,1,0,0,0,0,time.UTC)} {
+        d, err := a.FetchAt(at)
+        if err != nil || d.VolatilityIndex < 0 || d.VolatilityIndex > 720 { t.Fatalf("fetch at %s: %v %v", at, d.VolatilityIndex, err) }
+    }
+    if _, err := a.Fetch(context.Background()); err != nil { t.Fatalf("fetch: %v", err) }
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    if _, err := a.Fetch(ctx); err == nil { t.Fatal("expected context error") }
+}
+
+func TestVolatilityIndexBounds(t *testing.T) {
+    at := func(lons ...float64) []PlanetPosition {
+        ps := make([]PlanetPosition, len(lons))
+        for i, l := range lons { ps[i].Longitude = l }
+        return ps
+    }
+    cases := []struct {
+        name string
+        ps   []PlanetPosition
+        want float64
+    }{
+        {"all conjunct", at(10, 10, 10, 10, 10, 10, 10), 0},
+        {"evenly spread", at(0, 360./7, 720./7, 1080./7, 1440./7, 1800./7, 2160./7), 720},
+        {"one opposition", at(0, 180), 360},  // axis fully aligned, no net direction
+        {"square", at(90, 180), 360 * (1 - math.Sqrt2/2) + 360},
+        {"none", nil, 0},
+    }
+    for _, c := range cases {
+        if got := VolatilityIndex(c.ps); math.Abs(got-c.want) > 1e-9 { t.Fatalf("%s: %g want %g", c.name, got, c.want) }
+    }
+    if VolatilityIndex(at(0, 0, 0, 120)) >= VolatilityIndex(at(0, 90, 180, 270)) { t.Fatal("clustering should lower the index") }
+    for lon, want := range map[float64]string{0: "aries", 29.99: "aries", 30: "taurus", 359.9: "pisces", -1: "pisces", 725: "aries"} {
+        if got := Sign(lon); got != want { t.Fatalf("Sign(%g) = %s want %s", lon, got, want) }
+    }
+}
@@ -0,0 +1,72 @@
+package providers
+
+import (
+    "context"
+    "fmt"
+    "path/filepath"
+    "time"
+
+    "github.com/Jthora/autoBotTrader/api/internal/ephem"
+)
+
+// FileAstrology implements AstrologyProvider from a PTAB planetary table (see
+// ephem.PTAB), trading the per-request ephemeris evaluation of AlgoAstrology
+// for O(1) mapped lookups. Positions the table cannot answer (outside its
+// coverage, or a gap) are computed analytically, so Fetch never fails for
+// lack of data; Stale reports when that happens for the current time.
+type FileAstrology struct {
+    // Aspects and AspectWeight behave as in AlgoAstrology.
+    Aspects      AspectOptions
+    AspectWeight float64
+    tab          *ephem.PTAB
+    name         string
+    datasetID    string
+}
+
+// NewFileAstrology opens the PTAB at path, which must carry every body of
+// ephem.Bodies. datasetID overrides the table's embedded provenance.
+func NewFileAstrology(path, datasetID string) (*FileAstrology, error) {
+    tab, err := ephem.OpenPTAB(path, ephem.PTABOptions{})
+    if err != nil { return nil, err }
+    for _, b := range ephem.Bodies {
+        if !tab.Has(b) {
+            tab.Close()
+            return nil, fmt.Errorf("%s: table lacks %s", path, b)
+        }
+    }
+    if datasetID == "" {
+        prov, _ := tab.Provenance()
+        datasetID = prov.DatasetID
+    }
+    return &FileAstrology{tab: tab, name: filepath.Base(path), datasetID: datasetID}, nil
+}
+
+func (f *FileAstrology) Name() string { return f.name }
+func (f *FileAstrology) Mode() string { return "file" }
+func (f *FileAstrology) DatasetID() string { return f.datasetID }
+func (f *FileAstrology) Stale(now time.Time) bool {
+    start, end := f.tab.Coverage()
+    return now.Before(start) || now.After(end)
+}
+
+// AspectOptions reports the orbs /astrology/aspects uses by default.
+func (f *FileAstrology) AspectOptions() AspectOptions { return f.Aspects }
+
+func (f *FileAstrology) Fetch(ctx context.Context) (AstrologyData, error) {
+    select { case <-ctx.Done(): return AstrologyData{}, ctx.Err(); default: }
+    return f.FetchAt(time.Now().UTC())
+}
+
+// FetchAt fetches using a specific timestamp (testability and determinism). Not part of the interface.
+func (f *FileAstrology) FetchAt(at time.Time) (AstrologyData, error) {
+    ps := make([]PlanetPosition, 0, len(ephem.Bodies))
+    for _, b := range ephem.Bodies {
+        s, ok := f.tab.Lookup(b, at)
+        if !ok { s = ephem.ComputeBodyState(b, at) }
+        ps = append(ps, planetPosition(b, s))
+    }
+    return astrologyData(ps, f.Aspects, f.AspectWeight), nil
+}
+
+// Close releases the table.
+func (f *FileAstrology) Close() error { return f.tab.Close() }
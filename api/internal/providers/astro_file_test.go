@@ -0,0 +1,45 @@
+package providers
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jthora/autoBotTrader/api/internal/ephem"
+)
+
+func TestFileAstrologyMatchesAlgo(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    p := filepath.Join(dir, "planets_1h.ptab")
+    w, err := ephem.CreatePTAB(p, ephem.PTABHeader{Epoch: epoch, Step: time.Hour, Bodies: ephem.Bodies[:]}, ephem.PTABWriteOptions{Provenance: ephem.Provenance{DatasetID: "planets_2025"}})
+    if err != nil { t.Fatalf("create: %v", err) }
+    for i := 0; i < 48; i++ {
+        if err := w.WriteAt(); err != nil { t.Fatalf("write: %v", err) }
+    }
+    if err := w.Close(); err != nil { t.Fatalf("close: %v", err) }
+    f, err := NewFileAstrology(p, "")
+    if err != nil { t.Fatalf("open: %v", err) }
+    t.Cleanup(func(){ _ = f.Close() })
+    if f.Mode() != "file" || f.DatasetID() != "planets_2025" || f.Name() != "planets_1h.ptab" { t.Fatalf("identity: %s %s %s", f.Mode(), f.DatasetID(), f.Name()) }
+    f.AspectWeight = 0.5
+    algo := AlgoAstrology{AspectWeight: 0.5}
+    for _, at := range []time.Time{epoch.Add(90 * time.Minute), epoch.Add(30*time.Hour + 17*time.Minute), epoch.Add(-time.Hour), epoch.Add(72 * time.Hour)} {
+        fd, err := f.FetchAt(at)
+        if err != nil { t.Fatalf("fetch at %s: %v", at, err) }
+        ad, _ := algo.FetchAt(at)
+        if math.Abs(fd.VolatilityIndex-ad.VolatilityIndex) > 0.01 || len(fd.Planets) != len(ad.Planets) { t.Fatalf("at %s: file %g algo %g", at, fd.VolatilityIndex, ad.VolatilityIndex) }
+        for k := range fd.Planets {
+            a, b := fd.Planets[k], ad.Planets[k]
+            if a.Name != b.Name || a.Sign != b.Sign || a.Retrograde != b.Retrograde || math.Abs(a.Longitude-b.Longitude) > 1e-3 { t.Fatalf("at %s: file %+v algo %+v", at, a, b) }
+        }
+    }
+    if f.Stale(epoch.Add(time.Hour)) || !f.Stale(epoch.Add(72 * time.Hour)) { t.Fatal("stale outside coverage only") }
+
+    part := filepath.Join(dir, "moon.ptab")
+    w, _ = ephem.CreatePTAB(part, ephem.PTABHeader{Epoch: epoch, Step: time.Hour, Bodies: []ephem.Body{ephem.BodyMoon}}, ephem.PTABWriteOptions{})
+    _ = w.WriteAt()
+    _ = w.Close()
+    if _, err := NewFileAstrology(part, ""); err == nil { t.Fatal("table without every body accepted") }
+}
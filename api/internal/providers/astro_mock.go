@@ -8,6 +8,22 @@ import (
 
 type AstrologyData struct {
     VolatilityIndex float64 `json:"volatility_index"`
+    // Planet positions behind the index; empty for the mock provider.
+    Planets []PlanetPosition `json:"planets,omitempty"`
+    // Components of VolatilityIndex when aspects are blended into it (see
+    // AlgoAstrology.AspectWeight); omitted otherwise.
+    Dispersion  float64 `json:"dispersion,omitempty"`
+    AspectIndex float64 `json:"aspect_index,omitempty"`
+}
+
+// PlanetPosition is a body's geocentric ecliptic position at fetch time.
+type PlanetPosition struct {
+    Name       string  `json:"name"`       // ephem.Body name, e.g. "mars"
+    Longitude  float64 `json:"longitude"`  // degrees [0, 360), equinox of date
+    Latitude   float64 `json:"latitude"`   // degrees
+    Speed      float64 `json:"speed"`      // longitude rate, degrees/day
+    Retrograde bool    `json:"retrograde"` // speed < 0
+    Sign       string  `json:"sign"`       // zodiac sign of Longitude, e.g. "aries"
 }
 
 type AstrologyProvider interface {
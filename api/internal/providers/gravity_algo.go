@@ -0,0 +1,56 @@
+package providers
+
+import (
+    "context"
+    "time"
+
+    "github.com/Jthora/autoBotTrader/api/internal/ephem"
+)
+
+// AlgoGravimetric implements GravimetricProvider by evaluating Sun/Moon
+// ephemerides analytically (see ephem.ComputeSample), so it covers any
+// timestamp and never goes stale. tide_bps uses the same μ/r³ scalar and
+// p5→0 / p95→10000 normalization as the GTAB generator.
+type AlgoGravimetric struct {
+    scale ephem.Scale
+    hys   *bpsHysteresis
+}
+
+// NewAlgoGravimetric returns an algorithmic provider normalized with
+// ephem.DefaultScale. HYSTERESIS_BPS applies as for the file provider.
+func NewAlgoGravimetric() *AlgoGravimetric {
+    return NewAlgoGravimetricWithScale(ephem.DefaultScale)
+}
+
+// NewAlgoGravimetricWithScale normalizes tide_bps with an explicit scale, e.g.
+// the p5/p95 of a specific GTAB dataset.
+func NewAlgoGravimetricWithScale(sc ephem.Scale) *AlgoGravimetric {
+    return &AlgoGravimetric{scale: sc, hys: newHysteresisFromEnv()}
+}
+
+func (a *AlgoGravimetric) Name() string { return "algo_meeus_v1" }
+func (a *AlgoGravimetric) Mode() string { return "algo" }
+func (a *AlgoGravimetric) DatasetID() string { return "" }
+func (a *AlgoGravimetric) Stale(now time.Time) bool { return false }
+
+func (a *AlgoGravimetric) Fetch(ctx context.Context) (GravimetricData, error) {
+    select { case <-ctx.Done(): return GravimetricData{}, ctx.Err(); default: }
+    return a.FetchAt(time.Now().UTC())
+}
+
+// FetchAt fetches using a specific timestamp (testability and determinism). Not part of the interface.
+func (a *AlgoGravimetric) FetchAt(at time.Time) (GravimetricData, error) {
+    return withPhase(GravimetricData{LunarTideForce: tideForce(a.hys.apply(a.SampleAt(at).TideBPS))}, at), nil
+}
+
+// TideAt returns tide_bps at t as a float for ephem.FindEvents. Unlike the
+// fetched value it is not clamped to [0, 10000], so extrema beyond the p5/p95
+// band keep their true shape and timing.
+func (a *AlgoGravimetric) TideAt(at time.Time) (float64, bool) {
+    return a.scale.Value(ephem.TideRaw(at)), true
+}
+
+// SampleAt returns every GTAB field at t without applying hysteresis.
+func (a *AlgoGravimetric) SampleAt(at time.Time) ephem.Sample {
+    return ephem.ComputeSample(at, a.scale)
+}
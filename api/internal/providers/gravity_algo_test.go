@@ -0,0 +1,87 @@
+package providers
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jthora/autoBotTrader/api/internal/ephem"
+)
+
+func TestAlgoGravimetricNeverStale(t *testing.T) {
+    a := NewAlgoGravimetric()
+    if a.Mode() != "algo" || a.Name() == "" { t.Fatalf("identity: %s %s", a.Mode(), a.Name()) }
+    for _, at := range []time.Time{time.Date(1990,1,1,0,0,0,0,time.UTC), time.Now(), time.Date(2090,1,1,0,0,0,0,time.UTC)} {
+        if a.Stale(at) { t.Fatalf("stale at %s", at) }
+        v, err := a.FetchAt(at)
+        if err != nil || v.LunarTideForce < 80 || v.LunarTideForce > 130 { t.Fatalf("fetch at %s: %v %v", at, v, err) }
+    }
+    if _, err := a.Fetch(context.Background()); err != nil { t.Fatalf("fetch: %v", err) }
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    if _, err := a.Fetch(ctx); err == nil { t.Fatal("expected context error") }
+}
+
+// A table written from the algorithm must be served identically by the file
+// provider, i.e. both modes share the GTAB scalar and normalization.
+func TestAlgoMatchesFileProviderOnAlgoTable(t *testing.T) {
+    t.Setenv("HYSTERESIS_BPS", "")
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    path := filepath.Join(t.TempDir(), "gtab_60s.bin")
+    w, err := ephem.Create(path, ephem.Header{Epoch: epoch, Step: time.Minute, Fields: ephem.AlgoFields})
+    if err != nil { t.Fatalf("create: %v", err) }
+    a := NewAlgoGravimetric()
+    for i := 0; i < 24*60; i++ {
+        if err := w.Write(a.SampleAt(epoch.Add(time.Duration(i) * time.Minute))); err != nil { t.Fatalf("write: %v", err) }
+    }
+    if err := w.Close(); err != nil { t.Fatalf("close: %v", err) }
+    f, err := NewFileGravimetric(path, "algo-fixture")
+    if err != nil { t.Fatalf("open: %v", err) }
+    t.Cleanup(func(){ _ = f.Close() })
+    for i := 0; i < 24*60; i += 37 {
+        at := epoch.Add(time.Duration(i) * time.Minute)
+        fv, _ := f.FetchAt(at)
+        av, _ := a.FetchAt(at)
+        if fv != av { t.Fatalf("at %s: file %v algo %v", at, fv, av) }
+    }
+}
+
+func TestProvidersFeedEventFinder(t *testing.T) {
+    a := NewAlgoGravimetric()
+    start := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    // Lunar perigee/apogee dominate tide_raw: expect alternating extrema about two weeks apart.
+    evs, err := ephem.FindEvents(a.TideAt, start, start.Add(60*24*time.Hour), ephem.EventOptions{Step: time.Hour, Kinds: ephem.EventMax | ephem.EventMin})
+    if err != nil { t.Fatalf("find: %v", err) }
+    if len(evs) < 3 { t.Fatalf("too few extrema: %+v", evs) }
+    for i := 1; i < len(evs); i++ {
+        if evs[i].Kind == evs[i-1].Kind { t.Fatalf("extrema should alternate: %+v", evs) }
+        if gap := evs[i].Time.Sub(evs[i-1].Time); gap < 10*24*time.Hour || gap > 18*24*time.Hour { t.Fatalf("extrema %v apart: %+v", gap, evs) }
+    }
+    f, err := NewFileGravimetric(writeLevel(t, filepath.Join(t.TempDir(), "flat.bin"), start, time.Minute, 10, 500), "")
+    if err != nil { t.Fatalf("open: %v", err) }
+    t.Cleanup(func(){ _ = f.Close() })
+    if v, ok := f.TideAt(start.Add(90 * time.Second)); !ok || v != 500 { t.Fatalf("file TideAt: %v %v", v, ok) }
+    if _, ok := f.TideAt(start.Add(time.Hour)); ok { t.Fatal("TideAt outside coverage should miss") }
+}
+
+func TestProvidersReportLunarPhase(t *testing.T) {
+    full := time.Date(2025, 8, 9, 7, 55, 0, 0, time.UTC)
+    v, _ := NewAlgoGravimetric().FetchAt(full)
+    if v.Phase != ephem.PhaseFull || v.Illumination < 0.99 || v.PhaseAngle < 170 || v.PhaseAngle > 190 { t.Fatalf("algo at full moon: %+v", v) }
+    // A file dataset clamps the tide to its coverage but not the phase.
+    path := filepath.Join(t.TempDir(), "gtab_60s.bin")
+    epoch := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+    w, err := ephem.Create(path, ephem.Header{Epoch: epoch, Step: time.Minute, Fields: ephem.FieldTideBPS})
+    if err != nil { t.Fatalf("create: %v", err) }
+    for i := 0; i < 10; i++ {
+        if err := w.Write(ephem.Sample{TideBPS: 5000}); err != nil { t.Fatalf("write: %v", err) }
+    }
+    if err := w.Close(); err != nil { t.Fatalf("close: %v", err) }
+    f, err := NewFileGravimetric(path, "")
+    if err != nil { t.Fatalf("open: %v", err) }
+    t.Cleanup(func(){ _ = f.Close() })
+    fv, err := f.FetchAt(full)
+    if err != nil || fv.Phase != ephem.PhaseFull || fv.PhaseAngle != v.PhaseAngle { t.Fatalf("file past coverage: %+v %v", fv, err) }
+    if m, _ := (MockGravimetric{}).Fetch(context.Background()); m.Phase == "" { t.Fatalf("mock has no phase: %+v", m) }
+}
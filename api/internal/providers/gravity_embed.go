@@ -0,0 +1,71 @@
+package providers
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "io/fs"
+    "path"
+
+    "github.com/Jthora/autoBotTrader/api/internal/ephem"
+)
+
+// staticSource is a Pyramid that never changes, e.g. one embedded in the binary.
+type staticSource struct{ *ephem.Pyramid }
+
+func (staticSource) Refresh() (bool, error) { return false, nil }
+
+// NewFileGravimetricFS serves every *.bin at the root of fsys (typically
+// ephemdata.FS, a dataset embedded with go:embed) as one pyramid. Tables are
+// read in place through io.ReaderAt where fsys supports it. The dataset id
+// falls back to embedded provenance, then to gtab.meta.json in fsys.
+func NewFileGravimetricFS(fsys fs.FS, datasetID string) (*FileGravimetric, error) {
+    names, err := fs.Glob(fsys, "*.bin")
+    if err != nil { return nil, err }
+    if len(names) == 0 { return nil, fmt.Errorf("no *.bin tables in embedded dataset") }
+    opts := envOptions()
+    var tables []*ephem.GTAB
+    for _, name := range names {
+        g, err := openFS(fsys, name, opts)
+        if err != nil {
+            for _, t := range tables { t.Close() }
+            return nil, err
+        }
+        tables = append(tables, g)
+    }
+    pyr, err := ephem.NewPyramid(tables...)
+    if err != nil { return nil, err }
+    if datasetID == "" {
+        if prov, ok := pyr.Levels()[0].Provenance(); ok { datasetID = prov.DatasetID }
+    }
+    if datasetID == "" {
+        if b, err := fs.ReadFile(fsys, "gtab.meta.json"); err == nil {
+            var meta struct{ DatasetID string `json:"dataset_id"` }
+            if json.Unmarshal(b, &meta) == nil { datasetID = meta.DatasetID }
+        }
+    }
+    // metaDir "" disables the on-disk meta lookup; the id is fixed here
+    return newFileGravimetric(staticSource{pyr}, path.Base(names[0]), "", datasetID), nil
+}
+
+// openFS opens one table from fsys, through io.ReaderAt when the file offers
+// it (embed.FS does) and from a copy in memory otherwise.
+func openFS(fsys fs.FS, name string, opts ephem.Options) (*ephem.GTAB, error) {
+    f, err := fsys.Open(name)
+    if err != nil { return nil, err }
+    st, err := f.Stat()
+    if err != nil {
+        f.Close()
+        return nil, err
+    }
+    if ra, ok := f.(io.ReaderAt); ok {
+        // the table owns f: closing an embedded file is a no-op, others release their handle
+        g, err := ephem.OpenReadCloser(ra, f, st.Size(), name, opts)
+        if err != nil { f.Close() }
+        return g, err
+    }
+    b, err := io.ReadAll(f)
+    f.Close()
+    if err != nil { return nil, err }
+    return ephem.OpenBytes(b, name, opts)
+}
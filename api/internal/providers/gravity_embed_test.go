@@ -0,0 +1,67 @@
+package providers
+
+import (
+    "io/fs"
+    "os"
+    "path/filepath"
+    "testing"
+    "testing/fstest"
+    "time"
+)
+
+func TestFileGravimetricFS(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    fine := writeLevel(t, filepath.Join(dir, "gtab_1s.bin"), epoch, time.Second, 60, 0)
+    coarse := writeLevel(t, filepath.Join(dir, "gtab_60s.bin"), epoch, time.Minute, 600, 10000)
+    fsys := fstest.MapFS{"gtab.meta.json": {Data: []byte(`{"dataset_id": "embedded"}`)}}
+    for _, p := range []string{fine, coarse} {
+        b, err := os.ReadFile(p)
+        if err != nil { t.Fatal(err) }
+        fsys[filepath.Base(p)] = &fstest.MapFile{Data: b}
+    }
+    prov, err := NewFileGravimetricFS(fsys, "")
+    if err != nil { t.Fatalf("new: %v", err) }
+    t.Cleanup(func(){ _ = prov.Close() })
+    if prov.DatasetID() != "embedded" || prov.Name() != "gtab_1s.bin" || prov.Mode() != "file" { t.Fatalf("identity: %s %s %s", prov.DatasetID(), prov.Name(), prov.Mode()) }
+    if prov.Resolution(epoch.Add(30*time.Second)) != time.Second || prov.Resolution(epoch.Add(time.Hour)) != time.Minute { t.Fatal("pyramid routing lost") }
+    if changed, err := prov.Reload(); changed || err != nil { t.Fatalf("embedded data never reloads: %v %v", changed, err) }
+    if prov.Stale(epoch.Add(time.Hour)) { t.Fatal("unexpectedly stale") }
+
+    if _, err := NewFileGravimetricFS(fstest.MapFS{}, ""); err == nil { t.Fatal("expected error for empty FS") }
+}
+
+// countingFS opens files from a directory and counts the handles still open.
+type countingFS struct {
+    fs.FS
+    open *int
+}
+
+type countedFile struct {
+    *os.File
+    open *int
+}
+
+func (c countingFS) Open(name string) (fs.File, error) {
+    f, err := c.FS.Open(name)
+    if err != nil { return nil, err }
+    *c.open++
+    return countedFile{f.(*os.File), c.open}, nil
+}
+
+func (f countedFile) Close() error {
+    *f.open--
+    return f.File.Close()
+}
+
+func TestFileGravimetricFSClosesReaderAtFiles(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    writeLevel(t, filepath.Join(dir, "gtab_1s.bin"), epoch, time.Second, 60, 0)
+    writeLevel(t, filepath.Join(dir, "gtab_60s.bin"), epoch, time.Minute, 600, 10000)
+    open := 0
+    prov, err := NewFileGravimetricFS(countingFS{os.DirFS(dir), &open}, "x")
+    if err != nil { t.Fatalf("new: %v", err) }
+    if open != 2 { t.Fatalf("%d tables open, want 2", open) }
+    if err := prov.Close(); err != nil || open != 0 { t.Fatalf("close: %v, %d handles leaked", err, open) }
+}
@@ -3,100 +3,285 @@ package providers
 import (
     "context"
     "encoding/json"
+    "errors"
+    "fmt"
+    "log"
     "os"
     "path/filepath"
-    "strconv"
     "sync"
     "time"
 
     "github.com/Jthora/autoBotTrader/api/internal/ephem"
 )
 
-// FileGravimetric implements GravimetricProvider using a GTAB file with tide_bps.
+// tableSource is the table set behind a FileGravimetric: a fixed ephem.Pyramid
+// or a rotating ephem.Catalog.
+type tableSource interface {
+    Levels() []*ephem.GTAB
+    Select(t time.Time) (*ephem.GTAB, bool)
+    Coverage() (start, end time.Time)
+    LookupTideBPS(t time.Time) (uint16, time.Duration, bool)
+    LookupTideBPSMany(ts []time.Time, out []uint16) int
+    LookupSample(t time.Time) (ephem.Sample, time.Duration, bool)
+    TideAt(t time.Time) (float64, bool)
+    Refresh() (changed bool, err error)
+    Close() error
+}
+
+// FileGravimetric implements GravimetricProvider using one or more GTAB files with tide_bps.
+// With several files (e.g. 1s + 60s) each lookup is answered by the finest table covering it.
+// Watch reloads replaced tables and gtab.meta.json without a restart.
 type FileGravimetric struct {
-    name      string
-    mode      string
+    name    string
+    mode    string
+    fixedID string // dataset id given by the caller; overrides provenance and meta
+    metaDir string // directory holding gtab.meta.json; "" when there is none on disk
+    src     tableSource
+    hys     *bpsHysteresis
+
+    mu        sync.RWMutex
     datasetID string
-    gtab      *ephem.GTAB
-    start     time.Time
-    end       time.Time
-    // hysteresis in basis points; if 0, no hysteresis
-    hysteresis uint16
-    // last value cached for hysteresis; 65535 denotes "unset"
-    lastBPS uint16
-    mu sync.Mutex // protects lastBPS and hysteresis-related decisions
+    metaInfo  os.FileInfo // stat of gtab.meta.json when last read; nil if absent
+    stop      chan struct{}
+    done      chan struct{}
+    closed    bool
 }
 
 // NewFileGravimetric opens the given GTAB file and returns a provider.
 // datasetID is a human-readable id from meta JSON; may be empty.
+// EPHEM_INTERP selects nearest|linear|hermite interpolation (default linear).
 func NewFileGravimetric(path string, datasetID string) (*FileGravimetric, error) {
-    g, err := ephem.Open(path)
+    return NewFileGravimetricPyramid([]string{path}, datasetID)
+}
+
+// NewFileGravimetricPyramid opens several cadences of the same dataset (e.g.
+// gtab_1s.bin and gtab_60s.bin) as one provider. The provider is named after
+// and reads gtab.meta.json beside the first path.
+func NewFileGravimetricPyramid(paths []string, datasetID string) (*FileGravimetric, error) {
+    if len(paths) == 0 { return nil, errors.New("no GTAB paths") }
+    src, err := openPyramidSource(paths, envOptions())
     if err != nil { return nil, err }
-    s, e := g.Coverage()
-    hys := uint16(0)
-    if v := os.Getenv("HYSTERESIS_BPS"); v != "" {
-        if n, err := strconv.Atoi(v); err == nil && n >= 0 && n <= 10000 { hys = uint16(n) }
-    }
-    if datasetID == "" {
-        // Try to read sibling gtab.meta.json
-        metaPath := filepath.Join(filepath.Dir(path), "gtab.meta.json")
-        if b, err := os.ReadFile(metaPath); err == nil {
-            var meta struct{ DatasetID string `json:"dataset_id"` }
-            if json.Unmarshal(b, &meta) == nil && meta.DatasetID != "" {
-                datasetID = meta.DatasetID
-            }
-        }
+    return newFileGravimetric(src, filepath.Base(paths[0]), filepath.Dir(paths[0]), datasetID), nil
+}
+
+// NewFileGravimetricCatalog serves every table in dir as one rotating dataset
+// (see ephem.Catalog); call Watch to pick up new tables without a restart.
+// The provider is named after dir and reads gtab.meta.json inside it.
+func NewFileGravimetricCatalog(dir string, datasetID string) (*FileGravimetric, error) {
+    cat, err := ephem.OpenCatalog(dir, envOptions())
+    if err != nil { return nil, err }
+    return newFileGravimetric(cat, filepath.Base(dir), dir, datasetID), nil
+}
+
+// envOptions reads table options from the environment (EPHEM_INTERP). An
+// invalid mode is logged and linear interpolation used.
+func envOptions() ephem.Options {
+    var opts ephem.Options
+    if v := os.Getenv("EPHEM_INTERP"); v != "" {
+        if m, err := ephem.ParseInterp(v); err == nil { opts.Interp = m } else { log.Printf("[ephem] EPHEM_INTERP=%q ignored: %v — using linear", v, err) }
     }
-    return &FileGravimetric{
-        name: filepath.Base(path),
+    return opts
+}
+
+func newFileGravimetric(src tableSource, name, metaDir, datasetID string) *FileGravimetric {
+    f := &FileGravimetric{
+        name: name,
         mode: "file",
-        datasetID: datasetID,
-        gtab: g,
-        start: s,
-        end: e,
-    hysteresis: hys,
-    lastBPS: 65535,
-    }, nil
+        fixedID: datasetID,
+        metaDir: metaDir,
+        src: src,
+        hys: newHysteresisFromEnv(),
+    }
+    f.datasetID, f.metaInfo = f.resolveDatasetID()
+    return f
+}
+
+// resolveDatasetID picks the dataset id: the caller's, else the embedded
+// provenance of the finest table (GTAB v2+), else the sibling gtab.meta.json.
+// It also returns the meta file's stat so Watch can detect edits.
+func (f *FileGravimetric) resolveDatasetID() (string, os.FileInfo) {
+    if f.metaDir == "" { return f.fixedID, nil }
+    metaPath := filepath.Join(f.metaDir, "gtab.meta.json")
+    info, _ := os.Stat(metaPath)
+    if f.fixedID != "" { return f.fixedID, info }
+    if levels := f.src.Levels(); len(levels) > 0 {
+        if prov, ok := levels[0].Provenance(); ok && prov.DatasetID != "" { return prov.DatasetID, info }
+    }
+    if b, err := os.ReadFile(metaPath); err == nil {
+        var meta struct{ DatasetID string `json:"dataset_id"` }
+        if json.Unmarshal(b, &meta) == nil { return meta.DatasetID, info }
+    }
+    return "", info
 }
 
 func (f *FileGravimetric) Name() string { return f.name }
 func (f *FileGravimetric) Mode() string { return f.mode }
-func (f *FileGravimetric) DatasetID() string { return f.datasetID }
-func (f *FileGravimetric) Stale(now time.Time) bool { return now.Before(f.start) || now.After(f.end) }
+func (f *FileGravimetric) DatasetID() string {
+    f.mu.RLock()
+    defer f.mu.RUnlock()
+    return f.datasetID
+}
+// Coverage returns the UTC window the tables cover.
+func (f *FileGravimetric) Coverage() (start, end time.Time) { return f.src.Coverage() }
+func (f *FileGravimetric) Stale(now time.Time) bool {
+    start, end := f.src.Coverage()
+    return now.Before(start) || now.After(end)
+}
+
+// Watch polls the tables (the file paths, or a catalog's directory) and
+// gtab.meta.json every interval until Close. Replaced tables are opened and
+// verified before they are swapped in; if that fails the previous tables keep
+// serving. Tables are mapped, so replace them by rename (as ephem.Create and
+// scripts/ephem/generate.py do), never by rewriting in place. notify, if
+// non-nil, is called after each poll that changed something or failed.
+func (f *FileGravimetric) Watch(every time.Duration, notify func(changed bool, err error)) {
+    if f == nil || f.src == nil { return }
+    f.mu.Lock()
+    if f.stop != nil || f.closed {
+        f.mu.Unlock()
+        return
+    }
+    f.stop, f.done = make(chan struct{}), make(chan struct{})
+    stop, done := f.stop, f.done
+    f.mu.Unlock()
+    go func() {
+        defer close(done)
+        tick := time.NewTicker(every)
+        defer tick.Stop()
+        for {
+            select {
+            case <-stop:
+                return
+            case <-tick.C:
+                changed, err := f.Reload()
+                if notify != nil && (changed || err != nil) { notify(changed, err) }
+            }
+        }
+    }()
+}
+
+// Reload checks the tables and gtab.meta.json once and swaps in whatever
+// changed; Watch calls it on every tick.
+func (f *FileGravimetric) Reload() (changed bool, err error) {
+    changed, err = f.src.Refresh()
+    f.mu.RLock()
+    metaInfo := f.metaInfo
+    f.mu.RUnlock()
+    var info os.FileInfo
+    if f.metaDir != "" { info, _ = os.Stat(filepath.Join(f.metaDir, "gtab.meta.json")) }
+    if !changed && ephem.SameFile(metaInfo, info) { return false, err }
+    id, info := f.resolveDatasetID()
+    f.mu.Lock()
+    f.datasetID, f.metaInfo = id, info
+    f.mu.Unlock()
+    return true, err
+}
+
+// Interp reports the effective interpolation mode of the finest table.
+func (f *FileGravimetric) Interp() ephem.Interp {
+    if f == nil || f.src == nil { return ephem.InterpLinear }
+    levels := f.src.Levels()
+    if len(levels) == 0 { return ephem.InterpLinear }
+    return levels[0].Interp()
+}
+
+// Resolution reports the step of the table that answers (or, out of range,
+// is clamped to) a fetch at time at; 0 if none can.
+func (f *FileGravimetric) Resolution(at time.Time) time.Duration {
+    if f == nil || f.src == nil { return 0 }
+    if g, ok := f.src.Select(f.clamp(at)); ok { return g.Header().Step }
+    return 0
+}
+
+// clamp pins at to the overall coverage so out-of-range fetches repeat the edge value.
+func (f *FileGravimetric) clamp(at time.Time) time.Time {
+    start, end := f.src.Coverage()
+    if at.Before(start) { return start }
+    if at.After(end) { return end }
+    return at
+}
 
 func (f *FileGravimetric) Fetch(ctx context.Context) (GravimetricData, error) {
-    if f == nil || f.gtab == nil { return GravimetricData{}, context.Canceled }
+    if f == nil || f.src == nil { return GravimetricData{}, context.Canceled }
     select { case <-ctx.Done(): return GravimetricData{}, ctx.Err(); default: }
     return f.FetchAt(time.Now().UTC())
 }
 
 // FetchAt fetches using a specific timestamp (testability and determinism). Not part of the interface.
+// A gap in the dataset at the (clamped) time yields an error wrapping ErrNoData.
 func (f *FileGravimetric) FetchAt(at time.Time) (GravimetricData, error) {
-    if f == nil || f.gtab == nil { return GravimetricData{}, context.Canceled }
-    bps, ok := f.gtab.LookupTideBPS(at)
-    if !ok {
-        if at.Before(f.start) {
-            if v, ok := f.gtab.LookupTideBPS(f.start); ok { bps = v } else { bps = 0 }
-        } else {
-            if v, ok := f.gtab.LookupTideBPS(f.end); ok { bps = v } else { bps = 0 }
+    if f == nil || f.src == nil { return GravimetricData{}, context.Canceled }
+    bps, _, ok := f.src.LookupTideBPS(f.clamp(at))
+    if !ok { return GravimetricData{}, fmt.Errorf("%s: %w at %s", f.name, ErrNoData, at.Format(time.RFC3339)) }
+    // the phase follows at itself, not the clamped table time
+    return withPhase(GravimetricData{LunarTideForce: tideForce(f.hys.apply(bps))}, at), nil
+}
+
+// FetchMany is FetchAt for many timestamps (e.g. a backtest): out[k] receives
+// the data at ts[k]. Tables are read in batches (see
+// ephem.GTAB.LookupTideBPSMany) and the lunar phase is interpolated between
+// hourly analytic values instead of computed per point. Hysteresis is replayed over the batch in the
+// order given, starting from a fresh state, so the result neither depends on
+// nor changes what Fetch serves. Points without a valid sample (gaps) are left
+// zero and the first is reported in an error wrapping ErrNoData. out must be at least as long as ts.
+func (f *FileGravimetric) FetchMany(ts []time.Time, out []GravimetricData) error {
+    if f == nil || f.src == nil { return context.Canceled }
+    out = out[:len(ts)]
+    clamped := make([]time.Time, len(ts))
+    for k, at := range ts { clamped[k] = f.clamp(at) }
+    bps := make([]uint16, len(ts))
+    f.src.LookupTideBPSMany(clamped, bps)
+    hys := f.hys.replay()
+    phases := phaseKnots{}
+    var err error
+    for k, v := range bps {
+        if v == ephem.MissingBPS {
+            out[k] = GravimetricData{}
+            if err == nil { err = fmt.Errorf("%s: %w at %s", f.name, ErrNoData, ts[k].Format(time.RFC3339)) }
+            continue
         }
+        out[k] = setPhase(GravimetricData{LunarTideForce: tideForce(hys.apply(v))}, phases.at(ts[k]))
     }
-    f.mu.Lock()
-    if f.hysteresis > 0 && f.lastBPS != 65535 {
-        if bps > f.lastBPS {
-            if bps - f.lastBPS < f.hysteresis { bps = f.lastBPS }
-        } else if f.lastBPS > bps {
-            if f.lastBPS - bps < f.hysteresis { bps = f.lastBPS }
-        }
+    return err
+}
+
+// TideBPSMany looks up tide_bps for many timestamps without
+// clamping, hysteresis or phase: the fast path for backtests that only need the
+// signal. Unanswered slots hold ephem.MissingBPS; it returns the number answered.
+func (f *FileGravimetric) TideBPSMany(ts []time.Time, out []uint16) int {
+    if f == nil || f.src == nil {
+        for k := range ts { out[k] = ephem.MissingBPS }
+        return 0
     }
-    f.lastBPS = bps
-    f.mu.Unlock()
-    tide := 80.0 + (float64(bps) / 10000.0) * 50.0
-    return GravimetricData{LunarTideForce: tide}, nil
+    return f.src.LookupTideBPSMany(ts, out)
 }
 
-// Close releases underlying resources (file handles).
+// TideAt returns unrounded tide_bps at t without clamping or hysteresis, for
+// ephem.FindEvents; ok=false outside coverage.
+func (f *FileGravimetric) TideAt(at time.Time) (float64, bool) {
+    if f == nil || f.src == nil { return 0, false }
+    return f.src.TideAt(at)
+}
+
+// SampleAt returns every field of the dataset at t, interpolated, without
+// clamping or hysteresis; ok=false outside coverage.
+func (f *FileGravimetric) SampleAt(at time.Time) (ephem.Sample, bool) {
+    if f == nil || f.src == nil { return ephem.Sample{}, false }
+    s, _, ok := f.src.LookupSample(at)
+    return s, ok
+}
+
+// Close stops Watch and releases underlying resources (file handles).
 func (f *FileGravimetric) Close() error {
-    if f != nil && f.gtab != nil { return f.gtab.Close() }
-    return nil
+    if f == nil || f.src == nil { return nil }
+    f.mu.Lock()
+    stop, done := f.stop, f.done
+    f.stop = nil
+    f.closed = true
+    f.mu.Unlock()
+    if stop != nil {
+        close(stop)
+        <-done
+    }
+    return f.src.Close()
 }
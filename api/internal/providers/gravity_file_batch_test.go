@@ -0,0 +1,122 @@
+package providers
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jthora/autoBotTrader/api/internal/ephem"
+)
+
+// writeRamp writes n records of tide_bps stepping by inc every step.
+func writeRamp(t *testing.T, path string, epoch time.Time, step time.Duration, n int, inc int) string {
+    t.Helper()
+    w, err := ephem.Create(path, ephem.Header{Epoch: epoch, Step: step, Fields: ephem.FieldTideBPS})
+    if err != nil { t.Fatalf("create: %v", err) }
+    for i := 0; i < n; i++ {
+        if err := w.Write(ephem.Sample{TideBPS: uint16((i * inc) % 10001)}); err != nil { t.Fatalf("write: %v", err) }
+    }
+    if err := w.Close(); err != nil { t.Fatalf("close: %v", err) }
+    return path
+}
+
+// samePhase compares batch data to FetchAt: the tide exactly, the phase within
+// the interpolation error of phaseKnots.
+func samePhase(got, want GravimetricData) bool {
+    return got.LunarTideForce == want.LunarTideForce && got.Phase == ephem.PhaseName(got.PhaseAngle) &&
+        math.Abs(got.PhaseAngle-want.PhaseAngle) < 1e-3 && math.Abs(got.Illumination-want.Illumination) < 1e-5
+}
+
+func TestPhaseKnotsError(t *testing.T) {
+    k := phaseKnots{}
+    start := time.Date(2025,1,1,0,0,0,0,time.UTC)
+    for at := start; at.Before(start.Add(400 * 24 * time.Hour)); at = at.Add(17*time.Minute + 13*time.Second) {
+        got, want := k.at(at), ephem.PhaseAt(at)
+        d := math.Abs(got.Angle - want.Angle)
+        if d > 180 { d = 360 - d }
+        if d > 1e-3 || math.Abs(got.Illumination-want.Illumination) > 1e-5 { t.Fatalf("at %s: %+v, want %+v", at, got, want) }
+    }
+    if p := k.at(start); p != ephem.PhaseAt(start) { t.Fatalf("knot: %+v", p) }
+    if before := time.Date(1969,12,31,23,30,0,0,time.UTC); math.Abs(k.at(before).Angle-ephem.PhaseAt(before).Angle) > 1e-3 { t.Fatal("before 1970") }
+}
+
+func TestFetchManyMatchesFetchAt(t *testing.T) {
+    t.Setenv("HYSTERESIS_BPS", "")
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    dir := t.TempDir()
+    fine := writeRamp(t, filepath.Join(dir, "gtab_1s.bin"), epoch, time.Second, 3600, 3)
+    coarse := writeRamp(t, filepath.Join(dir, "gtab_60s.bin"), epoch, time.Minute, 24*60, 7)
+    f, err := NewFileGravimetricPyramid([]string{fine, coarse}, "batch")
+    if err != nil { t.Fatalf("open: %v", err) }
+    t.Cleanup(func(){ _ = f.Close() })
+    var ts []time.Time
+    for at := epoch.Add(-time.Hour); at.Before(epoch.Add(26 * time.Hour)); at = at.Add(97 * time.Second) {
+        ts = append(ts, at)
+    }
+    ts[0], ts[len(ts)-1] = ts[len(ts)-1], ts[0] // unsorted
+    out := make([]GravimetricData, len(ts))
+    if err := f.FetchMany(ts, out); err != nil { t.Fatalf("fetch many: %v", err) }
+    bps := make([]uint16, len(ts))
+    n := f.TideBPSMany(ts, bps)
+    answered := 0
+    for k, at := range ts {
+        want, err := f.FetchAt(at)
+        if err != nil { t.Fatalf("fetch at %s: %v", at, err) }
+        if !samePhase(out[k], want) { t.Fatalf("at %s: batch %+v, single %+v", at, out[k], want) }
+        if v, ok := f.TideAt(at); ok {
+            answered++
+            if bps[k] == ephem.MissingBPS || float64(bps[k]) < v-1 || float64(bps[k]) > v+1 { t.Fatalf("tide_bps at %s: %d vs %.1f", at, bps[k], v) }
+        } else if bps[k] != ephem.MissingBPS {
+            t.Fatalf("tide_bps outside coverage at %s: %d", at, bps[k])
+        }
+    }
+    if n != answered { t.Fatalf("answered %d, want %d", n, answered) }
+}
+
+func TestFetchManyLeavesHysteresisAlone(t *testing.T) {
+    t.Setenv("HYSTERESIS_BPS", "50")
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    path := writeRamp(t, filepath.Join(t.TempDir(), "gtab_60s.bin"), epoch, time.Minute, 600, 10)
+    f, err := NewFileGravimetric(path, "")
+    if err != nil { t.Fatalf("open: %v", err) }
+    t.Cleanup(func(){ _ = f.Close() })
+    ref, err := NewFileGravimetric(path, "")
+    if err != nil { t.Fatalf("open: %v", err) }
+    t.Cleanup(func(){ _ = ref.Close() })
+
+    live, _ := f.FetchAt(epoch.Add(100 * time.Minute))
+    ts := make([]time.Time, 300)
+    for k := range ts { ts[k] = epoch.Add(time.Duration(k) * time.Minute) }
+    out := make([]GravimetricData, len(ts))
+    if err := f.FetchMany(ts, out); err != nil { t.Fatalf("fetch many: %v", err) }
+    // the batch replays hysteresis like a fresh provider fetching in order
+    for k, at := range ts {
+        want, _ := ref.FetchAt(at)
+        if !samePhase(out[k], want) { t.Fatalf("at %s: batch %+v, sequential %+v", at, out[k], want) }
+    }
+    // the live state still holds the value served before the batch: 4 bps up is inside the band
+    if next, _ := f.FetchAt(epoch.Add(100*time.Minute + 24*time.Second)); next.LunarTideForce != live.LunarTideForce { t.Fatalf("live hysteresis disturbed: %v then %v", live, next) }
+}
+
+func BenchmarkFileGravimetricFetchMany(b *testing.B) {
+    path := benchWriteGTAB(b, 24*60*60, 1_000_000_000) // 24h @1s
+    fg, err := NewFileGravimetric(path, "bench_ds")
+    if err != nil { b.Fatalf("new file provider: %v", err) }
+    defer fg.Close()
+    start, _ := fg.src.Coverage()
+    ts := make([]time.Time, 100_000)
+    for k := range ts { ts[k] = start.Add(time.Duration(k) * 750 * time.Millisecond) }
+    out := make([]GravimetricData, len(ts))
+    bps := make([]uint16, len(ts))
+    b.Run("FetchMany", func(b *testing.B) {
+        for i := 0; i < b.N; i++ {
+            if err := fg.FetchMany(ts, out); err != nil { b.Fatalf("fetch many: %v", err) }
+        }
+    })
+    b.Run("TideBPSMany", func(b *testing.B) {
+        for i := 0; i < b.N; i++ {
+            if n := fg.TideBPSMany(ts, bps); n != len(ts) { b.Fatalf("answered %d", n) }
+        }
+    })
+}
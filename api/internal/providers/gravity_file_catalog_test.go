@@ -0,0 +1,30 @@
+package providers
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileGravimetricCatalogRollover(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    writeLevel(t, filepath.Join(dir, "2025-08.bin"), epoch, time.Minute, 60, 0)
+    writeMeta(t, dir, "rotating")
+    prov, err := NewFileGravimetricCatalog(dir, "")
+    if err != nil { t.Fatalf("new: %v", err) }
+    t.Cleanup(func(){ _ = prov.Close() })
+    if prov.DatasetID() != "rotating" || prov.Name() != filepath.Base(dir) { t.Fatalf("identity: %s %s", prov.DatasetID(), prov.Name()) }
+    later := epoch.Add(90 * time.Minute)
+    if !prov.Stale(later) { t.Fatal("expected stale before rotation") }
+    writeLevel(t, filepath.Join(dir, "2025-09.bin"), epoch.Add(time.Hour), time.Minute, 60, 10000)
+    changed := make(chan struct{}, 1)
+    prov.Watch(5*time.Millisecond, func(ok bool, err error) { if ok { select { case changed <- struct{}{}: default: } } })
+    select {
+    case <-changed:
+    case <-time.After(2 * time.Second):
+        t.Fatal("rotation not picked up")
+    }
+    if prov.Stale(later) { t.Fatal("new table should keep provider fresh") }
+    if v, err := prov.FetchAt(later); err != nil || v.LunarTideForce != 130 { t.Fatalf("fetch after rotation: %v %v", v, err) }
+}
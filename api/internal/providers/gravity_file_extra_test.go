@@ -1,9 +1,13 @@
 package providers
 
 import (
+    "bytes"
     "encoding/binary"
+    "errors"
+    "log"
     "os"
     "path/filepath"
+    "strings"
     "testing"
     "time"
     "github.com/Jthora/autoBotTrader/api/internal/ephem"
@@ -55,6 +59,23 @@ func TestOutOfRangeClampingAndStale(t *testing.T) {
     if !prov.Stale(time.Unix(epoch-10,0).UTC()) || !prov.Stale(time.Unix(epoch+100,0).UTC()) { t.Fatalf("stale flag logic incorrect") }
 }
 
+func TestFetchInGapReportsNoData(t *testing.T) {
+    t.Setenv("HYSTERESIS_BPS", "")
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC).Unix()
+    path := writeGTAB(t, dir, "gap.bin", epoch, 1_000_000_000, []uint16{2000, ephem.MissingBPS, 4000, 5000})
+    prov, err := NewFileGravimetric(path, "")
+    if err != nil { t.Fatalf("new: %v", err) }
+    for _, ms := range []int64{1000, 500, 1500} { // the gap and both intervals touching it
+        at := time.UnixMilli(epoch*1000 + ms).UTC()
+        if _, err := prov.FetchAt(at); !errors.Is(err, ErrNoData) { t.Fatalf("fetch at %s: expected ErrNoData, got %v", at, err) }
+    }
+    if d, err := prov.FetchAt(time.Unix(epoch+3, 0).UTC()); err != nil || d.LunarTideForce == 0 { t.Fatalf("fetch beside gap: %+v %v", d, err) }
+    ts := []time.Time{time.Unix(epoch, 0).UTC(), time.Unix(epoch+1, 0).UTC()}
+    out := make([]GravimetricData, len(ts))
+    if err := prov.FetchMany(ts, out); !errors.Is(err, ErrNoData) || out[0].LunarTideForce == 0 || out[1] != (GravimetricData{}) { t.Fatalf("fetch many: %v %+v", err, out) }
+}
+
 func TestDatasetIDResolutionModes(t *testing.T) {
     dir := t.TempDir()
     epoch := time.Now().UTC().Unix()
@@ -71,3 +92,31 @@ func TestDatasetIDResolutionModes(t *testing.T) {
     p3, _ := NewFileGravimetric(path, "")
     if p3.DatasetID() != "" { t.Fatalf("malformed meta should yield empty id: %s", p3.DatasetID()) }
 }
+
+func TestInterpModeFromEnv(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC).Unix()
+    path := writeGTAB(t, dir, "interp.bin", epoch, 1_000_000_000, []uint16{1000, 2000})
+    t.Setenv("EPHEM_INTERP", "nearest")
+    prov, err := NewFileGravimetric(path, "")
+    if err != nil { t.Fatalf("new: %v", err) }
+    t.Cleanup(func(){ _ = prov.Close() })
+    if prov.Interp() != ephem.InterpNearest { t.Fatalf("interp mode: %v", prov.Interp()) }
+    v, _ := prov.FetchAt(time.Unix(epoch, 900_000_000).UTC())
+    last, _ := prov.FetchAt(time.Unix(epoch+1, 0).UTC())
+    if v.LunarTideForce != last.LunarTideForce { t.Fatalf("nearest should snap to next sample: %v vs %v", v, last) }
+}
+
+func TestInvalidInterpModeIsLogged(t *testing.T) {
+    dir := t.TempDir()
+    path := writeGTAB(t, dir, "interp.bin", time.Date(2025,8,1,0,0,0,0,time.UTC).Unix(), 1_000_000_000, []uint16{1000, 2000})
+    var buf bytes.Buffer
+    log.SetOutput(&buf)
+    t.Cleanup(func(){ log.SetOutput(os.Stderr) })
+    t.Setenv("EPHEM_INTERP", "hermit")
+    prov, err := NewFileGravimetric(path, "")
+    if err != nil { t.Fatalf("new: %v", err) }
+    t.Cleanup(func(){ _ = prov.Close() })
+    if prov.Interp() != ephem.InterpLinear { t.Fatalf("interp mode: %v", prov.Interp()) }
+    if !strings.Contains(buf.String(), `EPHEM_INTERP="hermit" ignored`) { t.Fatalf("rejected mode not logged: %q", buf.String()) }
+}
@@ -0,0 +1,53 @@
+package providers
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jthora/autoBotTrader/api/internal/ephem"
+)
+
+func writeLevel(t *testing.T, path string, epoch time.Time, step time.Duration, n int, bps uint16) string {
+    t.Helper()
+    w, err := ephem.Create(path, ephem.Header{Epoch: epoch, Step: step, Fields: ephem.FieldTideBPS})
+    if err != nil { t.Fatalf("create: %v", err) }
+    for i := 0; i < n; i++ {
+        if err := w.Write(ephem.Sample{TideBPS: bps}); err != nil { t.Fatalf("write: %v", err) }
+    }
+    if err := w.Close(); err != nil { t.Fatalf("close: %v", err) }
+    return path
+}
+
+func TestFileGravimetricPyramidFallsBackToCoarse(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    fine := writeLevel(t, filepath.Join(dir, "gtab_1s.bin"), epoch, time.Second, 60, 0)
+    coarse := writeLevel(t, filepath.Join(dir, "gtab_60s.bin"), epoch, time.Minute, 600, 10000)
+    prov, err := NewFileGravimetricPyramid([]string{fine, coarse}, "pyr")
+    if err != nil { t.Fatalf("new: %v", err) }
+    t.Cleanup(func(){ _ = prov.Close() })
+    if prov.Name() != "gtab_1s.bin" { t.Fatalf("name: %s", prov.Name()) }
+    in1s := epoch.Add(30 * time.Second)
+    if r := prov.Resolution(in1s); r != time.Second { t.Fatalf("resolution in 1s window: %v", r) }
+    if v, _ := prov.FetchAt(in1s); v.LunarTideForce != 80 { t.Fatalf("1s value: %v", v) }
+    later := epoch.Add(5 * time.Hour)
+    if prov.Stale(later) { t.Fatal("coarse level should keep provider fresh") }
+    if r := prov.Resolution(later); r != time.Minute { t.Fatalf("resolution beyond 1s window: %v", r) }
+    if v, _ := prov.FetchAt(later); v.LunarTideForce != 130 { t.Fatalf("60s value: %v", v) }
+    if !prov.Stale(epoch.Add(11 * time.Hour)) { t.Fatal("expected stale beyond coarse coverage") }
+}
+
+func TestFileGravimetricPrefersEmbeddedProvenance(t *testing.T) {
+    dir := t.TempDir()
+    path := filepath.Join(dir, "gtab_1s.bin")
+    w, err := ephem.CreateWithOptions(path, ephem.Header{Epoch: time.Unix(1_726_000_000, 0), Step: time.Second, Fields: ephem.FieldTideBPS}, ephem.WriteOptions{Version: 2, Provenance: ephem.Provenance{DatasetID: "embedded"}})
+    if err != nil { t.Fatalf("create: %v", err) }
+    w.Write(ephem.Sample{TideBPS: 1})
+    if err := w.Close(); err != nil { t.Fatalf("close: %v", err) }
+    writeMeta(t, dir, "sibling")
+    prov, err := NewFileGravimetric(path, "")
+    if err != nil { t.Fatalf("new: %v", err) }
+    t.Cleanup(func(){ _ = prov.Close() })
+    if prov.DatasetID() != "embedded" { t.Fatalf("dataset id: %s", prov.DatasetID()) }
+}
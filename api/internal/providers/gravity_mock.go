@@ -2,14 +2,67 @@ package providers
 
 import (
 	"context"
+	"errors"
+	"math"
 	"math/rand"
 	"time"
+
+	"github.com/Jthora/autoBotTrader/api/internal/ephem"
 )
 
 type GravimetricData struct {
     LunarTideForce float64 `json:"lunar_tide_force"`
+    // Lunar phase at the fetch time (see ephem.PhaseAt); empty when the
+    // provider does not compute it.
+    Phase        string  `json:"phase,omitempty"`         // ephem.Phase* name
+    PhaseAngle   float64 `json:"phase_angle,omitempty"`   // Moon−Sun elongation, degrees [0, 360)
+    Illumination float64 `json:"illumination,omitempty"`  // illuminated fraction, 0..1
+}
+
+// withPhase fills the lunar phase fields of d for instant at. Phase depends on
+// geometry only, so it is computed analytically even for table-backed data.
+func withPhase(d GravimetricData, at time.Time) GravimetricData {
+    return setPhase(d, ephem.PhaseAt(at))
 }
 
+func setPhase(d GravimetricData, p ephem.LunarPhase) GravimetricData {
+    d.Phase, d.PhaseAngle, d.Illumination = p.Name, p.Angle, p.Illumination
+    return d
+}
+
+// phaseKnots serves lunar phases to batches: PhaseAt is evaluated once per
+// whole hour and instants between are interpolated linearly. The elongation
+// advances about 0.5° an hour and bends so slowly that the interpolation
+// error stays below 0.001° (and 1e-5 in illumination).
+type phaseKnots map[int64]ephem.LunarPhase
+
+func (k phaseKnots) knot(h int64) ephem.LunarPhase {
+    p, ok := k[h]
+    if !ok {
+        p = ephem.PhaseAt(time.Unix(h*3600, 0).UTC())
+        k[h] = p
+    }
+    return p
+}
+
+func (k phaseKnots) at(t time.Time) ephem.LunarPhase {
+    h := t.Unix() / 3600
+    if t.Unix() < 0 && t.Unix()%3600 != 0 { h-- }
+    frac := float64(t.Sub(time.Unix(h*3600, 0))) / float64(time.Hour)
+    p0 := k.knot(h)
+    if frac == 0 { return p0 }
+    p1 := k.knot(h + 1)
+    d := math.Mod(p1.Angle-p0.Angle+540, 360) - 180
+    angle := math.Mod(p0.Angle+d*frac+360, 360)
+    return ephem.LunarPhase{Angle: angle, Illumination: p0.Illumination + (p1.Illumination-p0.Illumination)*frac, Name: ephem.PhaseName(angle)}
+}
+
+// ErrNoData is wrapped by Fetch errors when the dataset has no sample for the
+// requested time (a gap in the table, or an unreadable record). Callers can
+// test for it with errors.Is and treat the signal as degraded rather than
+// failed.
+var ErrNoData = errors.New("no gravimetric data")
+
 type GravimetricProvider interface {
     Name() string
     Fetch(ctx context.Context) (GravimetricData, error)
@@ -29,5 +82,5 @@ func (m MockGravimetric) Stale(now time.Time) bool { return false }
 
 func (m MockGravimetric) Fetch(ctx context.Context) (GravimetricData, error) {
     rand.Seed(time.Now().UnixNano())
-    return GravimetricData{LunarTideForce: 80 + rand.Float64()*50}, nil
+    return withPhase(GravimetricData{LunarTideForce: 80 + rand.Float64()*50}, time.Now().UTC()), nil
 }
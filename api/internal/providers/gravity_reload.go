@@ -0,0 +1,148 @@
+package providers
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "sync"
+    "time"
+
+    "github.com/Jthora/autoBotTrader/api/internal/ephem"
+)
+
+// pyramidSource serves a fixed list of table paths and reopens them when any
+// file is replaced (new inode, size or mtime). Lookups hold the read lock for
+// their whole duration, so a retired pyramid is closed only after in-flight
+// lookups have finished.
+type pyramidSource struct {
+    paths []string
+    opts  ephem.Options
+
+    refreshMu sync.Mutex // serializes Refresh
+    mu        sync.RWMutex
+    pyr       *ephem.Pyramid // nil after Close
+    infos     []os.FileInfo  // stat of each path when pyr was opened
+}
+
+func openPyramidSource(paths []string, opts ephem.Options) (*pyramidSource, error) {
+    infos, err := statAll(paths)
+    if err != nil { return nil, err }
+    pyr, err := openValidated(paths, opts)
+    if err != nil { return nil, err }
+    return &pyramidSource{paths: paths, opts: opts, pyr: pyr, infos: infos}, nil
+}
+
+// openValidated opens paths as a pyramid and checks every embedded checksum,
+// so a truncated or corrupt replacement is rejected before it serves.
+func openValidated(paths []string, opts ephem.Options) (*ephem.Pyramid, error) {
+    pyr, err := ephem.OpenPyramid(paths, opts)
+    if err != nil { return nil, err }
+    for _, g := range pyr.Levels() {
+        if err := g.Verify(); err != nil && !errors.Is(err, ephem.ErrNoChecksums) {
+            pyr.Close()
+            return nil, err
+        }
+    }
+    return pyr, nil
+}
+
+func statAll(paths []string) ([]os.FileInfo, error) {
+    infos := make([]os.FileInfo, len(paths))
+    for i, p := range paths {
+        fi, err := os.Stat(p)
+        if err != nil { return nil, err }
+        infos[i] = fi
+    }
+    return infos, nil
+}
+
+// Refresh reopens the tables if any path changed on disk. The new set is
+// swapped in only if every file opens and verifies; otherwise the previous
+// set keeps serving and the error is returned.
+func (s *pyramidSource) Refresh() (changed bool, err error) {
+    s.refreshMu.Lock()
+    defer s.refreshMu.Unlock()
+    infos, err := statAll(s.paths)
+    if err != nil { return false, err }
+    s.mu.RLock()
+    old, closed := s.pyr, s.pyr == nil
+    unchanged := true
+    for i := range infos {
+        if !ephem.SameFile(s.infos[i], infos[i]) { unchanged = false }
+    }
+    s.mu.RUnlock()
+    if closed { return false, errors.New("tables closed") }
+    if unchanged { return false, nil }
+    pyr, err := openValidated(s.paths, s.opts)
+    if err != nil { return false, fmt.Errorf("reload rejected, previous tables keep serving: %w", err) }
+    s.mu.Lock()
+    s.pyr, s.infos = pyr, infos
+    s.mu.Unlock()
+    old.Close()
+    return true, nil
+}
+
+// Levels returns the current tables; a later refresh may close them, so use
+// them for metadata only.
+func (s *pyramidSource) Levels() []*ephem.GTAB {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    if s.pyr == nil { return nil }
+    return s.pyr.Levels()
+}
+
+func (s *pyramidSource) Select(t time.Time) (*ephem.GTAB, bool) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    if s.pyr == nil { return nil, false }
+    return s.pyr.Select(t)
+}
+
+func (s *pyramidSource) Coverage() (start, end time.Time) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    if s.pyr == nil { return start, end }
+    return s.pyr.Coverage()
+}
+
+func (s *pyramidSource) LookupTideBPS(t time.Time) (uint16, time.Duration, bool) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    if s.pyr == nil { return 0, 0, false }
+    return s.pyr.LookupTideBPS(t)
+}
+
+func (s *pyramidSource) LookupTideBPSMany(ts []time.Time, out []uint16) int {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    if s.pyr == nil {
+        for k := range ts { out[k] = ephem.MissingBPS }
+        return 0
+    }
+    return s.pyr.LookupTideBPSMany(ts, out)
+}
+
+func (s *pyramidSource) LookupSample(t time.Time) (ephem.Sample, time.Duration, bool) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    if s.pyr == nil { return ephem.Sample{}, 0, false }
+    return s.pyr.LookupSample(t)
+}
+
+func (s *pyramidSource) TideAt(t time.Time) (float64, bool) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    if s.pyr == nil { return 0, false }
+    return s.pyr.TideAt(t)
+}
+
+func (s *pyramidSource) Close() error {
+    s.refreshMu.Lock()
+    defer s.refreshMu.Unlock()
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.pyr == nil { return nil }
+    err := s.pyr.Close()
+    s.pyr = nil
+    return err
+}
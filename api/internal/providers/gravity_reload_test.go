@@ -0,0 +1,103 @@
+package providers
+
+import (
+    "os"
+    "path/filepath"
+    "sync"
+    "testing"
+    "time"
+)
+
+func TestFileGravimetricReloadsReplacedTable(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    table := writeLevel(t, filepath.Join(dir, "gtab_60s.bin"), epoch, time.Minute, 60, 0)
+    writeMeta(t, dir, "v1")
+    prov, err := NewFileGravimetric(table, "")
+    if err != nil { t.Fatalf("new: %v", err) }
+    t.Cleanup(func(){ _ = prov.Close() })
+    at := epoch.Add(10 * time.Minute)
+    if changed, err := prov.Reload(); changed || err != nil { t.Fatalf("reload without change: %v %v", changed, err) }
+
+    // a corrupt replacement is rejected and the old table keeps serving
+    if err := os.WriteFile(table+".tmp", []byte("GTAB1 not really a table"), 0o644); err != nil { t.Fatal(err) }
+    if err := os.Rename(table+".tmp", table); err != nil { t.Fatal(err) }
+    if changed, err := prov.Reload(); changed || err == nil { t.Fatalf("bad table: changed=%v err=%v", changed, err) }
+    if v, err := prov.FetchAt(at); err != nil || v.LunarTideForce != 80 { t.Fatalf("old table should still serve: %v %v", v, err) }
+
+    // a valid replacement and a new meta file are both picked up
+    writeLevel(t, table, epoch, time.Minute, 120, 10000)
+    writeMeta(t, dir, "v2")
+    if changed, err := prov.Reload(); !changed || err != nil { t.Fatalf("good table: changed=%v err=%v", changed, err) }
+    if v, err := prov.FetchAt(at); err != nil || v.LunarTideForce != 130 { t.Fatalf("new table: %v %v", v, err) }
+    if _, end := prov.src.Coverage(); !end.Equal(epoch.Add(119 * time.Minute)) { t.Fatalf("coverage end %s", end) }
+    if prov.DatasetID() != "v2" { t.Fatalf("dataset id %q", prov.DatasetID()) }
+
+    // meta edits alone also count as a change
+    writeMeta(t, dir, "v3-longer-id")
+    if changed, err := prov.Reload(); !changed || err != nil || prov.DatasetID() != "v3-longer-id" { t.Fatalf("meta edit: %v %v %q", changed, err, prov.DatasetID()) }
+}
+
+// TestFileGravimetricMappedTableSurvivesRename replaces a mapped table the
+// way the writers do: the old mapping keeps serving the renamed-over inode
+// until Reload swaps in the new file.
+func TestFileGravimetricMappedTableSurvivesRename(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    table := writeLevel(t, filepath.Join(dir, "gtab_60s.bin"), epoch, time.Minute, 60, 0)
+    prov, err := NewFileGravimetric(table, "")
+    if err != nil { t.Fatalf("new: %v", err) }
+    t.Cleanup(func(){ _ = prov.Close() })
+    if !prov.src.Levels()[0].Mapped() { t.Skip("mmap unavailable") }
+    at := epoch.Add(10 * time.Minute)
+    writeLevel(t, table, epoch, time.Minute, 60, 10000)
+    if v, err := prov.FetchAt(at); err != nil || v.LunarTideForce != 80 { t.Fatalf("old mapping: %v %v", v, err) }
+    if changed, err := prov.Reload(); !changed || err != nil { t.Fatalf("reload: %v %v", changed, err) }
+    if !prov.src.Levels()[0].Mapped() { t.Fatal("reloaded table not mapped") }
+    if v, err := prov.FetchAt(at); err != nil || v.LunarTideForce != 130 { t.Fatalf("after: %v %v", v, err) }
+}
+
+// TestFileGravimetricWatchUnderLoad swaps tables while lookups run; with -race
+// it also checks that retired tables are not closed under a reader.
+func TestFileGravimetricWatchUnderLoad(t *testing.T) {
+    dir := t.TempDir()
+    epoch := time.Date(2025,8,1,0,0,0,0,time.UTC)
+    table := writeLevel(t, filepath.Join(dir, "gtab_60s.bin"), epoch, time.Minute, 60, 0)
+    os.Setenv("HYSTERESIS_BPS", "0")
+    t.Cleanup(func(){ os.Unsetenv("HYSTERESIS_BPS") })
+    prov, err := NewFileGravimetric(table, "")
+    if err != nil { t.Fatalf("new: %v", err) }
+    var mu sync.Mutex
+    reloads := 0
+    prov.Watch(time.Millisecond, func(changed bool, err error) {
+        if err != nil { t.Errorf("watch: %v", err) }
+        mu.Lock(); reloads++; mu.Unlock()
+    })
+    stop := make(chan struct{})
+    var wg sync.WaitGroup
+    for i := 0; i < 4; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for {
+                select {
+                case <-stop:
+                    return
+                default:
+                }
+                v, err := prov.FetchAt(epoch.Add(time.Minute))
+                if err != nil || (v.LunarTideForce != 80 && v.LunarTideForce != 130) { t.Errorf("fetch: %v %v", v, err); return }
+            }
+        }()
+    }
+    for i := 0; i < 10; i++ {
+        writeLevel(t, table, epoch, time.Minute, 60, uint16(i%2)*10000)
+        time.Sleep(5 * time.Millisecond)
+    }
+    close(stop)
+    wg.Wait()
+    if err := prov.Close(); err != nil { t.Fatalf("close: %v", err) }
+    mu.Lock()
+    defer mu.Unlock()
+    if reloads == 0 { t.Fatal("no reload observed") }
+}
@@ -0,0 +1,52 @@
+package providers
+
+import (
+    "os"
+    "strconv"
+    "sync"
+)
+
+// bpsHysteresis suppresses tide_bps changes smaller than band relative to the
+// last value served, so consumers do not flap on tiny movements.
+type bpsHysteresis struct {
+    // band in basis points; if 0, no hysteresis
+    band uint16
+    // last value served; 65535 denotes "unset"
+    last uint16
+    mu   sync.Mutex
+}
+
+// newHysteresisFromEnv reads HYSTERESIS_BPS (0..10000); invalid values disable it.
+func newHysteresisFromEnv() *bpsHysteresis {
+    h := &bpsHysteresis{last: 65535}
+    if v := os.Getenv("HYSTERESIS_BPS"); v != "" {
+        if n, err := strconv.Atoi(v); err == nil && n >= 0 && n <= 10000 { h.band = uint16(n) }
+    }
+    return h
+}
+
+// replay returns an unset hysteresis with the same band, for batch fetches
+// that must not disturb the state of live fetches.
+func (h *bpsHysteresis) replay() *bpsHysteresis {
+    return &bpsHysteresis{band: h.band, last: 65535}
+}
+
+// apply returns the value to serve for bps and records it as the last value.
+func (h *bpsHysteresis) apply(bps uint16) uint16 {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    if h.band > 0 && h.last != 65535 {
+        if bps > h.last {
+            if bps - h.last < h.band { bps = h.last }
+        } else if h.last > bps {
+            if h.last - bps < h.band { bps = h.last }
+        }
+    }
+    h.last = bps
+    return bps
+}
+
+// tideForce maps tide_bps onto the 80..130 LunarTideForce range.
+func tideForce(bps uint16) float64 {
+    return 80.0 + (float64(bps) / 10000.0) * 50.0
+}
@@ -0,0 +1,214 @@
+package providers
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/Jthora/autoBotTrader/api/internal/normalize"
+)
+
+// Reading is one fetched value of a signal.
+type Reading struct {
+    // Value is the scalar the signal's normalizer maps to a 0–100 score.
+    Value float64
+    // Raw is the provider payload reported as "raw" (e.g. AstrologyData).
+    Raw any
+}
+
+// SignalMeta describes the source behind a signal at request time. Fields a
+// provider does not report stay empty.
+type SignalMeta struct {
+    Mode       string `json:"mode,omitempty"`
+    DatasetID  string `json:"dataset_id,omitempty"`
+    Stale      bool   `json:"stale,omitempty"`
+    Resolution string `json:"resolution,omitempty"`
+}
+
+// Signal is one input of the composite prediction.
+type Signal struct {
+    // Name keys the signal in /predict and in weights, e.g. "gravity".
+    Name string
+    // Provider names the implementation, reported as "provider".
+    Provider string
+    // Fetch reads the current value.
+    Fetch func(ctx context.Context) (Reading, error)
+    // Normalize maps Reading.Value to a score in [0, 100].
+    Normalize func(float64) uint32
+    // Weight is the default composite weight.
+    Weight uint32
+    // CalcVersion versions the normalization; empty means "v1".
+    CalcVersion string
+    // Meta, if set, reports the source's mode, dataset and staleness.
+    Meta func(now time.Time) SignalMeta
+    // ErrorCode, if set, names a Fetch failure for clients; the default is
+    // Name + "_fetch_failed".
+    ErrorCode func(err error) string
+}
+
+// Code returns the client-facing error code for a Fetch failure.
+func (s Signal) Code(err error) string {
+    if s.ErrorCode != nil { return s.ErrorCode(err) }
+    return s.Name + "_fetch_failed"
+}
+
+// Registry holds the signals available to the composite, in registration
+// order, and which of them are enabled. Safe for concurrent use.
+type Registry struct {
+    mu      sync.RWMutex
+    signals []Signal
+    enabled map[string]bool
+}
+
+func NewRegistry() *Registry { return &Registry{enabled: map[string]bool{}} }
+
+// Register adds s, enabled. Names must be unique and Fetch and Normalize set.
+func (r *Registry) Register(s Signal) error {
+    if s.Name == "" || strings.ContainsAny(s.Name, ":, ") { return fmt.Errorf("invalid signal name %q", s.Name) }
+    if s.Fetch == nil || s.Normalize == nil { return fmt.Errorf("signal %s: Fetch and Normalize are required", s.Name) }
+    if s.CalcVersion == "" { s.CalcVersion = "v1" }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    for _, x := range r.signals {
+        if x.Name == s.Name { return fmt.Errorf("signal %s already registered", s.Name) }
+    }
+    r.signals = append(r.signals, s)
+    r.enabled[s.Name] = true
+    return nil
+}
+
+// Get returns the signal registered as name, enabled or not.
+func (r *Registry) Get(name string) (Signal, bool) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    for _, s := range r.signals {
+        if s.Name == name { return s, true }
+    }
+    return Signal{}, false
+}
+
+// Enabled returns the enabled signals in registration order.
+func (r *Registry) Enabled() []Signal {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    out := make([]Signal, 0, len(r.signals))
+    for _, s := range r.signals {
+        if r.enabled[s.Name] { out = append(out, s) }
+    }
+    return out
+}
+
+// SetEnabled turns a registered signal on or off.
+func (r *Registry) SetEnabled(name string, on bool) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if _, ok := r.enabled[name]; !ok { return fmt.Errorf("unknown signal %q", name) }
+    r.enabled[name] = on
+    return nil
+}
+
+// SetWeight changes a registered signal's weight.
+func (r *Registry) SetWeight(name string, w uint32) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    for i := range r.signals {
+        if r.signals[i].Name == name {
+            r.signals[i].Weight = w
+            return nil
+        }
+    }
+    return fmt.Errorf("unknown signal %q", name)
+}
+
+// Configure applies a spec such as "astrology:60,gravity:40": the listed
+// signals are enabled (with the weight, if given) and every other one is
+// disabled. The registry is unchanged if the spec is invalid.
+func (r *Registry) Configure(spec string) error {
+    weights := map[string]int64{} // -1: keep the current weight
+    for _, part := range strings.Split(spec, ",") {
+        if part = strings.TrimSpace(part); part == "" { continue }
+        name, w, hasW := strings.Cut(part, ":")
+        name = strings.TrimSpace(name)
+        if _, ok := r.Get(name); !ok { return fmt.Errorf("unknown signal %q", name) }
+        weights[name] = -1
+        if hasW {
+            v, err := strconv.ParseUint(strings.TrimSpace(w), 10, 32)
+            if err != nil { return fmt.Errorf("signal %s: invalid weight %q", name, w) }
+            weights[name] = int64(v)
+        }
+    }
+    if len(weights) == 0 { return errors.New("no signals enabled") }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    for i, s := range r.signals {
+        w, on := weights[s.Name]
+        r.enabled[s.Name] = on
+        if w >= 0 && on { r.signals[i].Weight = uint32(w) }
+    }
+    return nil
+}
+
+// ProviderMeta reads the optional Mode, DatasetID, Stale and Resolution
+// methods of a provider, beneath any resilient wrapper.
+func ProviderMeta(p any, now time.Time) SignalMeta {
+    var m SignalMeta
+    p = Unwrap(p)
+    if x, ok := p.(interface{ Mode() string }); ok { m.Mode = x.Mode() }
+    if x, ok := p.(interface{ DatasetID() string }); ok { m.DatasetID = x.DatasetID() }
+    if x, ok := p.(interface{ Stale(time.Time) bool }); ok { m.Stale = x.Stale(now) }
+    if x, ok := p.(interface{ Resolution(time.Time) time.Duration }); ok {
+        if d := x.Resolution(now); d > 0 { m.Resolution = d.String() }
+    }
+    return m
+}
+
+// AstrologySignal adapts p as the "astrology" signal: volatility_index
+// normalized by normalize.AstrologyScore, default weight 50.
+func AstrologySignal(p AstrologyProvider) Signal {
+    return Signal{
+        Name:     "astrology",
+        Provider: p.Name(),
+        Fetch: func(ctx context.Context) (Reading, error) {
+            d, err := p.Fetch(ctx)
+            return Reading{Value: d.VolatilityIndex, Raw: d}, err
+        },
+        Normalize: normalize.AstrologyScore,
+        Weight:    50,
+        Meta:      func(now time.Time) SignalMeta { return ProviderMeta(p, now) },
+    }
+}
+
+// GravimetricSignal adapts p as the "gravity" signal: lunar_tide_force
+// normalized by normalize.GravimetricScore, default weight 50. Failures are
+// reported as gravimetrics_no_data for dataset gaps (ErrNoData) and
+// gravimetrics_fetch_failed otherwise.
+func GravimetricSignal(p GravimetricProvider) Signal {
+    return Signal{
+        Name:     "gravity",
+        Provider: p.Name(),
+        Fetch: func(ctx context.Context) (Reading, error) {
+            d, err := p.Fetch(ctx)
+            return Reading{Value: d.LunarTideForce, Raw: d}, err
+        },
+        Normalize: normalize.GravimetricScore,
+        Weight:    50,
+        Meta:      func(now time.Time) SignalMeta { return ProviderMeta(p, now) },
+        ErrorCode: func(err error) string {
+            if errors.Is(err, ErrNoData) { return "gravimetrics_no_data" }
+            return "gravimetrics_fetch_failed"
+        },
+    }
+}
+
+// DefaultRegistry registers the astrology and gravity signals of the given
+// providers; a nil provider is skipped.
+func DefaultRegistry(astro AstrologyProvider, grav GravimetricProvider) *Registry {
+    r := NewRegistry()
+    if astro != nil { _ = r.Register(AstrologySignal(astro)) }
+    if grav != nil { _ = r.Register(GravimetricSignal(grav)) }
+    return r
+}
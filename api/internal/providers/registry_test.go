@@ -0,0 +1,55 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func constSignal(name string, v float64, w uint32) Signal {
+    return Signal{
+        Name: name,
+        Fetch: func(ctx context.Context) (Reading, error) { return Reading{Value: v}, nil },
+        Normalize: func(x float64) uint32 { return uint32(x) },
+        Weight: w,
+    }
+}
+
+func TestRegistryConfigure(t *testing.T) {
+    r := DefaultRegistry(MockAstrology{}, MockGravimetric{})
+    if err := r.Register(constSignal("ml", 40, 0)); err != nil { t.Fatalf("register: %v", err) }
+    if err := r.Register(constSignal("ml", 40, 0)); err == nil { t.Fatal("duplicate name accepted") }
+    if err := r.Register(Signal{Name: "bad"}); err == nil { t.Fatal("signal without Fetch accepted") }
+    names := func() (s string) {
+        for _, sig := range r.Enabled() { s += fmt.Sprintf("%s:%d ", sig.Name, sig.Weight) }
+        return s
+    }
+    if got := names(); got != "astrology:50 gravity:50 ml:0 " { t.Fatalf("defaults: %q", got) }
+    if s, _ := r.Get("ml"); s.CalcVersion != "v1" { t.Fatalf("calc version default: %q", s.CalcVersion) }
+    if err := r.Configure("ml:20, gravity:80"); err != nil { t.Fatalf("configure: %v", err) }
+    // registration order is kept, unlisted signals are disabled
+    if got := names(); got != "gravity:80 ml:20 " { t.Fatalf("configured: %q", got) }
+    if err := r.Configure("gravity,astrology"); err != nil { t.Fatalf("configure: %v", err) }
+    if got := names(); got != "astrology:50 gravity:80 " { t.Fatalf("weights not kept: %q", got) }
+    for _, bad := range []string{"", " , ", "tarot:10", "gravity:-1", "gravity:x"} {
+        if err := r.Configure(bad); err == nil { t.Fatalf("spec %q accepted", bad) }
+    }
+    if got := names(); got != "astrology:50 gravity:80 " { t.Fatalf("invalid spec changed registry: %q", got) }
+    if err := r.SetEnabled("ml", true); err != nil || len(r.Enabled()) != 3 { t.Fatalf("enable ml: %v", err) }
+    if err := r.SetWeight("tarot", 1); err == nil { t.Fatal("unknown signal weighted") }
+}
+
+func TestBuiltinSignals(t *testing.T) {
+    g := GravimetricSignal(MockGravimetric{})
+    rd, err := g.Fetch(context.Background())
+    if err != nil { t.Fatalf("fetch: %v", err) }
+    d, ok := rd.Raw.(GravimetricData)
+    if !ok || rd.Value != d.LunarTideForce { t.Fatalf("reading: %+v", rd) }
+    if g.Code(fmt.Errorf("grav: %w", ErrNoData)) != "gravimetrics_no_data" || g.Code(fmt.Errorf("boom")) != "gravimetrics_fetch_failed" { t.Fatal("gravity error codes") }
+    now := time.Now().UTC()
+    if m := g.Meta(now); m.Mode != "mock" { t.Fatalf("meta: %+v", m) }
+    a := AstrologySignal(AlgoAstrology{})
+    if a.Code(fmt.Errorf("boom")) != "astrology_fetch_failed" { t.Fatal("astrology error code") }
+    if m := a.Meta(now); m.Mode != "algo" || m.Stale { t.Fatalf("meta: %+v", m) }
+}
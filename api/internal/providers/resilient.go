@@ -0,0 +1,359 @@
+package providers
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "math/rand"
+    "strconv"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "time"
+)
+
+// ErrCircuitOpen is wrapped by Fetch errors of a resilient provider whose
+// circuit breaker is rejecting calls.
+var ErrCircuitOpen = errors.New("circuit open")
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying (e.g. a rejected credential).
+// Permanent errors still count as failures of the source.
+func Permanent(err error) error {
+    if err == nil { return nil }
+    return permanentError{err}
+}
+
+// Transient reports whether a Fetch that failed with err may succeed when
+// retried. Dataset gaps (ErrNoData), open circuits, cancellation and errors
+// marked Permanent are not transient; everything else, including an attempt
+// running out of time, is.
+func Transient(err error) bool {
+    var p permanentError
+    switch {
+    case err == nil, errors.Is(err, ErrNoData), errors.Is(err, ErrCircuitOpen), errors.Is(err, context.Canceled), errors.As(err, &p):
+        return false
+    }
+    return true
+}
+
+// CircuitState is the state of a provider's circuit breaker.
+type CircuitState string
+
+const (
+    CircuitClosed   CircuitState = "closed"    // calls pass through
+    CircuitOpen     CircuitState = "open"      // calls fail fast with ErrCircuitOpen
+    CircuitHalfOpen CircuitState = "half_open" // one probe call decides
+)
+
+// Policy configures retries and the circuit breaker of a resilient provider.
+// Zero fields take their value from DefaultPolicy.
+type Policy struct {
+    // Attempts bounds the tries per Fetch, the first included.
+    Attempts int
+    // BaseDelay is the backoff before the second attempt; it doubles per
+    // attempt up to MaxDelay, and each wait is jittered to [d/2, d].
+    BaseDelay, MaxDelay time.Duration
+    // AttemptTimeout, if positive, bounds each attempt; otherwise only the
+    // caller's context does.
+    AttemptTimeout time.Duration
+    // FailureThreshold is the number of consecutive failed Fetches that
+    // opens the circuit.
+    FailureThreshold int
+    // OpenFor is how long an open circuit rejects calls before letting one
+    // probe through.
+    OpenFor time.Duration
+    // MaxStale is the age up to which LastGood offers the last successful
+    // reading.
+    MaxStale time.Duration
+    // OnStateChange, if set, is called after each circuit transition.
+    OnStateChange func(provider string, from, to CircuitState, err error)
+}
+
+// DefaultPolicy suits the local providers' latency within the handlers'
+// 3s request budget.
+var DefaultPolicy = Policy{Attempts: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second, FailureThreshold: 5, OpenFor: 30 * time.Second, MaxStale: 15 * time.Minute}
+
+func (p Policy) withDefaults() Policy {
+    d := DefaultPolicy
+    if p.Attempts <= 0 { p.Attempts = d.Attempts }
+    if p.BaseDelay <= 0 { p.BaseDelay = d.BaseDelay }
+    if p.MaxDelay <= 0 { p.MaxDelay = max(d.MaxDelay, p.BaseDelay) }
+    if p.FailureThreshold <= 0 { p.FailureThreshold = d.FailureThreshold }
+    if p.OpenFor <= 0 { p.OpenFor = d.OpenFor }
+    if p.MaxStale <= 0 { p.MaxStale = d.MaxStale }
+    return p
+}
+
+// ParsePolicy parses a comma-separated list of key:value pairs over
+// DefaultPolicy, e.g. "attempts:4,backoff:50ms,threshold:3,cooldown:1m".
+// Keys: attempts, backoff, max_backoff, timeout, threshold, cooldown,
+// max_stale.
+func ParsePolicy(s string) (Policy, error) {
+    p := DefaultPolicy
+    for _, part := range strings.Split(s, ",") {
+        if part = strings.TrimSpace(part); part == "" { continue }
+        key, val, ok := strings.Cut(part, ":")
+        if !ok { return Policy{}, fmt.Errorf("policy %q: want key:value", part) }
+        key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+        var n *int
+        var d *time.Duration
+        switch key {
+        case "attempts":
+            n = &p.Attempts
+        case "threshold":
+            n = &p.FailureThreshold
+        case "backoff":
+            d = &p.BaseDelay
+        case "max_backoff":
+            d = &p.MaxDelay
+        case "timeout":
+            d = &p.AttemptTimeout
+        case "cooldown":
+            d = &p.OpenFor
+        case "max_stale":
+            d = &p.MaxStale
+        default:
+            return Policy{}, fmt.Errorf("unknown policy key %q", key)
+        }
+        if n != nil {
+            v, err := strconv.Atoi(val)
+            if err != nil || v < 1 { return Policy{}, fmt.Errorf("policy %s: want a positive integer, got %q", key, val) }
+            *n = v
+            continue
+        }
+        v, err := time.ParseDuration(val)
+        if err != nil || v < 0 { return Policy{}, fmt.Errorf("policy %s: want a duration, got %q", key, val) }
+        *d = v
+    }
+    if p.MaxDelay < p.BaseDelay { return Policy{}, fmt.Errorf("policy: max_backoff %v below backoff %v", p.MaxDelay, p.BaseDelay) }
+    return p, nil
+}
+
+// ResilienceStatus reports a resilient provider's breaker, as on /health.
+type ResilienceStatus struct {
+    State               CircuitState `json:"state"`
+    ConsecutiveFailures int          `json:"consecutive_failures"`
+    Trips               uint64       `json:"trips"`   // closed or half-open → open transitions
+    Retries             uint64       `json:"retries"` // attempts beyond the first
+    LastError           string       `json:"last_error,omitempty"`
+    Since               time.Time    `json:"since"`   // last state change
+}
+
+// resilience retries calls and runs the circuit breaker of one provider.
+type resilience struct {
+    name    string
+    policy  Policy
+    now     func() time.Time
+    retries atomic.Uint64
+
+    mu       sync.Mutex
+    state    CircuitState
+    failures int
+    trips    uint64
+    lastErr  string
+    since    time.Time
+    probing  bool // a half-open probe is in flight
+    // last successful reading, for LastGood
+    last   any
+    lastAt time.Time
+}
+
+func newResilience(name string, p Policy) *resilience {
+    return &resilience{name: name, policy: p.withDefaults(), now: time.Now, state: CircuitClosed, since: time.Now().UTC()}
+}
+
+// backoff returns the jittered wait before attempt n (n ≥ 1).
+func (r *resilience) backoff(n int) time.Duration {
+    d := r.policy.MaxDelay
+    if n-1 < 32 {
+        if b := r.policy.BaseDelay << (n - 1); b > 0 && b < d { d = b }
+    }
+    return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// call runs fetch under the breaker, retrying transient failures, and keeps
+// the result of a successful call for lastGood.
+func (r *resilience) call(ctx context.Context, fetch func(context.Context) (any, error)) (any, error) {
+    probe, err := r.allow()
+    if err != nil { return nil, err }
+    var v any
+    for n := 0; n < r.policy.Attempts; n++ {
+        if n > 0 {
+            r.retries.Add(1)
+            t := time.NewTimer(r.backoff(n))
+            select {
+            case <-ctx.Done():
+                t.Stop()
+                r.record(probe, true, err)
+                return nil, err
+            case <-t.C:
+            }
+        }
+        actx, cancel := ctx, context.CancelFunc(func() {})
+        if r.policy.AttemptTimeout > 0 { actx, cancel = context.WithTimeout(ctx, r.policy.AttemptTimeout) }
+        v, err = fetch(actx)
+        cancel()
+        if !Transient(err) || ctx.Err() != nil { break }
+    }
+    r.record(probe, ctx.Err() != nil, err)
+    if err == nil {
+        r.mu.Lock()
+        r.last, r.lastAt = v, r.now()
+        r.mu.Unlock()
+    }
+    return v, err
+}
+
+// allow admits a call, reporting whether it is the half-open probe.
+func (r *resilience) allow() (probe bool, err error) {
+    r.mu.Lock()
+    var from CircuitState
+    switch r.state {
+    case CircuitOpen:
+        if r.now().Sub(r.since) < r.policy.OpenFor {
+            r.mu.Unlock()
+            return false, fmt.Errorf("%s: %w", r.name, ErrCircuitOpen)
+        }
+        from = r.transition(CircuitHalfOpen)
+        fallthrough
+    case CircuitHalfOpen:
+        if r.probing {
+            r.mu.Unlock()
+            return false, fmt.Errorf("%s: %w", r.name, ErrCircuitOpen)
+        }
+        r.probing, probe = true, true
+    }
+    r.mu.Unlock()
+    if from != "" { r.notify(from, CircuitHalfOpen, nil) }
+    return probe, nil
+}
+
+// record updates the breaker with the outcome of a call. Dataset gaps count
+// as the source answering. Failures once the caller's context is done
+// (abandoned) count for nothing: the caller's budget, possibly spent on
+// another source, says nothing about this one. An abandoned probe leaves the
+// next call to probe. Failures of calls admitted before the circuit opened
+// neither count nor extend the open window.
+func (r *resilience) record(probe, abandoned bool, err error) {
+    r.mu.Lock()
+    if probe { r.probing = false }
+    var from, to CircuitState
+    switch {
+    case err == nil || errors.Is(err, ErrNoData):
+        r.failures = 0
+        if r.state != CircuitClosed { from, to = r.transition(CircuitClosed), CircuitClosed }
+    case abandoned, errors.Is(err, context.Canceled), r.state == CircuitOpen:
+    default:
+        r.failures++
+        r.lastErr = err.Error()
+        if probe || r.failures >= r.policy.FailureThreshold {
+            r.trips++
+            from, to = r.transition(CircuitOpen), CircuitOpen
+        }
+    }
+    r.mu.Unlock()
+    if to != "" && from != to { r.notify(from, to, err) }
+}
+
+// transition moves to state s and returns the previous state; r.mu is held.
+func (r *resilience) transition(s CircuitState) CircuitState {
+    from := r.state
+    r.state, r.since = s, r.now().UTC()
+    return from
+}
+
+func (r *resilience) notify(from, to CircuitState, err error) {
+    if r.policy.OnStateChange != nil { r.policy.OnStateChange(r.name, from, to, err) }
+}
+
+func (r *resilience) status() ResilienceStatus {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return ResilienceStatus{State: r.state, ConsecutiveFailures: r.failures, Trips: r.trips, Retries: r.retries.Load(), LastError: r.lastErr, Since: r.since}
+}
+
+// lastGood returns the last successful reading if it is at most MaxStale old.
+func (r *resilience) lastGood(now time.Time) (any, bool) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if r.last == nil || now.Sub(r.lastAt) > r.policy.MaxStale { return nil, false }
+    return r.last, true
+}
+
+// Unwrap returns the provider beneath any resilient wrappers of p, so that
+// optional metadata methods (Resolution, AspectOptions, ...) can be found.
+func Unwrap(p any) any {
+    for {
+        u, ok := p.(interface{ Unwrap() any })
+        if !ok { return p }
+        p = u.Unwrap()
+    }
+}
+
+// ResilientAstrology adds retries and a circuit breaker to an astrology
+// provider.
+type ResilientAstrology struct {
+    AstrologyProvider
+    r *resilience
+}
+
+func NewResilientAstrology(p AstrologyProvider, policy Policy) *ResilientAstrology {
+    return &ResilientAstrology{AstrologyProvider: p, r: newResilience(p.Name(), policy)}
+}
+
+func (a *ResilientAstrology) Fetch(ctx context.Context) (AstrologyData, error) {
+    v, err := a.r.call(ctx, func(ctx context.Context) (any, error) {
+        d, err := a.AstrologyProvider.Fetch(ctx)
+        return d, err
+    })
+    if err != nil { return AstrologyData{}, err }
+    return v.(AstrologyData), nil
+}
+
+// LastGood returns the last successful reading unless it is older than the
+// policy's MaxStale.
+func (a *ResilientAstrology) LastGood(now time.Time) (AstrologyData, bool) {
+    v, ok := a.r.lastGood(now)
+    if !ok { return AstrologyData{}, false }
+    return v.(AstrologyData), true
+}
+
+func (a *ResilientAstrology) Status() ResilienceStatus { return a.r.status() }
+func (a *ResilientAstrology) Unwrap() any               { return a.AstrologyProvider }
+
+// ResilientGravimetric adds retries and a circuit breaker to a gravimetric
+// provider. Dataset gaps (ErrNoData) are returned at once and do not count
+// against the source.
+type ResilientGravimetric struct {
+    GravimetricProvider
+    r *resilience
+}
+
+func NewResilientGravimetric(p GravimetricProvider, policy Policy) *ResilientGravimetric {
+    return &ResilientGravimetric{GravimetricProvider: p, r: newResilience(p.Name(), policy)}
+}
+
+func (g *ResilientGravimetric) Fetch(ctx context.Context) (GravimetricData, error) {
+    v, err := g.r.call(ctx, func(ctx context.Context) (any, error) {
+        d, err := g.GravimetricProvider.Fetch(ctx)
+        return d, err
+    })
+    if err != nil { return GravimetricData{}, err }
+    return v.(GravimetricData), nil
+}
+
+// LastGood returns the last successful reading unless it is older than the
+// policy's MaxStale.
+func (g *ResilientGravimetric) LastGood(now time.Time) (GravimetricData, bool) {
+    v, ok := g.r.lastGood(now)
+    if !ok { return GravimetricData{}, false }
+    return v.(GravimetricData), true
+}
+
+func (g *ResilientGravimetric) Status() ResilienceStatus { return g.r.status() }
+func (g *ResilientGravimetric) Unwrap() any               { return g.GravimetricProvider }
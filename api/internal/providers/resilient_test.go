@@ -0,0 +1,123 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// scriptedGrav fails with errs in turn (nil: succeed), then succeeds.
+type scriptedGrav struct {
+    MockGravimetric
+    errs  []error
+    calls int
+}
+
+func (s *scriptedGrav) Fetch(ctx context.Context) (GravimetricData, error) {
+    s.calls++
+    if len(s.errs) > 0 {
+        err := s.errs[0]
+        s.errs = s.errs[1:]
+        if err != nil { return GravimetricData{}, err }
+    }
+    return GravimetricData{LunarTideForce: 100 + float64(s.calls)}, nil
+}
+
+var errFlaky = errors.New("connection reset")
+
+func TestResilientRetries(t *testing.T) {
+    fast := Policy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+    src := &scriptedGrav{errs: []error{errFlaky, errFlaky}}
+    g := NewResilientGravimetric(src, fast)
+    d, err := g.Fetch(context.Background())
+    if err != nil || src.calls != 3 || d.LunarTideForce != 103 { t.Fatalf("retry: %v calls=%d %+v", err, src.calls, d) }
+    if st := g.Status(); st.Retries != 2 || st.ConsecutiveFailures != 0 || st.State != CircuitClosed { t.Fatalf("status: %+v", st) }
+    // exhausted retries surface the last error
+    src = &scriptedGrav{errs: []error{errFlaky, errFlaky, fmt.Errorf("dial: %w", errFlaky)}}
+    g = NewResilientGravimetric(src, fast)
+    if _, err := g.Fetch(context.Background()); !errors.Is(err, errFlaky) || src.calls != 3 { t.Fatalf("exhausted: %v calls=%d", err, src.calls) }
+    if st := g.Status(); st.ConsecutiveFailures != 1 || st.LastError != "dial: connection reset" { t.Fatalf("status: %+v", st) }
+    // permanent errors and dataset gaps are not retried; gaps do not count against the source
+    for _, c := range []struct{ err error; failures int }{{Permanent(errors.New("401")), 1}, {fmt.Errorf("table: %w", ErrNoData), 0}} {
+        src = &scriptedGrav{errs: []error{c.err}}
+        g = NewResilientGravimetric(src, fast)
+        if _, err := g.Fetch(context.Background()); err == nil || src.calls != 1 { t.Fatalf("%v: calls=%d", c.err, src.calls) }
+        if st := g.Status(); st.ConsecutiveFailures != c.failures { t.Fatalf("%v: status %+v", c.err, st) }
+    }
+    if Transient(nil) || Transient(context.Canceled) || !Transient(context.DeadlineExceeded) || !Transient(errFlaky) { t.Fatal("classification") }
+    // backoff doubles from BaseDelay, capped at MaxDelay, jittered to [d/2, d]
+    r := newResilience("x", Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second})
+    for n, want := range map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 5: time.Second, 80: time.Second} {
+        if d := r.backoff(n); d < want/2 || d > want { t.Fatalf("backoff(%d) = %v, want [%v, %v]", n, d, want/2, want) }
+    }
+}
+
+func TestResilientCircuitBreaker(t *testing.T) {
+    var changes []string
+    p := Policy{Attempts: 1, FailureThreshold: 2, OpenFor: time.Minute, MaxStale: 5 * time.Minute,
+        OnStateChange: func(name string, from, to CircuitState, err error) { changes = append(changes, fmt.Sprintf("%s:%s>%s", name, from, to)) }}
+    src := &scriptedGrav{errs: []error{nil, errFlaky, errFlaky, errFlaky}}
+    g := NewResilientGravimetric(src, p)
+    clock := time.Now()
+    g.r.now = func() time.Time { return clock }
+    ctx := context.Background()
+    if _, err := g.Fetch(ctx); err != nil { t.Fatalf("first fetch: %v", err) }
+    g.Fetch(ctx)
+    if st := g.Status(); st.State != CircuitClosed { t.Fatalf("opened below threshold: %+v", st) }
+    g.Fetch(ctx)
+    if st := g.Status(); st.State != CircuitOpen || st.Trips != 1 { t.Fatalf("not open: %+v", st) }
+    // open: fail fast without calling the source, offering the last good reading
+    if _, err := g.Fetch(ctx); !errors.Is(err, ErrCircuitOpen) || src.calls != 3 { t.Fatalf("open: %v calls=%d", err, src.calls) }
+    if d, ok := g.LastGood(clock); !ok || d.LunarTideForce != 101 { t.Fatalf("last good: %+v %v", d, ok) }
+    // half-open: one probe; failure reopens
+    clock = clock.Add(time.Minute)
+    if _, err := g.Fetch(ctx); errors.Is(err, ErrCircuitOpen) || src.calls != 4 { t.Fatalf("probe: %v calls=%d", err, src.calls) }
+    if st := g.Status(); st.State != CircuitOpen || st.Trips != 2 { t.Fatalf("failed probe: %+v", st) }
+    // a concurrent call while the probe is in flight is rejected
+    clock = clock.Add(time.Minute)
+    probe, err := g.r.allow()
+    if !probe || err != nil { t.Fatalf("allow probe: %v %v", probe, err) }
+    if _, err := g.Fetch(ctx); !errors.Is(err, ErrCircuitOpen) { t.Fatalf("second probe admitted: %v", err) }
+    g.r.record(true, false, context.Canceled) // abandoned probe: the next call probes again
+    if _, err := g.Fetch(ctx); err != nil { t.Fatalf("probe: %v", err) }
+    if st := g.Status(); st.State != CircuitClosed || st.ConsecutiveFailures != 0 { t.Fatalf("not closed: %+v", st) }
+    want := "mock_grav_v1:closed>open mock_grav_v1:open>half_open mock_grav_v1:half_open>open mock_grav_v1:open>half_open mock_grav_v1:half_open>closed"
+    if got := fmt.Sprint(changes); got != "["+want+"]" { t.Fatalf("transitions %s", got) }
+    if _, ok := g.LastGood(clock.Add(6 * time.Minute)); ok { t.Fatal("last good beyond MaxStale") }
+    // metadata passes through the wrapper
+    a := NewResilientAstrology(AlgoAstrology{}, p)
+    if m, ok := Unwrap(a).(interface{ Mode() string }); !ok || m.Mode() != "algo" || g.Mode() != "mock" { t.Fatal("unwrap") }
+}
+
+func TestResilientIgnoresCallerDeadlineAndLateFailures(t *testing.T) {
+    p := Policy{Attempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, FailureThreshold: 1, OpenFor: time.Minute}
+    // a source failing only because the caller's budget ran out is not at fault
+    slow := NewResilientGravimetric(&scriptedGrav{errs: []error{context.DeadlineExceeded}}, p)
+    ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+    defer cancel()
+    <-ctx.Done()
+    if _, err := slow.Fetch(ctx); err == nil { t.Fatal("expired context fetch succeeded") }
+    if st := slow.Status(); st.State != CircuitClosed || st.ConsecutiveFailures != 0 { t.Fatalf("caller deadline counted: %+v", st) }
+    // two calls admitted while closed both fail; the second must not re-trip
+    g := NewResilientGravimetric(&scriptedGrav{}, p)
+    clock := time.Now()
+    g.r.now = func() time.Time { return clock }
+    g.r.allow()
+    g.r.allow()
+    g.r.record(false, false, errFlaky)
+    opened := g.Status()
+    clock = clock.Add(30 * time.Second)
+    g.r.record(false, false, errFlaky)
+    if st := g.Status(); st.State != CircuitOpen || st.Trips != 1 || st.ConsecutiveFailures != 1 || !st.Since.Equal(opened.Since) { t.Fatalf("late failure re-tripped: %+v, opened %+v", st, opened) }
+}
+
+func TestParsePolicy(t *testing.T) {
+    p, err := ParsePolicy("attempts:4, backoff:50ms,threshold:3,cooldown:1m,timeout:500ms")
+    if err != nil { t.Fatalf("parse: %v", err) }
+    if p.Attempts != 4 || p.BaseDelay != 50*time.Millisecond || p.FailureThreshold != 3 || p.OpenFor != time.Minute || p.AttemptTimeout != 500*time.Millisecond || p.MaxDelay != DefaultPolicy.MaxDelay { t.Fatalf("policy: %+v", p) }
+    for _, bad := range []string{"attempts", "attempts:0", "retries:3", "backoff:soon", "backoff:2s"} {
+        if _, err := ParsePolicy(bad); err == nil { t.Fatalf("%q accepted", bad) }
+    }
+}
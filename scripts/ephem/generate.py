@@ -9,6 +9,7 @@ from __future__ import annotations
 import argparse
 import hashlib
 import json
+import os
 import struct
 from dataclasses import dataclass
 from datetime import datetime, timezone, timedelta
@@ -61,6 +62,17 @@ def make_dataset_id(cfg: Config) -> str:
     return h.hexdigest()[:12]
 
 
+def write_atomic(path: Path, data: bytes):
+    """Write data beside path and rename it into place, so a server serving
+    (and mapping) the old file never sees it truncated or half written."""
+    tmp = path.with_name(path.name + '.tmp')
+    with open(tmp, 'wb') as f:
+        f.write(data)
+        f.flush()
+        os.fsync(f.fileno())
+    os.replace(tmp, path)
+
+
 def write_gtab(path: Path, epoch: int, dt_ns: int, samples: List[Tuple[int, float]], fields_mask: int):
     # Header
     hdr = bytearray()
@@ -70,7 +82,8 @@ def write_gtab(path: Path, epoch: int, dt_ns: int, samples: List[Tuple[int, floa
     hdr.extend(struct.pack('<q', dt_ns))
     hdr.extend(struct.pack('<I', len(samples)))
     hdr.extend(struct.pack('<I', fields_mask))
-    hdr.extend(b"\x00" * 16)  # reserved
+    hdr.extend(b"\x00")  # time_scale: 0 = UTC grid (samples are taken at UTC instants)
+    hdr.extend(b"\x00" * 15)  # reserved
     # Record packing in fixed order
     recs = bytearray()
     for bps, raw in samples:
@@ -78,7 +91,7 @@ def write_gtab(path: Path, epoch: int, dt_ns: int, samples: List[Tuple[int, floa
             recs.extend(struct.pack('<H', bps))
         if fields_mask & FIELD_TIDE_RAW:
             recs.extend(struct.pack('<f', raw))
-    path.write_bytes(hdr + recs)
+    write_atomic(path, bytes(hdr + recs))
 
 
 def generate(cfg: Config):
@@ -142,8 +155,18 @@ def generate(cfg: Config):
         "fields_mask": cfg.fields_mask,
         "version": VERSION,
         "created_at": datetime.now(timezone.utc).isoformat(),
+        # checked by `gtabctl verify`
+        "files": {name: file_digest(cfg.out_dir / name) for name in ('gtab_1s.bin', 'gtab_60s.bin')},
     }
-    (cfg.out_dir / 'gtab.meta.json').write_text(json.dumps(meta, indent=2) + "\n")
+    write_atomic(cfg.out_dir / 'gtab.meta.json', (json.dumps(meta, indent=2) + "\n").encode())
+
+
+def file_digest(path: Path) -> dict:
+    h = hashlib.sha256()
+    with open(path, 'rb') as f:
+        for block in iter(lambda: f.read(1 << 20), b''):
+            h.update(block)
+    return {"size": path.stat().st_size, "sha256": h.hexdigest()}
 
 
 def parse_args():